@@ -200,6 +200,11 @@ func (e *Engine) Prepare(options *EngineBuildingOptions) error {
 		return fmt.Errorf("Could not find the following tasks in project: %s", strings.Join(missingList, ", "))
 	}
 
+	rootPipeline, err := e.completeGraph.GetPipelineFromWorkspace(util.RootPkgName, e.isSinglePackage)
+	if err != nil {
+		return err
+	}
+
 	// Things get appended to traversalQueue inside this loop, so we use the len() check instead of range.
 	for len(traversalQueue) > 0 {
 		// pop off the first item from the traversalQueue
@@ -245,7 +250,12 @@ func (e *Engine) Prepare(options *EngineBuildingOptions) error {
 		deps := make(util.Set)
 		isPackageTask := util.IsPackageTask(taskName)
 
-		for _, dependency := range taskDefinition.TaskDependencies {
+		// Globs in dependsOn (e.g. "test:*") are expanded against the tasks that pkg can run
+		candidates, err := e.getDependencyCandidates(pkg, rootPipeline)
+		if err != nil {
+			return err
+		}
+		for _, dependency := range taskDefinition.ResolveTaskDependencies(taskID, candidates) {
 			// If the current task is a workspace-specific task (including root Task)
 			// and its dependency is _also_ a workspace-specific task, we need to add
 			// a reference to this dependency directly into the engine.
@@ -288,6 +298,10 @@ func (e *Engine) Prepare(options *EngineBuildingOptions) error {
 		// E.g. `build: { dependsOn: [dev] }`
 		hasDeps := deps.Len() > 0
 
+		// hasRootTaskDeps will be true if the task depends on any tasks in the root workspace
+		// E.g. `build: { dependsOn: [//lint] }`
+		hasRootTaskDeps := len(taskDefinition.RootTaskDependencies) > 0
+
 		// hasPackageTaskDeps will be true if this is a workspace-specific task, and
 		// it depends on another workspace-specific tasks
 		// E.g. `my-package#build: { dependsOn: [my-package#beforebuild] }`.
@@ -320,6 +334,20 @@ func (e *Engine) Prepare(options *EngineBuildingOptions) error {
 			}
 		}
 
+		if hasRootTaskDeps {
+			for _, from := range taskDefinition.RootTaskDependencies {
+				fromTaskID := util.RootTaskID(from)
+				// Root tasks only run if the root turbo.json has an entry for them
+				if _, ok := rootPipeline[fromTaskID]; !ok {
+					return fmt.Errorf("%v depends on %v, which is not defined in the root turbo.json", taskID, fromTaskID)
+				}
+				e.TaskGraph.Add(fromTaskID)
+				e.TaskGraph.Add(toTaskID)
+				e.TaskGraph.Connect(dag.BasicEdge(toTaskID, fromTaskID))
+				traversalQueue = append(traversalQueue, fromTaskID)
+			}
+		}
+
 		if hasPackageTaskDeps {
 			if pkgTaskDeps, ok := e.PackageTaskDeps[toTaskID]; ok {
 				for _, fromTaskID := range pkgTaskDeps {
@@ -332,7 +360,7 @@ func (e *Engine) Prepare(options *EngineBuildingOptions) error {
 		}
 
 		// Add the root node into the graph
-		if !hasDeps && !hasTopoDeps && !hasPackageTaskDeps {
+		if !hasDeps && !hasTopoDeps && !hasRootTaskDeps && !hasPackageTaskDeps {
 			e.TaskGraph.Add(ROOT_NODE_NAME)
 			e.TaskGraph.Add(toTaskID)
 			e.TaskGraph.Connect(dag.BasicEdge(toTaskID, ROOT_NODE_NAME))
@@ -511,13 +539,39 @@ func (e *Engine) getTaskDefinitionChain(taskID string, taskName string) ([]fs.Bo
 	return taskDefinitions, nil
 }
 
+// getDependencyCandidates returns the pipeline that globs in the dependsOn of pkg's tasks
+// are matched against: the root pipeline, along with the tasks from pkg's own turbo.json.
+func (e *Engine) getDependencyCandidates(pkg string, rootPipeline fs.Pipeline) (fs.Pipeline, error) {
+	if e.isSinglePackage || pkg == util.RootPkgName {
+		return rootPipeline, nil
+	}
+
+	workspacePipeline, err := e.completeGraph.GetPipelineFromWorkspace(pkg, e.isSinglePackage)
+	if err != nil {
+		// turbo.json config is not required in the workspace
+		if errors.Is(err, os.ErrNotExist) {
+			return rootPipeline, nil
+		}
+		return nil, err
+	}
+
+	candidates := make(fs.Pipeline, len(rootPipeline)+len(workspacePipeline))
+	for taskID, taskDefinition := range rootPipeline {
+		candidates[taskID] = taskDefinition
+	}
+	for taskName, taskDefinition := range workspacePipeline {
+		candidates[taskName] = taskDefinition
+	}
+	return candidates, nil
+}
+
 func validateNoPackageTaskSyntax(turboJSON *fs.TurboJSON) []error {
 	errors := []error{}
 
-	for taskIDOrName := range turboJSON.Pipeline {
-		if util.IsPackageTask(taskIDOrName) {
-			taskName := util.StripPackageName(taskIDOrName)
-			errors = append(errors, fmt.Errorf("\"%s\". Use \"%s\" instead", taskIDOrName, taskName))
+	for _, taskIDs := range turboJSON.Pipeline.PackageTasks() {
+		for _, taskID := range taskIDs {
+			taskName := util.StripPackageName(taskID)
+			errors = append(errors, fmt.Errorf("\"%s\". Use \"%s\" instead", taskID, taskName))
 		}
 	}
 
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/graph"
+	"gotest.tools/v3/assert"
+)
+
+// newTestEngine returns an Engine for a repository with the given turbo.json contents,
+// keyed by workspace. The root workspace ("//") is required.
+func newTestEngine(t *testing.T, isSinglePackage bool, turboJSONs map[string]string) *Engine {
+	t.Helper()
+	completeGraph := &graph.CompleteGraph{
+		WorkspaceGraph: dag.AcyclicGraph{},
+		WorkspaceInfos: graph.WorkspaceInfos{
+			PackageJSONs: map[string]*fs.PackageJSON{},
+			TurboConfigs: map[string]*fs.TurboJSON{},
+		},
+		TaskDefinitions: map[string]*fs.TaskDefinition{},
+	}
+	for workspace, contents := range turboJSONs {
+		turboJSON, err := fs.ReadTurboConfigFromReader(strings.NewReader(contents))
+		assert.NilError(t, err, "ReadTurboConfigFromReader")
+		completeGraph.WorkspaceInfos.PackageJSONs[workspace] = &fs.PackageJSON{Name: workspace}
+		completeGraph.WorkspaceInfos.TurboConfigs[workspace] = turboJSON
+		completeGraph.WorkspaceGraph.Add(workspace)
+	}
+	completeGraph.Pipeline = completeGraph.WorkspaceInfos.TurboConfigs["//"].Pipeline
+
+	engine := NewEngine(completeGraph, isSinglePackage)
+	for taskName := range completeGraph.Pipeline {
+		engine.AddTask(taskName)
+	}
+	return engine
+}
+
+// dependenciesOf returns the sorted IDs of the tasks that taskID depends on directly
+func dependenciesOf(engine *Engine, taskID string) []string {
+	dependencies := []string{}
+	for _, dependency := range engine.TaskGraph.DownEdges(taskID) {
+		dependencies = append(dependencies, dag.VertexName(dependency))
+	}
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+func TestPrepare_TaskDependencyGlobs(t *testing.T) {
+	t.Run("single package", func(t *testing.T) {
+		engine := newTestEngine(t, true, map[string]string{
+			"//": `{"pipeline": {
+				"//#test:all": {"dependsOn": ["test:*"]},
+				"//#test:unit": {},
+				"//#test:e2e": {},
+				"//#build": {}
+			}}`,
+		})
+		err := engine.Prepare(&EngineBuildingOptions{
+			Packages:  []string{"//"},
+			TaskNames: []string{"test:all"},
+		})
+		assert.NilError(t, err, "Prepare")
+		assert.DeepEqual(t, dependenciesOf(engine, "//#test:all"), []string{"//#test:e2e", "//#test:unit"})
+	})
+
+	t.Run("workspace", func(t *testing.T) {
+		engine := newTestEngine(t, false, map[string]string{
+			"//": `{"pipeline": {
+				"test:all": {"dependsOn": ["test:*"]},
+				"test:unit": {},
+				"build": {}
+			}}`,
+			"web": `{"extends": ["//"], "pipeline": {"test:visual": {}}}`,
+		})
+		err := engine.Prepare(&EngineBuildingOptions{
+			Packages:  []string{"web"},
+			TaskNames: []string{"test:all"},
+		})
+		assert.NilError(t, err, "Prepare")
+		// Tasks that are only defined in the workspace's turbo.json are matched too
+		assert.DeepEqual(t, dependenciesOf(engine, "web#test:all"), []string{"web#test:unit", "web#test:visual"})
+	})
+}
+
+func TestPrepare_RootTaskDependencies(t *testing.T) {
+	t.Run("adds an edge to the root task", func(t *testing.T) {
+		engine := newTestEngine(t, false, map[string]string{
+			"//": `{"pipeline": {
+				"build": {"dependsOn": ["//lint"]},
+				"//#lint": {}
+			}}`,
+			"web": `{"extends": ["//"], "pipeline": {}}`,
+		})
+		err := engine.Prepare(&EngineBuildingOptions{
+			Packages:  []string{"web"},
+			TaskNames: []string{"build"},
+		})
+		assert.NilError(t, err, "Prepare")
+		assert.DeepEqual(t, dependenciesOf(engine, "web#build"), []string{"//#lint"})
+	})
+
+	t.Run("root task is not defined", func(t *testing.T) {
+		engine := newTestEngine(t, false, map[string]string{
+			"//": `{"pipeline": {
+				"build": {"dependsOn": ["//lint"]}
+			}}`,
+			"web": `{"extends": ["//"], "pipeline": {}}`,
+		})
+		err := engine.Prepare(&EngineBuildingOptions{
+			Packages:  []string{"web"},
+			TaskNames: []string{"build"},
+		})
+		assert.Error(t, err, "web#build depends on //#lint, which is not defined in the root turbo.json")
+	})
+}
@@ -74,3 +74,20 @@ func GetHashableEnvPairs(envKeys []string, envPrefixes []string) []string {
 	sort.Strings(allHashableEnvPairs)
 	return allHashableEnvPairs
 }
+
+// StrictEnvVars are the environment variables that are passed to tasks in strict env
+// mode even when they don't declare them, since processes can't run without them
+var StrictEnvVars = []string{"PATH", "SHELL", "SYSTEMROOT"}
+
+// FilterEnviron returns the key=value pairs of environ (as from os.Environ) whose
+// keys are in envKeys, in the order of environ
+func FilterEnviron(environ []string, envKeys []string) []string {
+	keys := util.SetFromStrings(envKeys)
+	filtered := []string{}
+	for _, envVar := range environ {
+		if i := strings.Index(envVar, "="); i >= 0 && keys.Includes(envVar[:i]) {
+			filtered = append(filtered, envVar)
+		}
+	}
+	return filtered
+}
@@ -289,3 +289,12 @@ func TestGetHashableEnvPairs(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterEnviron(t *testing.T) {
+	environ := []string{"PATH=/usr/bin", "a=1=2", "b=2", "SECRET=shh", "c"}
+	got := FilterEnviron(environ, []string{"a", "b", "PATH", "c", "missing"})
+	want := []string{"PATH=/usr/bin", "a=1=2", "b=2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterEnviron() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+package fs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+const (
+	envWildcard       = "*"
+	envNegation       = "!"
+	envEscapeSequence = "\\*"
+	envDollarEscape   = "\\$"
+)
+
+// envPattern is a parsed entry from an "env" or "globalEnv" list.
+// E.g. "MY_APP_*" matches every env var starting with "MY_APP_",
+// "!MY_APP_SECRET" excludes a specific var from the matches, and
+// "MY\*VAR" matches the literal name "MY*VAR".
+type envPattern struct {
+	literal  string
+	wildcard bool
+	negated  bool
+}
+
+// parseEnvPattern parses a raw env declaration into an envPattern. A "*" is only
+// allowed as the last character, unless it is escaped as "\*".
+func parseEnvPattern(value string) (envPattern, error) {
+	pattern := envPattern{}
+	body := value
+	if strings.HasPrefix(body, envNegation) {
+		pattern.negated = true
+		body = strings.TrimPrefix(body, envNegation)
+	}
+
+	var literal strings.Builder
+	for i := 0; i < len(body); i++ {
+		if strings.HasPrefix(body[i:], envEscapeSequence) {
+			literal.WriteString(envWildcard)
+			i++
+			continue
+		}
+		if body[i] == envWildcard[0] {
+			if i != len(body)-1 {
+				return envPattern{}, fmt.Errorf("Invalid wildcard in \"%s\". Only a trailing \"*\" is supported, use \"\\*\" to match a literal \"*\"", value)
+			}
+			pattern.wildcard = true
+			continue
+		}
+		literal.WriteByte(body[i])
+	}
+	pattern.literal = literal.String()
+
+	return pattern, nil
+}
+
+// matches returns true if the given env var name is matched by the pattern,
+// ignoring whether or not the pattern is negated.
+func (p envPattern) matches(name string) bool {
+	if p.wildcard {
+		return strings.HasPrefix(name, p.literal)
+	}
+	return name == p.literal
+}
+
+// parseEnvDeclaration checks a single entry in an "env"-style key and returns it
+// with a leading "\$" unescaped, so that "\$FOO" declares a variable named "$FOO".
+func parseEnvDeclaration(key string, value string) (string, error) {
+	negation := ""
+	body := value
+	if strings.HasPrefix(body, envNegation) {
+		negation = envNegation
+		body = strings.TrimPrefix(body, envNegation)
+	}
+
+	if strings.HasPrefix(body, envDollarEscape) {
+		body = strings.TrimPrefix(body, "\\")
+	} else if strings.HasPrefix(body, envPipelineDelimiter) {
+		// Hard error to help people specify this correctly during migration.
+		// TODO: Remove this error after we have run summary.
+		return "", fmt.Errorf("You specified \"%s\" in the \"%s\" key. You should not prefix your environment variables with \"%s\". If the variable name starts with \"%s\", escape it as \"%s\"", value, key, envPipelineDelimiter, envPipelineDelimiter, envDollarEscape)
+	}
+
+	unescaped := negation + body
+	if _, err := parseEnvPattern(unescaped); err != nil {
+		return "", err
+	}
+	return unescaped, nil
+}
+
+// escapeEnvDeclaration is the inverse of parseEnvDeclaration
+func escapeEnvDeclaration(value string) string {
+	negation := ""
+	body := value
+	if strings.HasPrefix(body, envNegation) {
+		negation = envNegation
+		body = strings.TrimPrefix(body, envNegation)
+	}
+	if strings.HasPrefix(body, envPipelineDelimiter) {
+		body = "\\" + body
+	}
+	return negation + body
+}
+
+// escapeEnvDeclarations escapes every entry in values
+func escapeEnvDeclarations(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeEnvDeclaration(value)
+	}
+	return escaped
+}
+
+// MatchEnvVarPatterns returns the sorted list of names matched by the given env
+// patterns. Negated patterns take precedence over inclusions regardless of the
+// order in which they were declared.
+func MatchEnvVarPatterns(patterns []string, names []string) ([]string, error) {
+	inclusions := []envPattern{}
+	exclusions := []envPattern{}
+	for _, value := range patterns {
+		pattern, err := parseEnvPattern(value)
+		if err != nil {
+			return nil, err
+		}
+		if pattern.negated {
+			exclusions = append(exclusions, pattern)
+		} else {
+			inclusions = append(inclusions, pattern)
+		}
+	}
+
+	matched := []string{}
+	for _, name := range names {
+		if matchesAny(inclusions, name) && !matchesAny(exclusions, name) {
+			matched = append(matched, name)
+		}
+	}
+	sort.Strings(matched)
+
+	return matched, nil
+}
+
+// ResolveEnvVarPatterns returns the sorted names of the env vars that patterns declare:
+// every literal name, whether or not it is set, and every name in envNames (e.g. from
+// EnvVarNames) that a wildcard matches, without the names that are negated. This is
+// the list of env vars that affect a hash.
+func ResolveEnvVarPatterns(patterns []string, envNames []string) ([]string, error) {
+	names := util.SetFromStrings(envNames)
+	for _, value := range patterns {
+		pattern, err := parseEnvPattern(value)
+		if err != nil {
+			return nil, err
+		}
+		if !pattern.negated && !pattern.wildcard {
+			names.Add(pattern.literal)
+		}
+	}
+	return MatchEnvVarPatterns(patterns, names.UnsafeListOfStrings())
+}
+
+// EnvVarNames returns the names of the variables in environ, which is in the
+// "key=value" form of os.Environ()
+func EnvVarNames(environ []string) []string {
+	names := make([]string, 0, len(environ))
+	for _, envVar := range environ {
+		if i := strings.Index(envVar, "="); i >= 0 {
+			names = append(names, envVar[:i])
+		}
+	}
+	return names
+}
+
+func matchesAny(patterns []envPattern, name string) bool {
+	for _, pattern := range patterns {
+		if pattern.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingEnvVarValue is the value EnvSnapshot reports for a declared env var that is not set
+const MissingEnvVarValue = "<unset>"
+
+// EnvSnapshot resolves the EnvVarDependencies of the task to the values they have in
+// the environment, to help debug unexpected changes in the task's hash. Values are read
+// with lookup (e.g. os.LookupEnv), and declared names that lookup doesn't find map to
+// MissingEnvVarValue. Wildcards can't be expanded with a lookup, so they are skipped,
+// see ExpandedEnvSnapshot.
+func (td TaskDefinition) EnvSnapshot(lookup func(string) (string, bool)) map[string]string {
+	return td.ExpandedEnvSnapshot(nil, lookup)
+}
+
+// ExpandedEnvSnapshot is EnvSnapshot with wildcards expanded against envNames (e.g. from
+// EnvVarNames), the same way as when the task is hashed
+func (td TaskDefinition) ExpandedEnvSnapshot(envNames []string, lookup func(string) (string, bool)) map[string]string {
+	snapshot := map[string]string{}
+	// Invalid patterns are rejected when the TaskDefinition is parsed
+	names, err := ResolveEnvVarPatterns(td.EnvVarDependencies, envNames)
+	if err != nil {
+		return snapshot
+	}
+	for _, name := range names {
+		if value, ok := lookup(name); ok {
+			snapshot[name] = value
+		} else {
+			snapshot[name] = MissingEnvVarValue
+		}
+	}
+
+	return snapshot
+}
@@ -0,0 +1,190 @@
+package fs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MatchEnvVarPatterns(t *testing.T) {
+	names := []string{"MY_APP_URL", "MY_APP_SECRET", "MY_APPLE", "OTHER", "LITERAL*", "LITERAL_VAR"}
+
+	testCases := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{
+			name:     "exact names",
+			patterns: []string{"OTHER"},
+			expected: []string{"OTHER"},
+		},
+		{
+			name:     "prefix matching",
+			patterns: []string{"MY_APP_*"},
+			expected: []string{"MY_APP_SECRET", "MY_APP_URL"},
+		},
+		{
+			name:     "bare wildcard",
+			patterns: []string{"*"},
+			expected: []string{"LITERAL*", "LITERAL_VAR", "MY_APPLE", "MY_APP_SECRET", "MY_APP_URL", "OTHER"},
+		},
+		{
+			name:     "negation after inclusion",
+			patterns: []string{"MY_APP*", "!MY_APP_SECRET"},
+			expected: []string{"MY_APPLE", "MY_APP_URL"},
+		},
+		{
+			name:     "negation takes precedence regardless of order",
+			patterns: []string{"!MY_APP_*", "MY_APP_URL", "MY_APPLE"},
+			expected: []string{"MY_APPLE"},
+		},
+		{
+			name:     "escaped asterisk is literal",
+			patterns: []string{"LITERAL\\*"},
+			expected: []string{"LITERAL*"},
+		},
+		{
+			name:     "no matches",
+			patterns: []string{"NOPE_*"},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, err := MatchEnvVarPatterns(tc.patterns, names)
+			assert.NoError(t, err)
+			assert.EqualValues(t, tc.expected, matched)
+		})
+	}
+}
+
+func Test_MatchEnvVarPatterns_InvalidWildcard(t *testing.T) {
+	_, err := MatchEnvVarPatterns([]string{"MY_*_VAR"}, []string{"MY_APP_VAR"})
+	assert.EqualError(t, err, "Invalid wildcard in \"MY_*_VAR\". Only a trailing \"*\" is supported, use \"\\*\" to match a literal \"*\"")
+}
+
+func Test_ResolveEnvVarPatterns(t *testing.T) {
+	envNames := EnvVarNames([]string{"MY_APP_URL=https://example.com", "MY_APP_SECRET=hunter2", "MY_APPLE=1", "NODE_ENV=", "OTHER=a=b"})
+	assert.EqualValues(t, []string{"MY_APP_URL", "MY_APP_SECRET", "MY_APPLE", "NODE_ENV", "OTHER"}, envNames)
+
+	// Literal names are declared whether or not they are set, wildcards only match
+	// the variables that are, and negations exclude from both
+	resolved, err := ResolveEnvVarPatterns([]string{"MY_APP_*", "!MY_APP_SECRET", "API_KEY", "!NODE_ENV", "NODE_ENV", "MY\\*VAR"}, envNames)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"API_KEY", "MY*VAR", "MY_APP_URL"}, resolved)
+
+	resolved, err = ResolveEnvVarPatterns(nil, envNames)
+	assert.NoError(t, err)
+	assert.Empty(t, resolved)
+
+	_, err = ResolveEnvVarPatterns([]string{"MY_*_VAR"}, envNames)
+	assert.EqualError(t, err, "Invalid wildcard in \"MY_*_VAR\". Only a trailing \"*\" is supported, use \"\\*\" to match a literal \"*\"")
+}
+
+func Test_EnvWildcardDeclarations(t *testing.T) {
+	var turboJSON TurboJSON
+	err := json.Unmarshal([]byte(`{
+		"globalEnv": ["GLOBAL_*", "!GLOBAL_SECRET"],
+		"pipeline": {
+			"build": {
+				"env": ["MY_APP_*", "!MY_APP_SECRET", "LITERAL\\*"]
+			}
+		}
+	}`), &turboJSON)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"!GLOBAL_SECRET", "GLOBAL_*"}, turboJSON.GlobalEnv)
+	assert.EqualValues(t, []string{"!MY_APP_SECRET", "LITERAL\\*", "MY_APP_*"}, turboJSON.Pipeline["build"].TaskDefinition.EnvVarDependencies)
+}
+
+func Test_EnvWildcardDeclarations_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected string
+	}{
+		{
+			name:     "wildcard in the middle",
+			json:     `{"pipeline": {"build": {"env": ["MY_*_VAR"]}}}`,
+			expected: "Invalid wildcard in \"MY_*_VAR\". Only a trailing \"*\" is supported, use \"\\*\" to match a literal \"*\"",
+		},
+		{
+			name:     "negated $-prefixed var",
+			json:     `{"pipeline": {"build": {"env": ["!$MY_VAR"]}}}`,
+			expected: "You specified \"!$MY_VAR\" in the \"env\" key. You should not prefix your environment variables with \"$\". If the variable name starts with \"$\", escape it as \"\\$\"",
+		},
+		{
+			name:     "$-prefixed global var",
+			json:     `{"globalEnv": ["$MY_VAR*"], "pipeline": {}}`,
+			expected: "You specified \"$MY_VAR*\" in the \"env\" key. You should not prefix your environment variables with \"$\". If the variable name starts with \"$\", escape it as \"\\$\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var turboJSON TurboJSON
+			err := json.Unmarshal([]byte(tc.json), &turboJSON)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func Test_EnvSnapshot(t *testing.T) {
+	env := map[string]string{
+		"NODE_ENV":      "production",
+		"MY_APP_URL":    "https://example.com",
+		"MY_APP_SECRET": "hunter2",
+		"OTHER":         "other",
+	}
+	envNames := []string{}
+	for name := range env {
+		envNames = append(envNames, name)
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	taskDefinition := TaskDefinition{EnvVarDependencies: []string{"!MY_APP_SECRET", "API_KEY", "MY_APP_*", "NODE_ENV"}}
+	assert.Equal(t, map[string]string{
+		"API_KEY":  MissingEnvVarValue,
+		"NODE_ENV": "production",
+	}, taskDefinition.EnvSnapshot(lookup))
+	assert.Equal(t, map[string]string{
+		"API_KEY":    MissingEnvVarValue,
+		"MY_APP_URL": "https://example.com",
+		"NODE_ENV":   "production",
+	}, taskDefinition.ExpandedEnvSnapshot(envNames, lookup))
+
+	assert.Empty(t, TaskDefinition{}.EnvSnapshot(lookup))
+	assert.Empty(t, TaskDefinition{}.ExpandedEnvSnapshot(envNames, lookup))
+}
+
+func Test_EscapedDollarEnvDeclarations(t *testing.T) {
+	var turboJSON TurboJSON
+	err := json.Unmarshal([]byte(`{
+		"globalEnv": ["\\$GLOBAL_VAR"],
+		"pipeline": {"build": {"env": ["\\$MY_VAR", "!\\$MY_SECRET", "NODE_ENV"]}}
+	}`), &turboJSON)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"$GLOBAL_VAR"}, turboJSON.GlobalEnv)
+	assert.EqualValues(t, []string{"!$MY_SECRET", "$MY_VAR", "NODE_ENV"}, turboJSON.Pipeline["build"].TaskDefinition.EnvVarDependencies)
+
+	matched, err := MatchEnvVarPatterns(turboJSON.Pipeline["build"].TaskDefinition.EnvVarDependencies, []string{"$MY_VAR", "$MY_SECRET", "MY_VAR"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"$MY_VAR"}, matched)
+
+	// The escape is restored when writing the config back out
+	data, err := json.Marshal(&turboJSON)
+	assert.NoError(t, err)
+	var roundTripped TurboJSON
+	err = json.Unmarshal(data, &roundTripped)
+	assert.NoError(t, err)
+	assert.EqualValues(t, turboJSON.GlobalEnv, roundTripped.GlobalEnv)
+	assert.EqualValues(t, turboJSON.Pipeline["build"].TaskDefinition.EnvVarDependencies, roundTripped.Pipeline["build"].TaskDefinition.EnvVarDependencies)
+
+	err = json.Unmarshal([]byte(`{"pipeline": {"build": {"env": ["$MY_VAR"]}}}`), &turboJSON)
+	assert.EqualError(t, err, "You specified \"$MY_VAR\" in the \"env\" key. You should not prefix your environment variables with \"$\". If the variable name starts with \"$\", escape it as \"\\$\"")
+}
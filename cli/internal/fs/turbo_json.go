@@ -1,93 +1,270 @@
 package fs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/muhammadmuzzammil1998/jsonc"
 	"github.com/pkg/errors"
 	"github.com/vercel/turbo/cli/internal/turbopath"
 	"github.com/vercel/turbo/cli/internal/util"
+	"github.com/vercel/turbo/cli/internal/yaml"
 )
 
 const (
 	configFile                   = "turbo.json"
 	envPipelineDelimiter         = "$"
 	topologicalPipelineDelimiter = "^"
+	// turboDefaultInputs is a special entry in "inputs" for the files turbo hashes by default
+	turboDefaultInputs = "$TURBO_DEFAULT$"
+	// turboRootPrefix is a special prefix in "outputs" for globs that are relative to the
+	// repository root instead of the package, e.g. "$TURBO_ROOT$/coverage/**"
+	turboRootPrefix = "$TURBO_ROOT$"
+	// inheritedDependsOn is a special entry in "dependsOn" for the dependencies of the
+	// configurations that this extends, so that they are added to instead of replaced
+	inheritedDependsOn = "..."
+	// inheritedInputs is a special entry in "inputs" for the inputs of the configurations
+	// that this extends, so that they are added to instead of replaced
+	inheritedInputs = "..."
+	// configFileYAML is read instead of configFile if only it exists
+	configFileYAML = "turbo.yaml"
+	// defaultTaskWeight is the number of concurrency slots a task takes up by default
+	defaultTaskWeight = 1
+
+	deprecatedEnvInDependsOn          = "Declaring an environment variable in \"dependsOn\" is deprecated, found %s. Use the \"env\" key or use `npx @turbo/codemod migrate-env-var-dependencies`."
+	deprecatedEnvInGlobalDependencies = "Declaring an environment variable in \"globalDependencies\" is deprecated, found %s. Use the \"globalEnv\" key or use `npx @turbo/codemod migrate-env-var-dependencies`."
 )
 
+// ParseOptions controls how strictly configFile is parsed
+type ParseOptions struct {
+	// StrictDeprecations makes deprecated constructs an error instead of a logged warning
+	StrictDeprecations bool
+	// DisallowUnknownFields makes unknown keys (e.g. a misspelled "outptus") an error
+	// instead of being ignored
+	DisallowUnknownFields bool
+	// Conditions are the names of the "overrides" (e.g. "ci") that are merged over the
+	// pipeline when it is loaded, in order. Conditions without overrides are ignored.
+	Conditions []string
+	// LookupEnv resolves references to environment variables (e.g. "$VERCEL_TEAM_ID")
+	// in the string values of "remoteCache" when configFile is loaded. If it is nil,
+	// the values are used as written.
+	LookupEnv func(key string) (string, bool)
+	// SilenceWarnings stops the warnings found while loading configFile from being
+	// logged, for callers that report TurboJSON.Warnings themselves
+	SilenceWarnings bool
+	// StrictRemoteCacheOptions makes the problems found by ValidateRemoteCacheOptions
+	// (e.g. a malformed "remoteCache.teamId") an error when configFile is loaded, instead
+	// of leaving it to the caller to warn about them
+	StrictRemoteCacheOptions bool
+}
+
 type rawTurboJSON struct {
+	// The JSON Schema that editors validate configFile against
+	Schema string `json:"$schema,omitempty"`
 	// Global root filesystem dependencies
 	GlobalDependencies []string `json:"globalDependencies,omitempty"`
 	// Global env
 	GlobalEnv []string `json:"globalEnv,omitempty"`
+	// Global passthrough env
+	GlobalPassThroughEnv []string `json:"globalPassThroughEnv,omitempty"`
+	// Global .env files
+	GlobalDotEnv []string `json:"globalDotEnv,omitempty"`
 	// Pipeline is a map of Turbo pipeline entries which define the task graph
 	// and cache behavior on a per task or per package-task basis.
 	Pipeline Pipeline `json:"pipeline"`
 	// Configuration options when interfacing with the remote cache
 	RemoteCacheOptions RemoteCacheOptions `json:"remoteCache,omitempty"`
+	// The outputMode of every task in the pipeline that doesn't set its own
+	OutputMode *util.TaskOutputMode `json:"outputMode,omitempty"`
+	// The envMode of every task in the pipeline that doesn't set its own
+	EnvMode *util.EnvMode `json:"envMode,omitempty"`
+	// Root package.json scripts that are not turned into tasks in single-package repositories
+	ExcludeScripts []string `json:"excludeScripts,omitempty"`
+	// Feature flags for experimental features, see turbo_json_experimental.go
+	Experimental map[string]json.RawMessage `json:"experimental,omitempty"`
+	// Partial pipelines that are merged over Pipeline when their condition is active
+	Overrides map[string]Pipeline `json:"overrides,omitempty"`
 
 	// Extends can be the name of another workspace
 	Extends []string `json:"extends,omitempty"`
 }
 
 // pristineTurboJSON is used when marshaling a TurboJSON object into a turbo.json string
-// Notably, it includes a PristinePipeline instead of the regular Pipeline. (i.e. TaskDefinition
-// instead of BookkeepingTaskDefinition.)
+// Notably, its pipeline holds the rawTaskWithDefaults of each TaskDefinition instead of
+// the regular Pipeline. (i.e. without the bookkeeping of BookkeepingTaskDefinition.)
 type pristineTurboJSON struct {
-	GlobalDependencies []string           `json:"globalDependencies,omitempty"`
-	GlobalEnv          []string           `json:"globalEnv,omitempty"`
-	Pipeline           PristinePipeline   `json:"pipeline"`
-	RemoteCacheOptions RemoteCacheOptions `json:"remoteCache,omitempty"`
-	Extends            []string           `json:"extends,omitempty"`
+	Schema               string                         `json:"$schema,omitempty"`
+	GlobalDependencies   []string                       `json:"globalDependencies,omitempty"`
+	GlobalEnv            []string                       `json:"globalEnv,omitempty"`
+	GlobalPassThroughEnv []string                       `json:"globalPassThroughEnv,omitempty"`
+	GlobalDotEnv         []string                       `json:"globalDotEnv,omitempty"`
+	Pipeline             map[string]rawTaskWithDefaults `json:"pipeline"`
+	RemoteCacheOptions   RemoteCacheOptions             `json:"remoteCache,omitempty"`
+	OutputMode           *util.TaskOutputMode           `json:"outputMode,omitempty"`
+	EnvMode              *util.EnvMode                  `json:"envMode,omitempty"`
+	ExcludeScripts       []string                       `json:"excludeScripts,omitempty"`
+	Experimental         map[string]json.RawMessage     `json:"experimental,omitempty"`
+	Overrides            map[string]definedPipeline     `json:"overrides,omitempty"`
+	Extends              []string                       `json:"extends,omitempty"`
 }
 
+// definedPipeline is a pipeline with only the keys that each task sets, so that it
+// can be merged over another pipeline after a round trip
+type definedPipeline map[string]map[string]json.RawMessage
+
 // TurboJSON represents a turbo.json configuration file
 type TurboJSON struct {
-	GlobalDeps         []string
-	GlobalEnv          []string
+	// Schema is the "$schema" that editors use to validate configFile. It is only
+	// preserved, turbo doesn't use it.
+	Schema               string
+	GlobalDeps           []string
+	GlobalEnv            []string
+	GlobalPassThroughEnv []string
+	// GlobalDotEnv are .env files, relative to the repository root, whose contents affect
+	// the hash of every task. Later files take precedence, so the order is preserved.
+	GlobalDotEnv       []string
 	Pipeline           Pipeline
 	RemoteCacheOptions RemoteCacheOptions
+	// OutputMode is the default outputMode for tasks. nil means tasks default to full output.
+	OutputMode *util.TaskOutputMode
+	// EnvMode is the default envMode for tasks. nil means tasks default to loose.
+	EnvMode *util.EnvMode
+	// ExcludeScripts are root package.json scripts (e.g. "postinstall") that are not
+	// synthesized into tasks in single-package repositories
+	ExcludeScripts []string
+	// Experimental holds the raw values of the "experimental" feature flags. Use the
+	// typed accessors (e.g. ExperimentalUI) to read the ones turbo knows about.
+	Experimental map[string]json.RawMessage
+	// Overrides are partial pipelines, keyed by the name of a condition (e.g. "ci"), that
+	// are merged over Pipeline by ApplyOverrides when the condition is active
+	Overrides map[string]Pipeline
 
 	// A list of Workspace names
 	Extends []string
+
+	// remoteCacheFields are the keys that were set in .remoteCache of configFile
+	remoteCacheFields util.Set
+	// warnings are the problems found while loading the configuration that don't
+	// prevent it from being used, e.g. deprecated keys
+	warnings []string
+	// workspaceName is the name this was resolved under by ResolveExtends, if any
+	workspaceName string
 }
 
 // RemoteCacheOptions is a struct for deserializing .remoteCache of configFile
 type RemoteCacheOptions struct {
 	TeamID    string `json:"teamId,omitempty"`
 	Signature bool   `json:"signature,omitempty"`
+	// APIURL overrides the endpoint of the remote cache, e.g. for self-hosted caches
+	APIURL string `json:"apiUrl,omitempty"`
+	// Enabled toggles remote caching. nil means the CLI and environment defaults apply.
+	Enabled *bool `json:"enabled,omitempty"`
 }
 
 // rawTaskWithDefaults exists to Marshal (i.e. turn a TaskDefinition into json).
 // We use this for printing ResolvedTaskConfiguration, because we _want_ to show
 // the user the default values for key they have not configured.
 type rawTaskWithDefaults struct {
-	Outputs    []string            `json:"outputs"`
-	Cache      *bool               `json:"cache"`
-	DependsOn  []string            `json:"dependsOn"`
-	Inputs     []string            `json:"inputs"`
-	OutputMode util.TaskOutputMode `json:"outputMode"`
-	Env        []string            `json:"env"`
-	Persistent bool                `json:"persistent"`
+	Outputs             []string            `json:"outputs"`
+	Cache               interface{}         `json:"cache"`
+	CacheDisabledReason string              `json:"cacheDisabledReason,omitempty"`
+	CacheKey            string              `json:"cacheKey,omitempty"`
+	DependsOn           []string            `json:"dependsOn"`
+	Inputs              []string            `json:"inputs"`
+	OutputMode          util.TaskOutputMode `json:"outputMode"`
+	OutputLogs          util.TaskOutputLogs `json:"outputLogs,omitempty"`
+	EnvMode             *util.EnvMode       `json:"envMode,omitempty"`
+	Env                 []string            `json:"env"`
+	PassThroughEnv      []string            `json:"passThroughEnv,omitempty"`
+	Persistent          bool                `json:"persistent"`
+	Interactive         bool                `json:"interactive,omitempty"`
+	With                []string            `json:"with,omitempty"`
+	DotEnv              []string            `json:"dotEnv,omitempty"`
+	Timeout             string              `json:"timeout,omitempty"`
+	Weight              int                 `json:"weight,omitempty"`
+	Retries             int                 `json:"retries,omitempty"`
+	Description         string              `json:"description,omitempty"`
 }
 
 // rawTask exists to Unmarshal from json. When fields are omitted, we _want_
 // them to be missing, so that we can distinguish missing from empty value.
 type rawTask struct {
-	Outputs    []string             `json:"outputs,omitempty"`
-	Cache      *bool                `json:"cache,omitempty"`
-	DependsOn  []string             `json:"dependsOn,omitempty"`
-	Inputs     []string             `json:"inputs,omitempty"`
-	OutputMode *util.TaskOutputMode `json:"outputMode,omitempty"`
-	Env        []string             `json:"env,omitempty"`
-	Persistent *bool                `json:"persistent,omitempty"`
+	Outputs             []string             `json:"outputs,omitempty"`
+	OutputsExclude      []string             `json:"outputsExclude,omitempty"`
+	Cache               *rawCacheConfig      `json:"cache,omitempty"`
+	CacheDisabledReason *string              `json:"cacheDisabledReason,omitempty"`
+	CacheKey            *string              `json:"cacheKey,omitempty"`
+	DependsOn           []string             `json:"dependsOn,omitempty"`
+	Inputs              []string             `json:"inputs,omitempty"`
+	OutputMode          *util.TaskOutputMode `json:"outputMode,omitempty"`
+	OutputLogs          *util.TaskOutputLogs `json:"outputLogs,omitempty"`
+	EnvMode             *util.EnvMode        `json:"envMode,omitempty"`
+	Env                 []string             `json:"env,omitempty"`
+	PassThroughEnv      []string             `json:"passThroughEnv,omitempty"`
+	Persistent          *bool                `json:"persistent,omitempty"`
+	Interactive         *bool                `json:"interactive,omitempty"`
+	With                []string             `json:"with,omitempty"`
+	DotEnv              []string             `json:"dotEnv,omitempty"`
+	Timeout             *string              `json:"timeout,omitempty"`
+	Weight              *int                 `json:"weight,omitempty"`
+	Retries             *int                 `json:"retries,omitempty"`
+	Description         *string              `json:"description,omitempty"`
+}
+
+// rawCacheConfig exists to Unmarshal the "cache" key of a task, which is either a bool
+// that toggles all caching, or an object that toggles local and remote caching separately.
+// A key that is omitted from the object form is enabled.
+type rawCacheConfig struct {
+	Local  *bool `json:"local,omitempty"`
+	Remote *bool `json:"remote,omitempty"`
+}
+
+// UnmarshalJSON deserializes either form of the "cache" key
+func (c *rawCacheConfig) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		c.Local = &enabled
+		c.Remote = &enabled
+		return nil
+	}
+
+	// Use a type without this UnmarshalJSON method to parse the object form
+	type cacheObject rawCacheConfig
+	object := cacheObject{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("\"cache\" must be a boolean or an object with \"local\" and \"remote\" keys: %w", err)
+	}
+	*c = rawCacheConfig(object)
+	return nil
+}
+
+// invalidTaskValueError is returned when a key of a task has a value of the right type
+// that is not allowed, e.g. a "timeout" that is not a valid duration.
+// Pipeline.UnmarshalJSON adds the name of the task to it.
+type invalidTaskValueError struct {
+	key string
+	// value is the offending value as it appears in json
+	value string
+	err   error
+}
+
+func (e *invalidTaskValueError) Error() string {
+	return fmt.Sprintf("invalid \"%s\" value %s: %v", e.key, e.value, e.err)
+}
+
+func (e *invalidTaskValueError) Unwrap() error {
+	return e.err
 }
 
 // PristinePipeline contains original TaskDefinitions without the bookkeeping
@@ -96,80 +273,508 @@ type PristinePipeline map[string]TaskDefinition
 // Pipeline is a struct for deserializing .pipeline in configFile
 type Pipeline map[string]BookkeepingTaskDefinition
 
+// UnmarshalJSON deserializes .pipeline in configFile, naming the task in errors
+// that would otherwise be ambiguous.
+func (pc *Pipeline) UnmarshalJSON(data []byte) error {
+	var rawPipeline map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawPipeline); err != nil {
+		return err
+	}
+	if rawPipeline == nil {
+		*pc = nil
+		return nil
+	}
+
+	pipeline := make(Pipeline, len(rawPipeline))
+	for _, taskName := range sortedRawPipelineKeys(rawPipeline) {
+		var bookkeepingTaskDef BookkeepingTaskDefinition
+		if err := json.Unmarshal(rawPipeline[taskName], &bookkeepingTaskDef); err != nil {
+			var valueErr *invalidTaskValueError
+			if errors.As(err, &valueErr) {
+				return fmt.Errorf("task \"%s\": %w", taskName, err)
+			}
+			return err
+		}
+		pipeline[taskName] = bookkeepingTaskDef
+	}
+	*pc = pipeline
+	return nil
+}
+
+func sortedRawPipelineKeys(rawPipeline map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(rawPipeline))
+	for key := range rawPipeline {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // BookkeepingTaskDefinition holds the underlying TaskDefinition and some bookkeeping data
 // about the TaskDefinition. This wrapper struct allows us to leave TaskDefinition untouched.
 type BookkeepingTaskDefinition struct {
-	definedFields  util.Set
+	definedFields util.Set
+	deletedFields util.Set
+	// inheritsDependsOn is true if dependsOn includes inheritedDependsOn
+	inheritsDependsOn bool
+	// inheritsInputs is true if inputs includes inheritedInputs
+	inheritsInputs bool
+	// warnings are the problems found while parsing the task that don't prevent it
+	// from being used
+	warnings []string
+	// defaultOutputMode and defaultEnvMode are the global "outputMode" and "envMode" of the
+	// configFile the task is from, if the task doesn't set its own. They only apply if no
+	// configuration sets the task's.
+	defaultOutputMode *util.TaskOutputMode
+	defaultEnvMode    *util.EnvMode
+	// Description is the comment immediately preceding the task in configFile, if any
+	Description    string
 	TaskDefinition TaskDefinition
 }
 
+// nullableTaskFields maps the keys of rawTask to the bookkeeping fields that are
+// removed from the merged TaskDefinition when the key is explicitly set to null
+var nullableTaskFields = map[string][]string{
+	"outputs":             {"Outputs"},
+	"cache":               {"ShouldCache"},
+	"cacheDisabledReason": {"CacheDisabledReason"},
+	"cacheKey":            {"CacheKey"},
+	"dependsOn":           {"TopologicalDependencies", "TaskDependencies", "RootTaskDependencies", "TaskDependencyGlobs"},
+	"inputs":              {"Inputs"},
+	"outputMode":          {"OutputMode"},
+	"outputLogs":          {"OutputLogs"},
+	"envMode":             {"EnvMode"},
+	"env":                 {"EnvVarDependencies"},
+	"passThroughEnv":      {"PassThroughEnv"},
+	"persistent":          {"Persistent"},
+	"interactive":         {"Interactive"},
+	"with":                {"With"},
+	"dotEnv":              {"DotEnv"},
+	"timeout":             {"Timeout"},
+	"weight":              {"Weight"},
+	"retries":             {"Retries"},
+	"description":         {"Description"},
+}
+
+// CacheConfig controls where the outputs of a task are cached
+type CacheConfig struct {
+	Local  bool
+	Remote bool
+}
+
 // TaskDefinition is a representation of the configFile pipeline for further computation.
 type TaskDefinition struct {
-	Outputs     TaskOutputs
+	Outputs TaskOutputs
+
+	// ExplicitOutputs is true if the task declared "outputs", even as an empty list, so
+	// that "outputs": [] (a cached task that only has logs) can be told apart from unset
+	// outputs. Note that both are marshalled as an empty list.
+	ExplicitOutputs bool
+
+	// ShouldCache is true if the task is cached either locally or remotely
 	ShouldCache bool
 
+	// Cache toggles local and remote caching of the task separately.
+	// This field is custom-marshalled from rawTask.Cache together with ShouldCache
+	Cache CacheConfig
+
+	// CacheDisabledReason is an informational note explaining why the task is not cached.
+	// It has no effect on behavior and is only meaningful when ShouldCache is false.
+	CacheDisabledReason string
+
+	// CacheKey is included in the hash of the Task, so that changing it busts the cache
+	// for changes that turbo can't detect (e.g. an updated toolchain)
+	CacheKey string
+
 	// This field is custom-marshalled from rawTask.Env and rawTask.DependsOn
 	EnvVarDependencies []string
 
+	// PassThroughEnv are env vars that are made available to the task's process
+	// but are not included in the task's hash.
+	PassThroughEnv []string
+
 	// TopologicalDependencies are tasks from package dependencies.
 	// E.g. "build" is a topological dependency in:
 	// dependsOn: ['^build'].
 	// This field is custom-marshalled from rawTask.DependsOn
 	TopologicalDependencies []string
 
-	// TaskDependencies are anything that is not a topological dependency
-	// E.g. both something and //whatever are TaskDependencies in:
-	// dependsOn: ['something', '//whatever']
+	// TaskDependencies are anything that is not a topological or root task dependency
+	// E.g. both something and //#whatever are TaskDependencies in:
+	// dependsOn: ['something', '//#whatever']
 	// This field is custom-marshalled from rawTask.DependsOn
 	TaskDependencies []string
 
+	// RootTaskDependencies are tasks in the root workspace.
+	// E.g. "lint" is a root task dependency in:
+	// dependsOn: ['//lint']
+	// This field is custom-marshalled from rawTask.DependsOn
+	RootTaskDependencies []string
+
+	// TaskDependencyGlobs are task dependencies with wildcards, which match several
+	// tasks in the pipeline. E.g. "test:*" matches "test:unit" and "test:e2e" in:
+	// dependsOn: ['test:*']
+	// Use ResolveTaskDependencies to expand them.
+	// This field is custom-marshalled from rawTask.DependsOn
+	TaskDependencyGlobs []string
+
 	// Inputs indicate the list of files this Task depends on. If any of those files change
 	// we can conclude that any cached outputs or logs for this Task should be invalidated.
 	Inputs []string
 
+	// DefaultInputs is true if the default set of files (all tracked files in the package)
+	// should be hashed in addition to Inputs.
+	// This field is custom-marshalled from the turboDefaultInputs entry in rawTask.Inputs
+	DefaultInputs bool
+
+	// ExplicitInputs is true if the task declared "inputs", even as an empty list, so
+	// that "inputs": [] can be told apart from unset inputs, which hash every file in
+	// the package. Note that both are marshalled as an empty list.
+	ExplicitInputs bool
+
 	// OutputMode determins how we should log the output.
 	OutputMode util.TaskOutputMode
 
+	// OutputLogs determines how task logs are written to the cache. This is
+	// separate from OutputMode, which only affects the terminal.
+	OutputLogs util.TaskOutputLogs
+
+	// EnvMode determines whether the Task's hash includes the environment variables
+	// inferred from its framework, or only the ones it declares. In strict mode the
+	// Task's process also only receives the declared and global environment variables.
+	EnvMode util.EnvMode
+
 	// Persistent indicates whether the Task is expected to exit or not
 	// Tasks marked Persistent do not exit (e.g. --watch mode or dev servers)
 	Persistent bool
+
+	// Interactive indicates that the Task reads from stdin (e.g. it prompts the user),
+	// so stdin should be connected to the Task directly
+	Interactive bool
+
+	// With are tasks to start alongside this Task (e.g. a mock server for tests).
+	// Unlike dependencies, they don't need to finish first and don't affect the hash.
+	With []string
+
+	// DotEnv are .env files, relative to the package, whose contents affect the hash.
+	// Later files take precedence, so the order is preserved.
+	DotEnv []string
+
+	// Timeout is how long the Task is allowed to run before it is killed.
+	// A zero value means the Task can run indefinitely.
+	Timeout time.Duration
+
+	// Weight is the number of concurrency slots the Task takes up while it runs,
+	// so that heavy tasks can be budgeted for on constrained machines. Defaults to 1.
+	Weight int
+
+	// Retries is how many more times the Task is run if it fails, e.g. for flaky
+	// integration tests. Defaults to 0.
+	Retries int
+
+	// Description documents the Task for tooling. Unlike a comment before the task in
+	// configFile, it survives reformatting. It doesn't affect hashes.
+	Description string
 }
 
-// GetTask returns a TaskDefinition based on the ID (package#task format) or name (e.g. "build")
-func (pc Pipeline) GetTask(taskID string, taskName string) (*BookkeepingTaskDefinition, error) {
-	// first check for package-tasks
-	taskDefinition, ok := pc[taskID]
-	if !ok {
-		// then check for regular tasks
-		fallbackTaskDefinition, notcool := pc[taskName]
-		// if neither, then bail
-		if !notcool {
-			// Return an empty TaskDefinition
-			return nil, fmt.Errorf("Could not find task \"%s\" in pipeline", taskID)
+// Equal returns true if both TaskDefinitions are structurally the same. The order
+// of items in outputs, dependencies, env vars and inputs is not significant.
+// ExplicitInputs and ExplicitOutputs are not compared, since they do not survive marshalling.
+func (c TaskDefinition) Equal(other TaskDefinition) bool {
+	return c.ShouldCache == other.ShouldCache &&
+		c.Cache == other.Cache &&
+		c.CacheDisabledReason == other.CacheDisabledReason &&
+		c.CacheKey == other.CacheKey &&
+		c.OutputMode == other.OutputMode &&
+		c.OutputLogs == other.OutputLogs &&
+		c.EnvMode == other.EnvMode &&
+		c.Persistent == other.Persistent &&
+		c.Interactive == other.Interactive &&
+		stringSetsEqual(c.With, other.With) &&
+		stringSlicesEqual(c.DotEnv, other.DotEnv) &&
+		c.Timeout == other.Timeout &&
+		c.Weight == other.Weight &&
+		c.Retries == other.Retries &&
+		c.Description == other.Description &&
+		stringSetsEqual(c.Outputs.Inclusions, other.Outputs.Inclusions) &&
+		stringSetsEqual(c.Outputs.Exclusions, other.Outputs.Exclusions) &&
+		stringSetsEqual(c.Outputs.RootInclusions, other.Outputs.RootInclusions) &&
+		stringSetsEqual(c.Outputs.RootExclusions, other.Outputs.RootExclusions) &&
+		stringSetsEqual(c.EnvVarDependencies, other.EnvVarDependencies) &&
+		stringSetsEqual(c.PassThroughEnv, other.PassThroughEnv) &&
+		stringSetsEqual(c.TopologicalDependencies, other.TopologicalDependencies) &&
+		stringSetsEqual(c.TaskDependencies, other.TaskDependencies) &&
+		stringSetsEqual(c.RootTaskDependencies, other.RootTaskDependencies) &&
+		stringSetsEqual(c.TaskDependencyGlobs, other.TaskDependencyGlobs) &&
+		stringSetsEqual(c.Inputs, other.Inputs) &&
+		c.DefaultInputs == other.DefaultInputs
+}
+
+// IsCacheable returns true if the outputs and logs of the Task can be cached. Besides
+// "cache": false, persistent tasks never finish, and interactive tasks depend on what
+// the user enters, so neither of them are cached.
+func (c TaskDefinition) IsCacheable() bool {
+	return c.ShouldCache && !c.Persistent && !c.Interactive
+}
+
+// isTaskDependencyGlob returns true if dependency has wildcards, e.g. "test:*"
+func isTaskDependencyGlob(dependency string) bool {
+	return strings.ContainsAny(dependency, "*?[")
+}
+
+// ResolveTaskDependencies returns the TaskDependencies along with the tasks in pipeline
+// that match the TaskDependencyGlobs, sorted. Globs of bare task names (e.g. "test:*")
+// match the names of bare tasks and of the tasks scoped to taskID's own package, e.g.
+// "//#test:unit" in a single-package repository, and resolve to the bare name. Globs of
+// package tasks (e.g. "web#test:*") only match package tasks, and resolve to their ID.
+// The task itself is never matched, so that "test:*" doesn't make "test:all" depend on
+// itself.
+func (c TaskDefinition) ResolveTaskDependencies(taskID string, pipeline Pipeline) []string {
+	if len(c.TaskDependencyGlobs) == 0 {
+		return c.TaskDependencies
+	}
+
+	pkg, taskName, _ := pipeline.SplitKey(taskID)
+	dependencies := util.SetFromStrings(c.TaskDependencies)
+	for _, candidate := range pipeline.SortedTaskIDs() {
+		candidatePkg, candidateName, isPackageTask := pipeline.SplitKey(candidate)
+		for _, glob := range c.TaskDependencyGlobs {
+			dependency := candidate
+			if util.IsPackageTask(glob) {
+				if !isPackageTask || candidate == taskID {
+					continue
+				}
+			} else {
+				if (isPackageTask && candidatePkg != pkg) || candidateName == taskName {
+					continue
+				}
+				dependency = candidateName
+			}
+			// Malformed globs are rejected when parsing configFile
+			if matched, _ := path.Match(glob, dependency); matched {
+				dependencies.Add(dependency)
+				break
+			}
 		}
+	}
+
+	resolved := dependencies.UnsafeListOfStrings()
+	sort.Strings(resolved)
+	return resolved
+}
+
+// Hash returns a fingerprint of the TaskDefinition. The order in which outputs,
+// dependencies, env vars and inputs were declared does not affect the hash.
+func (c TaskDefinition) Hash() (string, error) {
+	return HashObject(c.hashable().sorted())
+}
+
+// hashable returns a copy of the TaskDefinition without the fields that only document
+// the Task, and don't change how it runs. ExplicitInputs and ExplicitOutputs are left
+// out as well, so that TaskDefinitions that are Equal have the same hash, even after
+// marshalling.
+func (c TaskDefinition) hashable() TaskDefinition {
+	hashableCopy := c
+	hashableCopy.Description = ""
+	hashableCopy.ExplicitInputs = false
+	hashableCopy.ExplicitOutputs = false
+	return hashableCopy
+}
+
+// sorted returns a copy of the TaskDefinition with all of its slices sorted
+func (c TaskDefinition) sorted() TaskDefinition {
+	sortedCopy := c
+	sortedCopy.Outputs = c.Outputs.Sort()
+	sortedCopy.EnvVarDependencies = sortedStrings(c.EnvVarDependencies)
+	sortedCopy.PassThroughEnv = sortedStrings(c.PassThroughEnv)
+	sortedCopy.TopologicalDependencies = sortedStrings(c.TopologicalDependencies)
+	sortedCopy.TaskDependencies = sortedStrings(c.TaskDependencies)
+	sortedCopy.RootTaskDependencies = sortedStrings(c.RootTaskDependencies)
+	sortedCopy.TaskDependencyGlobs = sortedStrings(c.TaskDependencyGlobs)
+	sortedCopy.Inputs = sortedStrings(c.Inputs)
+	sortedCopy.With = sortedStrings(c.With)
+	// DotEnv is not sorted, since later files take precedence
+	return sortedCopy
+}
+
+// Normalize sorts and dedupes the outputs, dependencies, env vars and inputs of the
+// TaskDefinition, so that equivalent definitions are identical. DotEnv is left as is,
+// since later files take precedence. The slices are replaced rather than modified,
+// because they may be shared with the definitions that this was merged from.
+func (c *TaskDefinition) Normalize() {
+	c.Outputs.Inclusions = normalizedStrings(c.Outputs.Inclusions)
+	c.Outputs.Exclusions = normalizedStrings(c.Outputs.Exclusions)
+	c.Outputs.RootInclusions = normalizedStrings(c.Outputs.RootInclusions)
+	c.Outputs.RootExclusions = normalizedStrings(c.Outputs.RootExclusions)
+	c.EnvVarDependencies = normalizedStrings(c.EnvVarDependencies)
+	c.PassThroughEnv = normalizedStrings(c.PassThroughEnv)
+	c.TopologicalDependencies = normalizedStrings(c.TopologicalDependencies)
+	c.TaskDependencies = normalizedStrings(c.TaskDependencies)
+	c.RootTaskDependencies = normalizedStrings(c.RootTaskDependencies)
+	c.TaskDependencyGlobs = normalizedStrings(c.TaskDependencyGlobs)
+	c.Inputs = normalizedStrings(c.Inputs)
+	c.With = normalizedStrings(c.With)
+}
+
+// normalizedStrings returns a sorted copy of values without duplicates, preserving
+// whether it is nil
+func normalizedStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	normalized := util.SetFromStrings(values).UnsafeListOfStrings()
+	sort.Strings(normalized)
+	return normalized
+}
 
-		// override if we need to...
-		taskDefinition = fallbackTaskDefinition
+// sortedStrings returns a sorted copy of the given slice
+func sortedStrings(values []string) []string {
+	sortedValues := make([]string, len(values))
+	copy(sortedValues, values)
+	sort.Strings(sortedValues)
+	return sortedValues
+}
+
+// unionStrings returns the sorted, deduplicated strings that are in either slice
+func unionStrings(a []string, b []string) []string {
+	union := util.SetFromStrings(a)
+	for _, value := range b {
+		union.Add(value)
+	}
+	values := union.UnsafeListOfStrings()
+	sort.Strings(values)
+	return values
+}
+
+// stringSlicesEqual returns true if both slices contain the same strings in the same order
+func stringSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
+	return true
+}
 
+// stringSetsEqual returns true if both slices contain the same strings, ignoring order and duplicates
+func stringSetsEqual(a []string, b []string) bool {
+	aSet := util.SetFromStrings(a)
+	bSet := util.SetFromStrings(b)
+	return aSet.Len() == bSet.Len() && aSet.Intersection(bSet).Len() == aSet.Len()
+}
+
+// MarshalJSON serializes CacheConfig into the object form of the "cache" key
+func (c CacheConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rawCacheConfig{Local: &c.Local, Remote: &c.Remote})
+}
+
+// GetTask returns a TaskDefinition based on the ID (package#task format) or name (e.g. "build").
+//
+// Deprecated: taskName is ignored, since it is always the task in taskID. Use GetTaskByID.
+func (pc Pipeline) GetTask(taskID string, taskName string) (*BookkeepingTaskDefinition, error) {
+	return pc.GetTaskByID(taskID)
+}
+
+// GetTaskByID returns a TaskDefinition based on the ID (package#task format) or name (e.g. "build").
+// See resolveTask for how a package task falls back to the task name.
+func (pc Pipeline) GetTaskByID(taskID string) (*BookkeepingTaskDefinition, error) {
+	taskDefinition, ok := pc.resolveTask(taskID)
+	if !ok {
+		return nil, fmt.Errorf("Could not find task \"%s\" in pipeline", taskID)
+	}
 	return &taskDefinition, nil
 }
 
-// LoadTurboConfig loads, or optionally, synthesizes a TurboJSON instance
-func LoadTurboConfig(dir turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJSON, includeSynthesizedFromRootPackageJSON bool) (*TurboJSON, error) {
-	// If the root package.json stil has a `turbo` key, log a warning and remove it.
+// RawTaskJSON returns the definition of a task, looked up like GetTaskByID, as JSON, e.g.
+// for showing it while debugging. This is not the source from configFile: it is
+// marshaled with TaskDefinition.MarshalJSON, so it includes the default values.
+func (pc Pipeline) RawTaskJSON(taskID string) ([]byte, error) {
+	bookkeepingTaskDef, err := pc.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(bookkeepingTaskDef.TaskDefinition)
+}
+
+// resolveTask looks up the exact taskID (e.g. "web#build") first, then falls back to
+// the name of the task (e.g. "build") if taskID is a package task.
+func (pc Pipeline) resolveTask(taskID string) (BookkeepingTaskDefinition, bool) {
+	if entry, ok := pc[taskID]; ok {
+		return entry, true
+	}
+	_, taskName, isPackageScoped := pc.SplitKey(taskID)
+	if !isPackageScoped {
+		return BookkeepingTaskDefinition{}, false
+	}
+	entry, ok := pc[taskName]
+	return entry, ok
+}
+
+// SplitKey splits a key of the pipeline into the package it is scoped to and the
+// name of the task, e.g. "web" and "build" for "web#build". Tasks that aren't scoped
+// to a package (e.g. "build") have an empty pkg. Keys without a package name before
+// the delimiter (e.g. "#build") aren't package tasks, and are returned as the task;
+// see ValidateTaskNames for the keys that are valid.
+func (pc Pipeline) SplitKey(key string) (pkg string, task string, isPackageScoped bool) {
+	if !util.IsPackageTask(key) {
+		return "", key, false
+	}
+	pkg, task = util.GetPackageTaskFromId(key)
+	return pkg, task, true
+}
+
+// LoadTurboConfig loads, or optionally, synthesizes a TurboJSON instance. Warnings,
+// e.g. about deprecated keys, are returned instead of logged, so that the caller can
+// report them however it reports other problems.
+func LoadTurboConfig(dir turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJSON, includeSynthesizedFromRootPackageJSON bool) (*TurboJSON, []string, error) {
+	turboJSON, err := LoadTurboConfigWithOptions(dir, rootPackageJSON, includeSynthesizedFromRootPackageJSON, ParseOptions{SilenceWarnings: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	return turboJSON, turboJSON.Warnings(), nil
+}
+
+// LoadTurboConfigWithOptions is LoadTurboConfig with control over how configFile is parsed
+func LoadTurboConfigWithOptions(dir turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJSON, includeSynthesizedFromRootPackageJSON bool, opts ParseOptions) (*TurboJSON, error) {
+	return LoadTurboConfigFromPath(dir.UntypedJoin(configFile), rootPackageJSON, includeSynthesizedFromRootPackageJSON, opts)
+}
+
+// LoadTurboConfigFromPath is LoadTurboConfigWithOptions for a config file at an arbitrary
+// path (e.g. from --config) instead of configFile in the repository root
+func LoadTurboConfigFromPath(turboJSONPath turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJSON, includeSynthesizedFromRootPackageJSON bool, opts ParseOptions) (*TurboJSON, error) {
+	// If the root package.json stil has a `turbo` key, warn about it and remove it.
+	var packageJSONWarnings []string
 	if rootPackageJSON.LegacyTurboConfig != nil {
-		log.Printf("[WARNING] \"turbo\" in package.json is no longer supported. Migrate to %s by running \"npx @turbo/codemod create-turbo-config\"\n", configFile)
+		packageJSONWarnings = append(packageJSONWarnings, fmt.Sprintf("\"turbo\" in package.json is no longer supported. Migrate to %s by running \"npx @turbo/codemod create-turbo-config\"", configFile))
+		if !opts.SilenceWarnings {
+			log.Printf("[WARNING] %s", packageJSONWarnings[0])
+		}
 		rootPackageJSON.LegacyTurboConfig = nil
 	}
 
 	var turboJSON *TurboJSON
-	turboFromFiles, err := readTurboConfig(dir.UntypedJoin(configFile))
+	turboFromFiles, err := readTurboConfig(turboJSONPath, opts)
+	if err == nil && turboFromFiles != nil {
+		err = turboFromFiles.ApplyOverrides(opts.Conditions)
+	}
+	if err == nil && turboFromFiles != nil && opts.LookupEnv != nil {
+		err = turboFromFiles.interpolateRemoteCacheOptions(opts.LookupEnv)
+	}
+	// The options are checked after interpolation, since a reference to an environment
+	// variable is only a valid team id once it is resolved
+	if err == nil && turboFromFiles != nil && opts.StrictRemoteCacheOptions {
+		if validationErrors := turboFromFiles.Validate([]TurboJSONValidation{ValidateRemoteCacheOptions}); len(validationErrors) > 0 {
+			err = fmt.Errorf("%s: %w", turboJSONPath.Base(), validationErrors[0])
+		}
+	}
 
 	if !includeSynthesizedFromRootPackageJSON && err != nil {
 		// If the file didn't exist, throw a custom error here instead of propagating
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, errors.Wrap(err, fmt.Sprintf("Could not find %s. Follow directions at https://turbo.build/repo/docs to create one", configFile))
+			return nil, errors.Wrap(err, fmt.Sprintf("Could not find %s. Follow directions at https://turbo.build/repo/docs to create one", turboJSONPath.Base()))
 
 		}
 
@@ -178,6 +783,9 @@ func LoadTurboConfig(dir turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJ
 		return nil, err
 	} else if !includeSynthesizedFromRootPackageJSON {
 		// We're not synthesizing anything and there was no error, we're done
+		if turboFromFiles != nil {
+			turboFromFiles.warnings = append(packageJSONWarnings, turboFromFiles.warnings...)
+		}
 		return turboFromFiles, nil
 	} else if errors.Is(err, os.ErrNotExist) {
 		// turbo.json doesn't exist, but we're going try to synthesize something
@@ -202,8 +810,9 @@ func LoadTurboConfig(dir turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJ
 		turboJSON.Pipeline = pipeline
 	}
 
+	excludedScripts := util.SetFromStrings(turboJSON.ExcludeScripts)
 	for scriptName := range rootPackageJSON.Scripts {
-		if !turboJSON.Pipeline.HasTask(scriptName) {
+		if !turboJSON.Pipeline.HasTask(scriptName) && !excludedScripts.Includes(scriptName) {
 			taskName := util.RootTaskID(scriptName)
 			// Explicitly set ShouldCache to false in this definition and add the bookkeeping fields
 			// so downstream we can pretend that it was set on purpose (as if read from a config file)
@@ -212,13 +821,106 @@ func LoadTurboConfig(dir turbopath.AbsoluteSystemPath, rootPackageJSON *PackageJ
 				definedFields: util.SetFromStrings([]string{"ShouldCache"}),
 				TaskDefinition: TaskDefinition{
 					ShouldCache: false,
+					Cache:       CacheConfig{Local: false, Remote: false},
 				},
 			}
 		}
 	}
+	turboJSON.warnings = append(packageJSONWarnings, turboJSON.warnings...)
 	return turboJSON, nil
 }
 
+// LoadLayeredTurboConfig reads each of files in dir, in order, and layers them, e.g. a
+// committed configFile and a gitignored turbo.local.json with a developer's overrides.
+// Files that don't exist are skipped, but at least one of them must exist. The top-level
+// keys are merged with MergeTurboJSON and the pipelines with Pipeline.Merge, so keys set
+// in later files take precedence.
+func LoadLayeredTurboConfig(dir turbopath.AbsoluteSystemPath, files []string) (*TurboJSON, error) {
+	layers := []*TurboJSON{}
+	layerFiles := []string{}
+	for _, file := range files {
+		turboJSON, err := readTurboConfig(dir.UntypedJoin(file), ParseOptions{})
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		layers = append(layers, turboJSON)
+		layerFiles = append(layerFiles, file)
+	}
+	if len(layers) == 0 {
+		return nil, errors.Wrap(os.ErrNotExist, fmt.Sprintf("Could not find any of %s in %s", strings.Join(files, ", "), dir))
+	}
+
+	merged, err := MergeTurboJSON(layers)
+	if err != nil {
+		return nil, err
+	}
+	for i, layer := range layers {
+		pipeline, err := merged.Pipeline.Merge(layer.Pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", layerFiles[i], err)
+		}
+		merged.Pipeline = pipeline
+
+		for condition, override := range layer.Overrides {
+			if merged.Overrides == nil {
+				merged.Overrides = map[string]Pipeline{}
+			}
+			mergedOverride, err := merged.Overrides[condition].Merge(override)
+			if err != nil {
+				return nil, fmt.Errorf("%s: overrides \"%s\": %w", layerFiles[i], condition, err)
+			}
+			merged.Overrides[condition] = mergedOverride
+		}
+
+		if layer.Schema != "" {
+			merged.Schema = layer.Schema
+		}
+		if layer.Extends != nil {
+			merged.Extends = append([]string{}, layer.Extends...)
+		}
+		for _, warning := range layer.warnings {
+			merged.warnings = append(merged.warnings, fmt.Sprintf("%s: %s", layerFiles[i], warning))
+		}
+	}
+	return merged, nil
+}
+
+// Warnings returns the problems found while loading tj that don't prevent it from
+// being used, e.g. deprecated keys and absolute paths, in the order they were found
+func (tj *TurboJSON) Warnings() []string {
+	if tj == nil {
+		return nil
+	}
+	return append([]string{}, tj.warnings...)
+}
+
+// logWarnings logs each of the Warnings of tj
+func (tj *TurboJSON) logWarnings() {
+	for _, warning := range tj.Warnings() {
+		log.Printf("[WARNING] %s", warning)
+	}
+}
+
+// ApplyOverrides merges the overrides of each of the active conditions over the
+// pipeline, in order. Tasks in an override are merged with the task of the same name
+// with MergeTaskDefinitions, so only the keys that the override sets change.
+func (tj *TurboJSON) ApplyOverrides(conditions []string) error {
+	for _, condition := range conditions {
+		override, ok := tj.Overrides[condition]
+		if !ok {
+			continue
+		}
+		pipeline, err := tj.Pipeline.Merge(override)
+		if err != nil {
+			return fmt.Errorf("overrides \"%s\": %w", condition, err)
+		}
+		tj.Pipeline = pipeline
+	}
+	return nil
+}
+
 // TurboJSONValidation is the signature for a validation function passed to Validate()
 type TurboJSONValidation func(*TurboJSON) []error
 
@@ -234,150 +936,1431 @@ func (tj *TurboJSON) Validate(validations []TurboJSONValidation) []error {
 	return allErrors
 }
 
-// TaskOutputs represents the patterns for including and excluding files from outputs
-type TaskOutputs struct {
-	Inclusions []string
-	Exclusions []string
+// AllEnvVarDependencies returns the sorted, deduplicated union of GlobalEnv and
+// every task's EnvVarDependencies, i.e. every env var that can affect a cache key.
+func (tj *TurboJSON) AllEnvVarDependencies() []string {
+	envVarDependencies := util.SetFromStrings(tj.GlobalEnv)
+	for _, bookkeepingTaskDef := range tj.Pipeline {
+		for _, envVar := range bookkeepingTaskDef.TaskDefinition.EnvVarDependencies {
+			envVarDependencies.Add(envVar)
+		}
+	}
+
+	allEnvVarDependencies := envVarDependencies.UnsafeListOfStrings()
+	sort.Strings(allEnvVarDependencies)
+	return allEnvVarDependencies
 }
 
-// Sort contents of task outputs
-func (to TaskOutputs) Sort() TaskOutputs {
-	var inclusions []string
-	var exclusions []string
-	copy(inclusions, to.Inclusions)
-	copy(exclusions, to.Exclusions)
-	sort.Strings(inclusions)
-	sort.Strings(exclusions)
-	return TaskOutputs{Inclusions: inclusions, Exclusions: exclusions}
+// MigrateEnvVarDependencies returns a copy of tj where deprecated "$VAR" entries in
+// dependsOn and globalDependencies are moved to env and globalEnv. configFile is
+// migrated like this when it is parsed, so this is for a TurboJSON that was built
+// some other way (e.g. by @turbo/codemod). All other entries are left untouched.
+func MigrateEnvVarDependencies(tj *TurboJSON) *TurboJSON {
+	migrated := *tj
+	migrated.Pipeline = tj.Pipeline.Clone()
+
+	globalEnv := util.SetFromStrings(tj.GlobalEnv)
+	migrated.GlobalDeps = copyStrings(tj.GlobalDeps)[:0]
+	for _, value := range tj.GlobalDeps {
+		if strings.HasPrefix(value, envPipelineDelimiter) {
+			globalEnv.Add(strings.TrimPrefix(value, envPipelineDelimiter))
+		} else {
+			migrated.GlobalDeps = append(migrated.GlobalDeps, value)
+		}
+	}
+	migrated.GlobalEnv = globalEnv.UnsafeListOfStrings()
+	sort.Strings(migrated.GlobalEnv)
+
+	for taskID, bookkeepingTaskDef := range migrated.Pipeline {
+		taskDefinition := &bookkeepingTaskDef.TaskDefinition
+		envVarDependencies := util.SetFromStrings(taskDefinition.EnvVarDependencies)
+		taskDependencies := []string{}
+		for _, dependency := range taskDefinition.TaskDependencies {
+			if strings.HasPrefix(dependency, envPipelineDelimiter) {
+				envVarDependencies.Add(strings.TrimPrefix(dependency, envPipelineDelimiter))
+			} else {
+				taskDependencies = append(taskDependencies, dependency)
+			}
+		}
+		if len(taskDependencies) == len(taskDefinition.TaskDependencies) {
+			continue
+		}
+
+		taskDefinition.TaskDependencies = taskDependencies
+		taskDefinition.EnvVarDependencies = envVarDependencies.UnsafeListOfStrings()
+		sort.Strings(taskDefinition.EnvVarDependencies)
+		if bookkeepingTaskDef.definedFields == nil {
+			bookkeepingTaskDef.definedFields = make(util.Set)
+		}
+		bookkeepingTaskDef.definedFields.Add("EnvVarDependencies")
+		migrated.Pipeline[taskID] = bookkeepingTaskDef
+	}
+
+	return &migrated
 }
 
-// readTurboConfig reads turbo.json from a provided path
-func readTurboConfig(turboJSONPath turbopath.AbsoluteSystemPath) (*TurboJSON, error) {
-	// If the configFile exists, use that
-	if turboJSONPath.FileExists() {
-		turboJSON, err := readTurboJSON(turboJSONPath)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", configFile, err)
+// TasksDependingOnEnv returns the sorted tasks whose "env" (including wildcard patterns)
+// matches the env var name, i.e. the tasks whose hash changes when it changes. The
+// bool is true if the env var is matched by "globalEnv", which affects every task.
+func (tj *TurboJSON) TasksDependingOnEnv(name string) ([]string, bool) {
+	// Invalid patterns are rejected when configFile is parsed, so errors are ignored
+	globalMatches, _ := MatchEnvVarPatterns(tj.GlobalEnv, []string{name})
+
+	tasks := []string{}
+	for _, taskID := range tj.Pipeline.SortedTaskIDs() {
+		matches, _ := MatchEnvVarPatterns(tj.Pipeline[taskID].TaskDefinition.EnvVarDependencies, []string{name})
+		if len(matches) > 0 {
+			tasks = append(tasks, taskID)
 		}
+	}
 
-		return turboJSON, nil
+	return tasks, len(globalMatches) > 0
+}
+
+// AllGlobs returns the sorted, deduplicated globs of every task's "inputs" and
+// "outputs", and of "globalDependencies", so that they can all be checked at once.
+// Outputs are returned as they are declared, see TaskOutputs.Globs.
+func (tj *TurboJSON) AllGlobs() (inputs []string, outputs []string, globalDeps []string) {
+	inputSet := make(util.Set)
+	outputSet := make(util.Set)
+	for _, bookkeepingTaskDef := range tj.Pipeline {
+		for _, input := range bookkeepingTaskDef.TaskDefinition.Inputs {
+			inputSet.Add(input)
+		}
+		for _, output := range bookkeepingTaskDef.TaskDefinition.Outputs.Globs() {
+			outputSet.Add(output)
+		}
 	}
 
-	// If there's no turbo.json, return an error.
-	return nil, os.ErrNotExist
+	inputs = inputSet.UnsafeListOfStrings()
+	sort.Strings(inputs)
+	outputs = outputSet.UnsafeListOfStrings()
+	sort.Strings(outputs)
+	globalDeps = util.SetFromStrings(tj.GlobalDeps).UnsafeListOfStrings()
+	sort.Strings(globalDeps)
+	return inputs, outputs, globalDeps
 }
 
-// readTurboJSON reads the configFile in to a struct
-func readTurboJSON(path turbopath.AbsoluteSystemPath) (*TurboJSON, error) {
-	file, err := path.Open()
-	if err != nil {
-		return nil, err
+// ResolveExtends walks the extends keys starting at this TurboJSON and returns every
+// config in the chain, ordered so that bases come before the configs that extend them.
+// The last item is always tj itself. lookup is used to load the TurboJSON for a workspace
+// name, and workspaceName identifies tj so that a chain looping back to it is detected.
+func (tj *TurboJSON) ResolveExtends(workspaceName string, lookup func(workspaceName string) (*TurboJSON, error)) ([]*TurboJSON, error) {
+	chain := []*TurboJSON{}
+	resolved := make(util.Set)
+
+	var visit func(name string, turboJSON *TurboJSON, path []string) error
+	visit = func(name string, turboJSON *TurboJSON, path []string) error {
+		for i, visiting := range path {
+			if visiting == name {
+				cycle := append(append([]string{}, path[i:]...), name)
+				return fmt.Errorf("cyclic extends detected: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		if resolved.Includes(name) {
+			return nil
+		}
+
+		path = append(path, name)
+		for _, baseName := range turboJSON.Extends {
+			base, err := lookup(baseName)
+			if err != nil {
+				return fmt.Errorf("could not resolve \"%s\" extended by \"%s\": %w", baseName, name, err)
+			}
+			if err := visit(baseName, base, path); err != nil {
+				return err
+			}
+		}
+
+		resolved.Add(name)
+		turboJSON.workspaceName = name
+		chain = append(chain, turboJSON)
+		return nil
 	}
-	var turboJSON *TurboJSON
-	data, err := ioutil.ReadAll(file)
-	if err != nil {
+
+	if err := visit(workspaceName, tj, []string{}); err != nil {
 		return nil, err
 	}
 
-	err = jsonc.Unmarshal(data, &turboJSON)
+	return chain, nil
+}
+
+// hasRemoteCacheField returns true if the given key of .remoteCache was set. For a
+// TurboJSON that wasn't parsed from configFile, non-zero values count as set.
+func (tj *TurboJSON) hasRemoteCacheField(key string) bool {
+	if tj.remoteCacheFields != nil {
+		return tj.remoteCacheFields.Includes(key)
+	}
+	switch key {
+	case "teamId":
+		return tj.RemoteCacheOptions.TeamID != ""
+	case "signature":
+		return tj.RemoteCacheOptions.Signature
+	case "apiUrl":
+		return tj.RemoteCacheOptions.APIURL != ""
+	case "enabled":
+		return tj.RemoteCacheOptions.Enabled != nil
+	}
+	return false
+}
 
+// interpolateRemoteCacheOptions replaces the references to environment variables in
+// the string values of .remoteCache with their values from lookupEnv
+func (tj *TurboJSON) interpolateRemoteCacheOptions(lookupEnv func(key string) (string, bool)) error {
+	teamID, err := interpolateEnv("remoteCache.teamId", tj.RemoteCacheOptions.TeamID, lookupEnv)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	apiURL, err := interpolateEnv("remoteCache.apiUrl", tj.RemoteCacheOptions.APIURL, lookupEnv)
+	if err != nil {
+		return err
 	}
+	tj.RemoteCacheOptions.TeamID = teamID
+	tj.RemoteCacheOptions.APIURL = apiURL
+	return nil
+}
 
-	return turboJSON, nil
+// interpolateEnv replaces the $VAR and ${VAR} references in value, the value of key,
+// with their values from lookupEnv. Referencing a variable that isn't set is an error.
+func interpolateEnv(key string, value string, lookupEnv func(key string) (string, bool)) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	var missing []string
+	interpolated := os.Expand(value, func(name string) string {
+		resolved, ok := lookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return resolved
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("\"%s\" references $%s, which is not set", key, missing[0])
+	}
+	return interpolated, nil
 }
 
-// GetTaskDefinition returns a TaskDefinition from a serialized definition in configFile
-func (pc Pipeline) GetTaskDefinition(taskID string) (TaskDefinition, bool) {
-	if entry, ok := pc[taskID]; ok {
-		return entry.TaskDefinition, true
+// mergeRemoteCacheOptions overrides the fields of .remoteCache that layer set, keeping
+// the rest of tj's
+func (tj *TurboJSON) mergeRemoteCacheOptions(layer *TurboJSON) {
+	if tj.remoteCacheFields == nil {
+		tj.remoteCacheFields = make(util.Set)
+	}
+	if layer.hasRemoteCacheField("teamId") {
+		tj.RemoteCacheOptions.TeamID = layer.RemoteCacheOptions.TeamID
+		tj.remoteCacheFields.Add("teamId")
+	}
+	if layer.hasRemoteCacheField("signature") {
+		tj.RemoteCacheOptions.Signature = layer.RemoteCacheOptions.Signature
+		tj.remoteCacheFields.Add("signature")
+	}
+	if layer.hasRemoteCacheField("apiUrl") {
+		tj.RemoteCacheOptions.APIURL = layer.RemoteCacheOptions.APIURL
+		tj.remoteCacheFields.Add("apiUrl")
+	}
+	if layer.hasRemoteCacheField("enabled") {
+		tj.RemoteCacheOptions.Enabled = layer.RemoteCacheOptions.Enabled
+		tj.remoteCacheFields.Add("enabled")
+	}
+}
+
+// MergeTurboJSON merges the top-level configuration of layers, ordered from the
+// base-most configuration. GlobalDeps, GlobalEnv, GlobalPassThroughEnv and
+// ExcludeScripts are unioned, and GlobalDotEnv, OutputMode, EnvMode, each RemoteCacheOptions field
+// and each Experimental option are taken from the last layer that set them. Tasks are not merged, use
+// ResolvePipeline for that.
+func MergeTurboJSON(layers []*TurboJSON) (*TurboJSON, error) {
+	globalDeps := make(util.Set)
+	globalEnv := make(util.Set)
+	var globalPassThroughEnv util.Set
+	excludeScripts := make(util.Set)
+	merged := &TurboJSON{
+		Pipeline:          Pipeline{},
+		remoteCacheFields: make(util.Set),
+	}
+
+	for _, layer := range layers {
+		for _, value := range layer.GlobalDeps {
+			globalDeps.Add(value)
+		}
+		for _, value := range layer.GlobalEnv {
+			globalEnv.Add(value)
+		}
+		if layer.GlobalPassThroughEnv != nil {
+			if globalPassThroughEnv == nil {
+				globalPassThroughEnv = make(util.Set)
+			}
+			for _, value := range layer.GlobalPassThroughEnv {
+				globalPassThroughEnv.Add(value)
+			}
+		}
+
+		for _, value := range layer.ExcludeScripts {
+			excludeScripts.Add(value)
+		}
+
+		if layer.GlobalDotEnv != nil {
+			merged.GlobalDotEnv = append([]string{}, layer.GlobalDotEnv...)
+		}
+		if layer.OutputMode != nil {
+			merged.OutputMode = layer.OutputMode
+		}
+		if layer.EnvMode != nil {
+			merged.EnvMode = layer.EnvMode
+		}
+		for key, value := range layer.Experimental {
+			if merged.Experimental == nil {
+				merged.Experimental = map[string]json.RawMessage{}
+			}
+			merged.Experimental[key] = value
+		}
+
+		merged.mergeRemoteCacheOptions(layer)
+	}
+
+	merged.GlobalDeps = globalDeps.UnsafeListOfStrings()
+	sort.Strings(merged.GlobalDeps)
+	merged.GlobalEnv = globalEnv.UnsafeListOfStrings()
+	sort.Strings(merged.GlobalEnv)
+	if globalPassThroughEnv != nil {
+		merged.GlobalPassThroughEnv = globalPassThroughEnv.UnsafeListOfStrings()
+		sort.Strings(merged.GlobalPassThroughEnv)
+	}
+	if excludeScripts.Len() > 0 {
+		merged.ExcludeScripts = excludeScripts.UnsafeListOfStrings()
+		sort.Strings(merged.ExcludeScripts)
+	}
+
+	return merged, nil
+}
+
+// UnusedTasks returns the sorted pipeline entries that no workspace has a script for.
+// packageScripts maps each workspace name to the names of its scripts. Package tasks
+// (e.g. "web#build") are only used if that workspace has the script.
+func (tj *TurboJSON) UnusedTasks(packageScripts map[string][]string) []string {
+	scripts := make(util.Set)
+	packageTasks := make(util.Set)
+	for pkg, pkgScripts := range packageScripts {
+		for _, script := range pkgScripts {
+			scripts.Add(script)
+			packageTasks.Add(util.GetTaskId(pkg, script))
+		}
+	}
+
+	unused := []string{}
+	for taskID := range tj.Pipeline {
+		if util.IsPackageTask(taskID) {
+			if !packageTasks.Includes(taskID) {
+				unused = append(unused, taskID)
+			}
+		} else if !scripts.Includes(taskID) {
+			unused = append(unused, taskID)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// DependencyClosure returns the sorted tasks that taskName transitively depends on, in
+// the format of dependsOn: "^build" for a topological dependency and "//#lint" for a
+// root task. Topological dependencies run in other workspaces, which aren't known
+// here, so their own dependencies are not followed.
+func (tj *TurboJSON) DependencyClosure(taskName string) ([]string, error) {
+	if _, ok := tj.Pipeline[taskName]; !ok {
+		return nil, fmt.Errorf("Could not find task \"%s\" in pipeline", taskName)
+	}
+
+	closure := make(util.Set)
+	done := make(util.Set)
+	var visit func(taskID string, path []string) error
+	visit = func(taskID string, path []string) error {
+		for i, visited := range path {
+			if visited == taskID {
+				cycle := append(append([]string{}, path[i:]...), taskID)
+				return fmt.Errorf("cyclic task dependency detected: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		if done.Includes(taskID) {
+			return nil
+		}
+		path = append(path, taskID)
+
+		taskDefinition, ok := tj.Pipeline.resolveDependency(path[0], taskID)
+		if !ok {
+			// Missing dependencies are reported by ValidateDependenciesExist
+			return nil
+		}
+
+		for _, dependency := range taskDefinition.TopologicalDependencies {
+			closure.Add(topologicalPipelineDelimiter + dependency)
+		}
+		dependencies := append([]string{}, taskDefinition.ResolveTaskDependencies(taskID, tj.Pipeline)...)
+		for _, dependency := range taskDefinition.RootTaskDependencies {
+			dependencies = append(dependencies, util.RootTaskID(dependency))
+		}
+		for _, dependency := range dependencies {
+			closure.Add(dependency)
+			if err := visit(dependency, path); err != nil {
+				return err
+			}
+		}
+		done.Add(taskID)
+		return nil
+	}
+
+	if err := visit(taskName, []string{}); err != nil {
+		return nil, err
+	}
+
+	dependencies := closure.UnsafeListOfStrings()
+	sort.Strings(dependencies)
+	return dependencies, nil
+}
+
+// GetResolvedTask merges the definitions of a task across an extends chain, ordered
+// from the base-most configuration. Each layer is looked up with Pipeline.GetTask,
+// so a package task (taskID) takes precedence over the task (taskName) in that layer.
+func GetResolvedTask(taskID string, taskName string, chain []*TurboJSON) (*TaskDefinition, error) {
+	taskDefinitions := []BookkeepingTaskDefinition{}
+	for _, layer := range chain {
+		if bookkeepingTaskDef, err := layer.Pipeline.GetTask(taskID, taskName); err == nil {
+			taskDefinitions = append(taskDefinitions, *bookkeepingTaskDef)
+		}
+	}
+
+	if len(taskDefinitions) == 0 {
+		return nil, fmt.Errorf("Could not find task \"%s\" in any of the %d configurations in its extends chain", taskID, len(chain))
+	}
+
+	return MergeTaskDefinitions(taskDefinitions)
+}
+
+// extendsLayers returns extendsChain with tj at the end, whether or not it was included
+func (tj *TurboJSON) extendsLayers(extendsChain []*TurboJSON) []*TurboJSON {
+	layers := []*TurboJSON{}
+	for _, turboJSON := range extendsChain {
+		if turboJSON != tj {
+			layers = append(layers, turboJSON)
+		}
+	}
+	return append(layers, tj)
+}
+
+// ResolvePipeline merges the tasks of tj with the tasks of the configurations it
+// extends, returning every task with all defaults applied. extendsChain is ordered
+// from the base-most configuration, as returned by ResolveExtends. tj is always
+// merged last, whether or not it is included at the end of extendsChain.
+func (tj *TurboJSON) ResolvePipeline(extendsChain []*TurboJSON) (PristinePipeline, error) {
+	layers := tj.extendsLayers(extendsChain)
+
+	taskNames := make(util.Set)
+	for _, layer := range layers {
+		for taskName := range layer.Pipeline {
+			taskNames.Add(taskName)
+		}
+	}
+
+	resolved := PristinePipeline{}
+	for _, taskName := range taskNames.UnsafeListOfStrings() {
+		taskDefinitions := []BookkeepingTaskDefinition{}
+		for _, layer := range layers {
+			if bookkeepingTaskDef, ok := layer.Pipeline[taskName]; ok {
+				taskDefinitions = append(taskDefinitions, bookkeepingTaskDef)
+			}
+		}
+
+		mergedTaskDefinition, err := MergeTaskDefinitions(taskDefinitions)
+		if err != nil {
+			return nil, err
+		}
+		resolved[taskName] = *mergedTaskDefinition
+	}
+
+	return resolved, nil
+}
+
+// ResolveRemoteCacheOptions merges the .remoteCache of tj with the ones of the
+// configurations it extends. Each field is inherited from the base-most configuration
+// unless a configuration closer to tj sets it. extendsChain is ordered as for
+// ResolvePipeline.
+func (tj *TurboJSON) ResolveRemoteCacheOptions(extendsChain []*TurboJSON) RemoteCacheOptions {
+	resolved := &TurboJSON{}
+	for _, layer := range tj.extendsLayers(extendsChain) {
+		resolved.mergeRemoteCacheOptions(layer)
+	}
+	return resolved.RemoteCacheOptions
+}
+
+// ExtendsLookup returns a lookup function for ResolveExtends. Each name is first looked up
+// as a workspace with workspaceLookup, which reports whether the name is a known workspace.
+// Names that are not workspaces are treated as npm packages that publish a shared config,
+// and are loaded from node_modules/<name>/turbo.json relative to repoRoot.
+func ExtendsLookup(repoRoot turbopath.AbsoluteSystemPath, workspaceLookup func(workspaceName string) (*TurboJSON, bool, error)) func(name string) (*TurboJSON, error) {
+	return func(name string) (*TurboJSON, error) {
+		turboJSON, isWorkspace, err := workspaceLookup(name)
+		if isWorkspace {
+			return turboJSON, err
+		}
+
+		packageConfigPath := repoRoot.UntypedJoin("node_modules", name, configFile)
+		turboJSON, err = readTurboConfig(packageConfigPath, ParseOptions{})
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("\"%s\" is not a workspace, and no %s was found at %s", name, configFile, packageConfigPath)
+		} else if err != nil {
+			return nil, err
+		}
+
+		return turboJSON, nil
+	}
+}
+
+// TaskOutputs represents the patterns for including and excluding files from outputs
+type TaskOutputs struct {
+	Inclusions []string
+	Exclusions []string
+	// RootInclusions and RootExclusions are relative to the repository root rather than
+	// the package. They are declared with the turboRootPrefix in "outputs".
+	RootInclusions []string
+	RootExclusions []string
+}
+
+// Sort contents of task outputs
+func (to TaskOutputs) Sort() TaskOutputs {
+	inclusions := make([]string, len(to.Inclusions))
+	exclusions := make([]string, len(to.Exclusions))
+	copy(inclusions, to.Inclusions)
+	copy(exclusions, to.Exclusions)
+	rootInclusions := copyStrings(to.RootInclusions)
+	rootExclusions := copyStrings(to.RootExclusions)
+	sort.Strings(inclusions)
+	sort.Strings(exclusions)
+	sort.Strings(rootInclusions)
+	sort.Strings(rootExclusions)
+	return TaskOutputs{
+		Inclusions:     inclusions,
+		Exclusions:     exclusions,
+		RootInclusions: rootInclusions,
+		RootExclusions: rootExclusions,
+	}
+}
+
+// Globs returns the outputs as they are declared in configFile, with exclusions
+// prefixed with "!" and globs relative to the repository root with turboRootPrefix
+func (to TaskOutputs) Globs() []string {
+	globs := append([]string{}, to.Inclusions...)
+	for _, exclusion := range to.Exclusions {
+		globs = append(globs, "!"+exclusion)
+	}
+	for _, inclusion := range to.RootInclusions {
+		globs = append(globs, turboRootPrefix+"/"+inclusion)
+	}
+	for _, exclusion := range to.RootExclusions {
+		globs = append(globs, "!"+turboRootPrefix+"/"+exclusion)
+	}
+	return globs
+}
+
+// trimTurboRootPrefix returns glob relative to the repository root if it starts with
+// the turboRootPrefix
+func trimTurboRootPrefix(glob string) (string, bool) {
+	if !strings.HasPrefix(glob, turboRootPrefix) {
+		return glob, false
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(glob, turboRootPrefix), "/"), true
+}
+
+// readTurboConfig reads turbo.json from a provided path
+func readTurboConfig(turboJSONPath turbopath.AbsoluteSystemPath, opts ParseOptions) (*TurboJSON, error) {
+	// configFileYAML is an alternative to configFile, but only one of them may exist
+	if turboJSONPath.Base() == configFile {
+		turboYAMLPath := turboJSONPath.Dir().UntypedJoin(configFileYAML)
+		if turboYAMLPath.FileExists() {
+			if turboJSONPath.FileExists() {
+				return nil, fmt.Errorf("Found both %s and %s in %s, remove one of them", configFile, configFileYAML, turboJSONPath.Dir())
+			}
+			turboJSON, err := readTurboYAML(turboYAMLPath, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", configFileYAML, err)
+			}
+			return turboJSON, nil
+		}
+	}
+
+	// If the configFile exists, use that
+	if turboJSONPath.FileExists() {
+		turboJSON, err := readTurboJSON(turboJSONPath, opts)
+		if err != nil {
+			// Parse errors already point at the location in the file
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %w", turboJSONPath.Base(), err)
+		}
+
+		return turboJSON, nil
+	}
+
+	// If there's no turbo.json, return an error.
+	return nil, os.ErrNotExist
+}
+
+// readTurboJSON reads the configFile in to a struct
+func readTurboJSON(path turbopath.AbsoluteSystemPath, opts ParseOptions) (*TurboJSON, error) {
+	file, err := path.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseAndIgnoreError(file)
+	return readTurboJSONFromReader(file, path.ToString(), opts)
+}
+
+// ReadTurboConfigFromReader parses the contents of a configFile from r, e.g. for a
+// configuration that is not on disk. Parse errors are reported as if the contents
+// were in a file named configFile.
+func ReadTurboConfigFromReader(r io.Reader) (*TurboJSON, error) {
+	return readTurboJSONFromReader(r, configFile, ParseOptions{})
+}
+
+// readTurboJSONFromReader parses configFile (JSONC) contents from r. name is used to
+// point at the location of parse errors.
+func readTurboJSONFromReader(r io.Reader, name string, opts ParseOptions) (*TurboJSON, error) {
+	var turboJSON *TurboJSON
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.StrictDeprecations {
+		if err := checkDeprecations(data); err != nil {
+			return nil, err
+		}
+	}
+
+	err = jsonc.Unmarshal(data, &turboJSON)
+
+	if err != nil {
+		return nil, withParseErrorPosition(name, data, err)
+	}
+
+	if err := checkDuplicatePipelineKeys(jsonc.ToJSON(data)); err != nil {
+		return nil, err
+	}
+
+	if turboJSON != nil {
+		for taskID, description := range taskDescriptions(data) {
+			if bookkeepingTaskDef, ok := turboJSON.Pipeline[taskID]; ok {
+				if bookkeepingTaskDef.TaskDefinition.Description != "" {
+					turboJSON.warnings = append(turboJSON.warnings, fmt.Sprintf("Task \"%s\" is described by both a comment and \"description\", remove one of them", taskID))
+				}
+				bookkeepingTaskDef.Description = description
+				turboJSON.Pipeline[taskID] = bookkeepingTaskDef
+			}
+		}
+	}
+
+	if opts.DisallowUnknownFields {
+		if err := checkUnknownFields(jsonc.ToJSON(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.SilenceWarnings {
+		turboJSON.logWarnings()
+	}
+	return turboJSON, nil
+}
+
+// readTurboYAML reads configFileYAML in to a struct. It is converted to JSON first,
+// so that it is parsed exactly like configFile.
+func readTurboYAML(path turbopath.AbsoluteSystemPath, opts ParseOptions) (*TurboJSON, error) {
+	yamlData, err := path.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var rawYAML interface{}
+	if err := yaml.Unmarshal(yamlData, &rawYAML); err != nil {
+		return nil, err
+	}
+	if rawYAML == nil {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	data, err := json.Marshal(rawYAML)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.StrictDeprecations {
+		if err := checkDeprecations(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var turboJSON *TurboJSON
+	if err := json.Unmarshal(data, &turboJSON); err != nil {
+		return nil, err
+	}
+
+	if opts.DisallowUnknownFields {
+		if err := checkUnknownFields(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.SilenceWarnings {
+		turboJSON.logWarnings()
+	}
+	return turboJSON, nil
+}
+
+// decodeDisallowingUnknownFields is json.Unmarshal, except that keys which don't match
+// a field of v are an error
+func decodeDisallowingUnknownFields(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// checkUnknownFields returns an error naming the first key in data that is not a key
+// of rawTurboJSON or rawTask. The custom UnmarshalJSON methods can't be told to reject
+// unknown keys, so this runs after data was otherwise parsed successfully.
+func checkUnknownFields(data []byte) error {
+	// The shallower Pipeline and Overrides fields take precedence over the ones in
+	// rawTurboJSON, so that the tasks are checked separately below
+	raw := struct {
+		rawTurboJSON
+		Pipeline  map[string]json.RawMessage            `json:"pipeline"`
+		Overrides map[string]map[string]json.RawMessage `json:"overrides"`
+	}{}
+	if err := decodeDisallowingUnknownFields(data, &raw); err != nil {
+		return err
+	}
+
+	for _, taskID := range sortedRawPipelineKeys(raw.Pipeline) {
+		if err := decodeDisallowingUnknownFields(raw.Pipeline[taskID], &rawTask{}); err != nil {
+			return fmt.Errorf("task \"%s\": %w", taskID, err)
+		}
+	}
+	conditions := make([]string, 0, len(raw.Overrides))
+	for condition := range raw.Overrides {
+		conditions = append(conditions, condition)
+	}
+	sort.Strings(conditions)
+	for _, condition := range conditions {
+		for _, taskID := range sortedRawPipelineKeys(raw.Overrides[condition]) {
+			if err := decodeDisallowingUnknownFields(raw.Overrides[condition][taskID], &rawTask{}); err != nil {
+				return fmt.Errorf("overrides \"%s\": task \"%s\": %w", condition, taskID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkDeprecations returns an error for the first deprecated construct in data.
+// Deprecations are otherwise only logged while unmarshaling, so this runs first.
+// Malformed data is left for the regular parse to report.
+func checkDeprecations(data []byte) error {
+	raw := struct {
+		GlobalDependencies []string `json:"globalDependencies"`
+		Pipeline           map[string]struct {
+			DependsOn []string `json:"dependsOn"`
+		} `json:"pipeline"`
+	}{}
+	if err := jsonc.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+
+	for _, value := range raw.GlobalDependencies {
+		if strings.HasPrefix(value, envPipelineDelimiter) {
+			return fmt.Errorf(deprecatedEnvInGlobalDependencies, value)
+		}
+	}
+	taskNames := make([]string, 0, len(raw.Pipeline))
+	for taskName := range raw.Pipeline {
+		taskNames = append(taskNames, taskName)
+	}
+	sort.Strings(taskNames)
+	for _, taskName := range taskNames {
+		for _, dependency := range raw.Pipeline[taskName].DependsOn {
+			if strings.HasPrefix(dependency, envPipelineDelimiter) {
+				return fmt.Errorf("task \"%s\": "+deprecatedEnvInDependsOn, taskName, dependency)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDuplicatePipelineKeys returns an error if a task is configured more than once
+// in "pipeline", which encoding/json would otherwise silently resolve to the last one.
+// Malformed data is left for the regular parse to report.
+func checkDuplicatePipelineKeys(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		if key != "pipeline" {
+			if err := decoder.Decode(&json.RawMessage{}); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+			return nil
+		}
+		seen := make(util.Set)
+		for decoder.More() {
+			taskID, err := decoder.Token()
+			if err != nil {
+				return nil
+			}
+			if seen.Includes(taskID) {
+				return fmt.Errorf("task \"%s\" is configured more than once in \"pipeline\"", taskID)
+			}
+			seen.Add(taskID)
+			if err := decoder.Decode(&json.RawMessage{}); err != nil {
+				return nil
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// ParseError is returned when a config file is malformed, and points at the
+// line and column of the offending JSON.
+type ParseError struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.Path, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// withParseErrorPosition converts syntax and type errors from unmarshaling data into
+// a ParseError. jsonc strips comments and whitespace before unmarshaling, so the
+// offsets it reports don't match the file. Instead, we re-parse a copy of data with the
+// comments blanked out, which leaves every offset where it was.
+func withParseErrorPosition(path string, data []byte, err error) error {
+	var turboJSON *TurboJSON
+	positionErr := json.Unmarshal(blankJSONCComments(data), &turboJSON)
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	offset := -1
+	if errors.As(positionErr, &syntaxErr) {
+		// Offset is the number of bytes read before the error, so the offending
+		// character is the one just before it.
+		offset = int(syntaxErr.Offset) - 1
+	} else if errors.As(positionErr, &typeErr) {
+		// Type errors in task definitions are reported relative to the task rather
+		// than the file, so find the value by its field path when we can.
+		offset = int(typeErr.Offset) - 1
+		if root, scanErr := parseJSONCPositions(data); scanErr == nil && typeErr.Field != "" {
+			path := strings.Split(typeErr.Field, ".")
+			if node := root.find(path); node != nil && node.end == int(typeErr.Offset) {
+				offset = node.start
+			} else if node := root.locate(path, int(typeErr.Offset)); node != nil {
+				offset = node.start
+			}
+		}
+	}
+	if offset < 0 || offset >= len(data) {
+		return err
+	}
+
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return &ParseError{Path: path, Line: line, Column: column, Err: positionErr}
+}
+
+// GetTaskDefinition returns a TaskDefinition from a serialized definition in configFile
+func (pc Pipeline) GetTaskDefinition(taskID string) (TaskDefinition, bool) {
+	entry, ok := pc.resolveTask(taskID)
+	return entry.TaskDefinition, ok
+}
+
+// SortedTaskIDs returns the keys of the pipeline in a stable order, so that errors
+// and logs about the tasks are deterministic
+func (pc Pipeline) SortedTaskIDs() []string {
+	taskIDs := make([]string, 0, len(pc))
+	for taskID := range pc {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+	return taskIDs
+}
+
+// HasTask returns true if the given task is defined in the pipeline, either directly or
+// via a package task (`pkg#task`)
+func (pc Pipeline) HasTask(task string) bool {
+	for _, key := range pc.SortedTaskIDs() {
+		if key == task {
+			return true
+		}
+		if _, taskName, isPackageScoped := pc.SplitKey(key); isPackageScoped && taskName == task {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskNames returns the sorted names of the tasks in the pipeline that are not
+// scoped to a package (e.g. "build", but not "web#build")
+func (pc Pipeline) TaskNames() []string {
+	taskNames := []string{}
+	for _, key := range pc.SortedTaskIDs() {
+		if !util.IsPackageTask(key) {
+			taskNames = append(taskNames, key)
+		}
+	}
+	return taskNames
+}
+
+// PackageTasks returns the package-scoped task IDs in the pipeline (e.g. "web#build"),
+// sorted and grouped by package name
+func (pc Pipeline) PackageTasks() map[string][]string {
+	packageTasks := map[string][]string{}
+	for key := range pc {
+		if util.IsPackageTask(key) {
+			pkg, _ := util.GetPackageTaskFromId(key)
+			packageTasks[pkg] = append(packageTasks[pkg], key)
+		}
+	}
+	for _, taskIDs := range packageTasks {
+		sort.Strings(taskIDs)
+	}
+	return packageTasks
+}
+
+// Filter returns a new Pipeline with only the entries for which pred returns true
+func (pc Pipeline) Filter(pred func(taskID string, def BookkeepingTaskDefinition) bool) Pipeline {
+	filtered := make(Pipeline)
+	for taskID, bookkeepingTaskDef := range pc {
+		if pred(taskID, bookkeepingTaskDef) {
+			filtered[taskID] = bookkeepingTaskDef
+		}
+	}
+	return filtered
+}
+
+// Pristine returns a PristinePipeline
+func (pc Pipeline) Pristine() PristinePipeline {
+	pristine := PristinePipeline{}
+	for taskName, taskDef := range pc {
+		pristine[taskName] = taskDef.TaskDefinition
+	}
+	return pristine
+}
+
+// HashablePristine returns a PristinePipeline without the fields of each task that
+// don't affect hashes, e.g. "description"
+func (pc Pipeline) HashablePristine() PristinePipeline {
+	pristine := PristinePipeline{}
+	for taskName, taskDef := range pc {
+		pristine[taskName] = taskDef.TaskDefinition.hashable()
+	}
+	return pristine
+}
+
+// Merge returns a new Pipeline with the tasks of both pc and other. Tasks that are in
+// both are merged with MergeTaskDefinitions, so fields that other sets take precedence,
+// and the result keeps track of the fields that either of them set.
+func (pc Pipeline) Merge(other Pipeline) (Pipeline, error) {
+	merged := pc.Clone()
+	if merged == nil {
+		merged = Pipeline{}
+	}
+
+	for _, taskID := range other.SortedTaskIDs() {
+		childTaskDef := other[taskID]
+		baseTaskDef, ok := pc[taskID]
+		if !ok {
+			merged[taskID] = childTaskDef.clone()
+			continue
+		}
+
+		taskDefinition, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{baseTaskDef, childTaskDef})
+		if err != nil {
+			return nil, err
+		}
+
+		mergedTaskDef := BookkeepingTaskDefinition{
+			definedFields:  baseTaskDef.definedFields.Copy(),
+			Description:    baseTaskDef.Description,
+			TaskDefinition: *taskDefinition,
+		}
+		// Fields that other deleted are no longer set by pc either
+		for _, field := range childTaskDef.deletedFields.UnsafeListOfStrings() {
+			mergedTaskDef.definedFields.Delete(field)
+		}
+		for _, field := range childTaskDef.definedFields.UnsafeListOfStrings() {
+			mergedTaskDef.definedFields.Add(field)
+		}
+		if childTaskDef.deletedFields != nil {
+			mergedTaskDef.deletedFields = childTaskDef.deletedFields.Copy()
+		}
+		if childTaskDef.Description != "" {
+			mergedTaskDef.Description = childTaskDef.Description
+		}
+		if !childTaskDef.hasDeletedField("OutputMode") {
+			mergedTaskDef.defaultOutputMode = baseTaskDef.defaultOutputMode
+		}
+		if childTaskDef.defaultOutputMode != nil {
+			mergedTaskDef.defaultOutputMode = childTaskDef.defaultOutputMode
+		}
+		if !childTaskDef.hasDeletedField("EnvMode") {
+			mergedTaskDef.defaultEnvMode = baseTaskDef.defaultEnvMode
+		}
+		if childTaskDef.defaultEnvMode != nil {
+			mergedTaskDef.defaultEnvMode = childTaskDef.defaultEnvMode
+		}
+
+		// The merged dependsOn and inputs still extend the inherited ones if the ones
+		// that took precedence did
+		if childTaskDef.definesDependsOn() {
+			mergedTaskDef.inheritsDependsOn = childTaskDef.inheritsDependsOn && (baseTaskDef.inheritsDependsOn || !baseTaskDef.definesDependsOn())
+		} else {
+			mergedTaskDef.inheritsDependsOn = baseTaskDef.inheritsDependsOn
+		}
+		if childTaskDef.hasField("Inputs") {
+			mergedTaskDef.inheritsInputs = childTaskDef.inheritsInputs && (baseTaskDef.inheritsInputs || !baseTaskDef.hasField("Inputs"))
+		} else {
+			mergedTaskDef.inheritsInputs = baseTaskDef.inheritsInputs
+		}
+
+		merged[taskID] = mergedTaskDef
+	}
+
+	return merged, nil
+}
+
+// definesDependsOn returns true if any of the dependencies in dependsOn were set
+func (btd BookkeepingTaskDefinition) definesDependsOn() bool {
+	for _, field := range nullableTaskFields["dependsOn"] {
+		if btd.hasField(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithCachingDisabled returns a copy of tj where no task is cached, e.g. for hashing and
+// logging a run with caching turned off, as if every task (including those in
+// Overrides) set "cache" to false. tj is not changed.
+func (tj *TurboJSON) WithCachingDisabled() *TurboJSON {
+	clone := *tj
+	clone.GlobalDeps = copyStrings(tj.GlobalDeps)
+	clone.GlobalEnv = copyStrings(tj.GlobalEnv)
+	clone.GlobalPassThroughEnv = copyStrings(tj.GlobalPassThroughEnv)
+	clone.GlobalDotEnv = copyStrings(tj.GlobalDotEnv)
+	clone.ExcludeScripts = copyStrings(tj.ExcludeScripts)
+	clone.Extends = copyStrings(tj.Extends)
+	clone.warnings = copyStrings(tj.warnings)
+	if tj.remoteCacheFields != nil {
+		clone.remoteCacheFields = tj.remoteCacheFields.Copy()
+	}
+	if tj.Experimental != nil {
+		clone.Experimental = make(map[string]json.RawMessage, len(tj.Experimental))
+		for key, value := range tj.Experimental {
+			clone.Experimental[key] = value
+		}
+	}
+
+	clone.Pipeline = tj.Pipeline.withCachingDisabled()
+	if tj.Overrides != nil {
+		clone.Overrides = make(map[string]Pipeline, len(tj.Overrides))
+		for condition, override := range tj.Overrides {
+			clone.Overrides[condition] = override.withCachingDisabled()
+		}
+	}
+	return &clone
+}
+
+// withCachingDisabled returns a copy of pc where every task sets "cache" to false
+func (pc Pipeline) withCachingDisabled() Pipeline {
+	clone := pc.Clone()
+	for taskID, bookkeepingTaskDef := range clone {
+		bookkeepingTaskDef.TaskDefinition.ShouldCache = false
+		bookkeepingTaskDef.TaskDefinition.Cache = CacheConfig{Local: false, Remote: false}
+		if bookkeepingTaskDef.definedFields == nil {
+			bookkeepingTaskDef.definedFields = make(util.Set)
+		}
+		bookkeepingTaskDef.definedFields.Add("ShouldCache")
+		if bookkeepingTaskDef.deletedFields != nil {
+			bookkeepingTaskDef.deletedFields.Delete("ShouldCache")
+		}
+		clone[taskID] = bookkeepingTaskDef
+	}
+	return clone
+}
+
+// Clone returns a deep copy of the Pipeline, which can be mutated without
+// affecting pc
+func (pc Pipeline) Clone() Pipeline {
+	if pc == nil {
+		return nil
+	}
+	clone := make(Pipeline, len(pc))
+	for taskID, bookkeepingTaskDef := range pc {
+		clone[taskID] = bookkeepingTaskDef.clone()
+	}
+	return clone
+}
+
+// clone returns a deep copy of the BookkeepingTaskDefinition
+func (btd BookkeepingTaskDefinition) clone() BookkeepingTaskDefinition {
+	clone := btd
+	if btd.definedFields != nil {
+		clone.definedFields = btd.definedFields.Copy()
+	}
+	if btd.deletedFields != nil {
+		clone.deletedFields = btd.deletedFields.Copy()
+	}
+	clone.TaskDefinition = btd.TaskDefinition.clone()
+	return clone
+}
+
+// clone returns a deep copy of the TaskDefinition
+func (c TaskDefinition) clone() TaskDefinition {
+	clone := c
+	clone.Outputs.Inclusions = copyStrings(c.Outputs.Inclusions)
+	clone.Outputs.Exclusions = copyStrings(c.Outputs.Exclusions)
+	clone.Outputs.RootInclusions = copyStrings(c.Outputs.RootInclusions)
+	clone.Outputs.RootExclusions = copyStrings(c.Outputs.RootExclusions)
+	clone.EnvVarDependencies = copyStrings(c.EnvVarDependencies)
+	clone.PassThroughEnv = copyStrings(c.PassThroughEnv)
+	clone.TopologicalDependencies = copyStrings(c.TopologicalDependencies)
+	clone.TaskDependencies = copyStrings(c.TaskDependencies)
+	clone.RootTaskDependencies = copyStrings(c.RootTaskDependencies)
+	clone.TaskDependencyGlobs = copyStrings(c.TaskDependencyGlobs)
+	clone.Inputs = copyStrings(c.Inputs)
+	clone.With = copyStrings(c.With)
+	clone.DotEnv = copyStrings(c.DotEnv)
+	return clone
+}
+
+// copyStrings returns a copy of values, preserving whether it is nil
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// hasField checks the internal bookkeeping definedFields field to
+// see whether a field was actually in the underlying turbo.json
+// or whether it was initialized with its 0-value.
+func (btd BookkeepingTaskDefinition) hasField(fieldName string) bool {
+	return btd.definedFields.Includes(fieldName)
+}
+
+// DefinedFields returns the sorted names of the TaskDefinition fields that were
+// explicitly set in the source configFile, as opposed to being left as defaults
+func (btd BookkeepingTaskDefinition) DefinedFields() []string {
+	fields := btd.definedFields.UnsafeListOfStrings()
+	sort.Strings(fields)
+	return fields
+}
+
+// definedValues returns the keys of configFile that the task sets, with their
+// marshaled values. Keys that were set to null are included as null.
+func (btd BookkeepingTaskDefinition) definedValues() (map[string]json.RawMessage, error) {
+	taskDefinition := btd.TaskDefinition
+	taskDefinition.Normalize()
+	data, err := json.Marshal(taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	marshaled := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &marshaled); err != nil {
+		return nil, err
 	}
-	_, task := util.GetPackageTaskFromId(taskID)
-	entry, ok := pc[task]
-	return entry.TaskDefinition, ok
-}
 
-// HasTask returns true if the given task is defined in the pipeline, either directly or
-// via a package task (`pkg#task`)
-func (pc Pipeline) HasTask(task string) bool {
-	for key := range pc {
-		if key == task {
-			return true
+	// A loose envMode is omitted because it is the default, but a task that sets it
+	// explicitly has to keep it, or it would pick up a global envMode when read back
+	if btd.hasField("EnvMode") {
+		data, err := json.Marshal(taskDefinition.EnvMode)
+		if err != nil {
+			return nil, err
 		}
-		if util.IsPackageTask(key) {
-			_, taskName := util.GetPackageTaskFromId(key)
-			if taskName == task {
-				return true
+		marshaled["envMode"] = data
+	}
+
+	values := map[string]json.RawMessage{}
+	for key, fields := range nullableTaskFields {
+		for _, field := range fields {
+			if btd.hasField(field) {
+				values[key] = marshaled[key]
+				break
+			}
+			if btd.hasDeletedField(field) {
+				values[key] = nil
 			}
 		}
+		// Values that are omitted when marshaling were set to their zero value
+		if value, ok := values[key]; ok && value == nil {
+			values[key] = json.RawMessage("null")
+		}
 	}
-	return false
+	return values, nil
 }
 
-// Pristine returns a PristinePipeline
-func (pc Pipeline) Pristine() PristinePipeline {
-	pristine := PristinePipeline{}
-	for taskName, taskDef := range pc {
-		pristine[taskName] = taskDef.TaskDefinition
-	}
-	return pristine
+// hasDeletedField returns true if the field was explicitly set to null
+func (btd BookkeepingTaskDefinition) hasDeletedField(fieldName string) bool {
+	return btd.deletedFields.Includes(fieldName)
 }
 
-// hasField checks the internal bookkeeping definedFields field to
-// see whether a field was actually in the underlying turbo.json
-// or whether it was initialized with its 0-value.
-func (btd BookkeepingTaskDefinition) hasField(fieldName string) bool {
-	return btd.definedFields.Includes(fieldName)
+// resetField restores a bookkeeping field of the TaskDefinition to its default value
+func (c *TaskDefinition) resetField(fieldName string) {
+	switch fieldName {
+	case "Outputs":
+		c.Outputs = TaskOutputs{}
+		c.ExplicitOutputs = false
+	case "ShouldCache":
+		c.ShouldCache = true
+		c.Cache = CacheConfig{Local: true, Remote: true}
+	case "CacheDisabledReason":
+		c.CacheDisabledReason = ""
+	case "CacheKey":
+		c.CacheKey = ""
+	case "EnvVarDependencies":
+		c.EnvVarDependencies = nil
+	case "PassThroughEnv":
+		c.PassThroughEnv = nil
+	case "TopologicalDependencies":
+		c.TopologicalDependencies = nil
+	case "TaskDependencies":
+		c.TaskDependencies = nil
+	case "RootTaskDependencies":
+		c.RootTaskDependencies = nil
+	case "TaskDependencyGlobs":
+		c.TaskDependencyGlobs = nil
+	case "Inputs":
+		c.Inputs = nil
+		c.DefaultInputs = false
+		c.ExplicitInputs = false
+	case "OutputMode":
+		c.OutputMode = util.FullTaskOutput
+	case "OutputLogs":
+		c.OutputLogs = util.FullTaskOutputLogs
+	case "EnvMode":
+		c.EnvMode = util.LooseEnvMode
+	case "Persistent":
+		c.Persistent = false
+	case "Interactive":
+		c.Interactive = false
+	case "With":
+		c.With = nil
+	case "DotEnv":
+		c.DotEnv = nil
+	case "Timeout":
+		c.Timeout = 0
+	case "Weight":
+		c.Weight = defaultTaskWeight
+	case "Retries":
+		c.Retries = 0
+	case "Description":
+		c.Description = ""
+	}
 }
 
 // MergeTaskDefinitions accepts an array of BookkeepingTaskDefinitions and merges them into
 // a single TaskDefinition. It uses the bookkeeping definedFields to determine which fields should
 // be overwritten and when 0-values should be respected.
 func MergeTaskDefinitions(taskDefinitions []BookkeepingTaskDefinition) (*TaskDefinition, error) {
+	mergedTaskDefinition, _, err := MergeTaskDefinitionsWithTrace(taskDefinitions)
+	return mergedTaskDefinition, err
+}
+
+// MergeTaskDefinitionsWithTrace merges like MergeTaskDefinitions, and additionally
+// returns a map from each field that was set to the index of the layer in
+// taskDefinitions that last set it (i.e. the layer whose value won).
+// Fields that were not set by any layer keep their defaults and are not in the map.
+func MergeTaskDefinitionsWithTrace(taskDefinitions []BookkeepingTaskDefinition) (*TaskDefinition, map[string]int, error) {
+	trace := map[string]int{}
+	var defaultOutputMode *util.TaskOutputMode
+	var defaultEnvMode *util.EnvMode
+
 	// Start with an empty definition
 	mergedTaskDefinition := &TaskDefinition{}
 
 	// Set the default, because the 0-value will be false, and if no turbo.jsons had
 	// this field set for this task, we want it to be true.
 	mergedTaskDefinition.ShouldCache = true
+	mergedTaskDefinition.Cache = CacheConfig{Local: true, Remote: true}
+	mergedTaskDefinition.Weight = defaultTaskWeight
 
 	// For each of the TaskDefinitions we know of, merge them in
-	for _, bookkeepingTaskDef := range taskDefinitions {
+	for i, bookkeepingTaskDef := range taskDefinitions {
 		taskDef := bookkeepingTaskDef.TaskDefinition
+		for _, field := range bookkeepingTaskDef.definedFields.UnsafeListOfStrings() {
+			trace[field] = i
+		}
+
+		// Fields that were set to null remove whatever was inherited from earlier layers
+		for _, field := range bookkeepingTaskDef.deletedFields.UnsafeListOfStrings() {
+			mergedTaskDefinition.resetField(field)
+			delete(trace, field)
+		}
+
 		if bookkeepingTaskDef.hasField("Outputs") {
 			mergedTaskDefinition.Outputs = taskDef.Outputs
+			mergedTaskDefinition.ExplicitOutputs = taskDef.ExplicitOutputs
 		}
 
 		if bookkeepingTaskDef.hasField("ShouldCache") {
 			mergedTaskDefinition.ShouldCache = taskDef.ShouldCache
+			mergedTaskDefinition.Cache = taskDef.Cache
+		}
+
+		if bookkeepingTaskDef.hasField("CacheDisabledReason") {
+			mergedTaskDefinition.CacheDisabledReason = taskDef.CacheDisabledReason
+		}
+
+		if bookkeepingTaskDef.hasField("CacheKey") {
+			mergedTaskDefinition.CacheKey = taskDef.CacheKey
 		}
 
 		if bookkeepingTaskDef.hasField("EnvVarDependencies") {
 			mergedTaskDefinition.EnvVarDependencies = taskDef.EnvVarDependencies
 		}
 
+		if bookkeepingTaskDef.hasField("PassThroughEnv") {
+			mergedTaskDefinition.PassThroughEnv = taskDef.PassThroughEnv
+		}
+
+		// A "..." entry in dependsOn adds to the dependencies from earlier layers
+		// instead of replacing them
 		if bookkeepingTaskDef.hasField("TopologicalDependencies") {
-			mergedTaskDefinition.TopologicalDependencies = taskDef.TopologicalDependencies
+			if bookkeepingTaskDef.inheritsDependsOn {
+				mergedTaskDefinition.TopologicalDependencies = unionStrings(mergedTaskDefinition.TopologicalDependencies, taskDef.TopologicalDependencies)
+			} else {
+				mergedTaskDefinition.TopologicalDependencies = taskDef.TopologicalDependencies
+			}
 		}
 
 		if bookkeepingTaskDef.hasField("TaskDependencies") {
-			mergedTaskDefinition.TaskDependencies = taskDef.TaskDependencies
+			if bookkeepingTaskDef.inheritsDependsOn {
+				mergedTaskDefinition.TaskDependencies = unionStrings(mergedTaskDefinition.TaskDependencies, taskDef.TaskDependencies)
+			} else {
+				mergedTaskDefinition.TaskDependencies = taskDef.TaskDependencies
+			}
+		}
+
+		if bookkeepingTaskDef.hasField("RootTaskDependencies") {
+			if bookkeepingTaskDef.inheritsDependsOn {
+				mergedTaskDefinition.RootTaskDependencies = unionStrings(mergedTaskDefinition.RootTaskDependencies, taskDef.RootTaskDependencies)
+			} else {
+				mergedTaskDefinition.RootTaskDependencies = taskDef.RootTaskDependencies
+			}
+		}
+
+		if bookkeepingTaskDef.hasField("TaskDependencyGlobs") {
+			if bookkeepingTaskDef.inheritsDependsOn {
+				mergedTaskDefinition.TaskDependencyGlobs = unionStrings(mergedTaskDefinition.TaskDependencyGlobs, taskDef.TaskDependencyGlobs)
+			} else {
+				mergedTaskDefinition.TaskDependencyGlobs = taskDef.TaskDependencyGlobs
+			}
 		}
 
+		// A "..." entry in inputs adds to the inputs from earlier layers instead of
+		// replacing them
 		if bookkeepingTaskDef.hasField("Inputs") {
-			mergedTaskDefinition.Inputs = taskDef.Inputs
+			if bookkeepingTaskDef.inheritsInputs {
+				mergedTaskDefinition.Inputs = unionStrings(mergedTaskDefinition.Inputs, taskDef.Inputs)
+				mergedTaskDefinition.DefaultInputs = mergedTaskDefinition.DefaultInputs || taskDef.DefaultInputs
+			} else {
+				mergedTaskDefinition.Inputs = taskDef.Inputs
+				mergedTaskDefinition.DefaultInputs = taskDef.DefaultInputs
+			}
+			mergedTaskDefinition.ExplicitInputs = taskDef.ExplicitInputs
 		}
 
 		if bookkeepingTaskDef.hasField("OutputMode") {
 			mergedTaskDefinition.OutputMode = taskDef.OutputMode
 		}
+
+		if bookkeepingTaskDef.hasField("OutputLogs") {
+			mergedTaskDefinition.OutputLogs = taskDef.OutputLogs
+		}
+
+		if bookkeepingTaskDef.hasField("EnvMode") {
+			mergedTaskDefinition.EnvMode = taskDef.EnvMode
+		}
+
 		if bookkeepingTaskDef.hasField("Persistent") {
 			mergedTaskDefinition.Persistent = taskDef.Persistent
 		}
+
+		if bookkeepingTaskDef.hasField("Interactive") {
+			mergedTaskDefinition.Interactive = taskDef.Interactive
+		}
+
+		if bookkeepingTaskDef.hasField("With") {
+			mergedTaskDefinition.With = taskDef.With
+		}
+
+		if bookkeepingTaskDef.hasField("DotEnv") {
+			mergedTaskDefinition.DotEnv = taskDef.DotEnv
+		}
+
+		if bookkeepingTaskDef.hasField("Timeout") {
+			mergedTaskDefinition.Timeout = taskDef.Timeout
+		}
+
+		if bookkeepingTaskDef.hasField("Weight") {
+			mergedTaskDefinition.Weight = taskDef.Weight
+		}
+
+		if bookkeepingTaskDef.hasField("Retries") {
+			mergedTaskDefinition.Retries = taskDef.Retries
+		}
+
+		if bookkeepingTaskDef.hasField("Description") {
+			mergedTaskDefinition.Description = taskDef.Description
+		}
+
+		// A global default is inherited like a task's own keys, but only used when no
+		// layer sets the key itself
+		if bookkeepingTaskDef.hasDeletedField("OutputMode") {
+			defaultOutputMode = nil
+		}
+		if bookkeepingTaskDef.defaultOutputMode != nil {
+			defaultOutputMode = bookkeepingTaskDef.defaultOutputMode
+		}
+		if bookkeepingTaskDef.hasDeletedField("EnvMode") {
+			defaultEnvMode = nil
+		}
+		if bookkeepingTaskDef.defaultEnvMode != nil {
+			defaultEnvMode = bookkeepingTaskDef.defaultEnvMode
+		}
 	}
 
-	return mergedTaskDefinition, nil
+	if _, ok := trace["OutputMode"]; !ok && defaultOutputMode != nil {
+		mergedTaskDefinition.OutputMode = *defaultOutputMode
+	}
+	if _, ok := trace["EnvMode"]; !ok && defaultEnvMode != nil {
+		mergedTaskDefinition.EnvMode = *defaultEnvMode
+	}
+
+	mergedTaskDefinition.Normalize()
+
+	return mergedTaskDefinition, trace, nil
 }
 
 // UnmarshalJSON deserializes a single task definition from
@@ -389,42 +2372,114 @@ func (btd *BookkeepingTaskDefinition) UnmarshalJSON(data []byte) error {
 	}
 
 	btd.definedFields = util.Set{}
+	btd.deletedFields = nil
+	btd.warnings = nil
+
+	// rawTask can't distinguish null from a missing key, so look for nulls separately
+	var rawFields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawFields); err != nil {
+		return err
+	}
+	for key, value := range rawFields {
+		if fields, ok := nullableTaskFields[key]; ok && string(bytes.TrimSpace(value)) == "null" {
+			if btd.deletedFields == nil {
+				btd.deletedFields = util.Set{}
+			}
+			for _, field := range fields {
+				btd.deletedFields.Add(field)
+			}
+		}
+	}
 
-	if task.Outputs != nil {
+	if task.Outputs != nil || task.OutputsExclude != nil {
 		var inclusions []string
-		var exclusions []string
+		exclusions := make(util.Set)
+		var rootInclusions []string
+		rootExclusions := make(util.Set)
 		// Assign a bookkeeping field so we know that there really were
 		// outputs configured in the underlying config file.
 		btd.definedFields.Add("Outputs")
 
+		// "outputsExclude" is the same as "!"-prefixed globs in "outputs"
+		excludedGlobs := []string{}
 		for _, glob := range task.Outputs {
 			if strings.HasPrefix(glob, "!") {
-				if filepath.IsAbs(glob[1:]) {
-					log.Printf("[WARNING] Using an absolute path in \"outputs\" (%v) will not work and will be an error in a future version", glob)
-				}
-				exclusions = append(exclusions, glob[1:])
+				excludedGlobs = append(excludedGlobs, glob[1:])
+			}
+		}
+		for _, glob := range task.OutputsExclude {
+			if strings.HasPrefix(glob, "!") {
+				return &invalidTaskValueError{key: "outputsExclude", value: strconv.Quote(glob), err: fmt.Errorf("globs are already excluded, remove the \"!\"")}
+			}
+			excludedGlobs = append(excludedGlobs, glob)
+		}
+		for _, glob := range excludedGlobs {
+			if filepath.IsAbs(glob) {
+				btd.warnings = append(btd.warnings, fmt.Sprintf("Using an absolute path in \"outputs\" (!%v) will not work and will be an error in a future version", glob))
+			}
+			if rootGlob, ok := trimTurboRootPrefix(glob); ok {
+				rootExclusions.Add(rootGlob)
+			} else {
+				exclusions.Add(glob)
+			}
+		}
+
+		for _, glob := range task.Outputs {
+			if strings.HasPrefix(glob, "!") {
+				continue
+			}
+			if filepath.IsAbs(glob) {
+				btd.warnings = append(btd.warnings, fmt.Sprintf("Using an absolute path in \"outputs\" (%v) will not work and will be an error in a future version", glob))
+			}
+			if rootGlob, ok := trimTurboRootPrefix(glob); ok {
+				rootInclusions = append(rootInclusions, rootGlob)
 			} else {
-				if filepath.IsAbs(glob) {
-					log.Printf("[WARNING] Using an absolute path in \"outputs\" (%v) will not work and will be an error in a future version", glob)
-				}
 				inclusions = append(inclusions, glob)
 			}
 		}
 
 		btd.TaskDefinition.Outputs = TaskOutputs{
-			Inclusions: inclusions,
-			Exclusions: exclusions,
+			Inclusions:     inclusions,
+			RootInclusions: rootInclusions,
+		}
+		btd.TaskDefinition.ExplicitOutputs = task.Outputs != nil
+		if exclusions.Len() > 0 {
+			btd.TaskDefinition.Outputs.Exclusions = exclusions.UnsafeListOfStrings()
+		}
+		if rootExclusions.Len() > 0 {
+			btd.TaskDefinition.Outputs.RootExclusions = rootExclusions.UnsafeListOfStrings()
 		}
 
 		sort.Strings(btd.TaskDefinition.Outputs.Inclusions)
 		sort.Strings(btd.TaskDefinition.Outputs.Exclusions)
+		sort.Strings(btd.TaskDefinition.Outputs.RootInclusions)
+		sort.Strings(btd.TaskDefinition.Outputs.RootExclusions)
 	}
 
 	if task.Cache == nil {
 		btd.TaskDefinition.ShouldCache = true
+		btd.TaskDefinition.Cache = CacheConfig{Local: true, Remote: true}
 	} else {
+		// ShouldCache and Cache are always set together, so they share a bookkeeping field
 		btd.definedFields.Add("ShouldCache")
-		btd.TaskDefinition.ShouldCache = *task.Cache
+		btd.TaskDefinition.Cache = CacheConfig{
+			Local:  task.Cache.Local == nil || *task.Cache.Local,
+			Remote: task.Cache.Remote == nil || *task.Cache.Remote,
+		}
+		btd.TaskDefinition.ShouldCache = btd.TaskDefinition.Cache.Local || btd.TaskDefinition.Cache.Remote
+	}
+
+	if task.CacheDisabledReason != nil {
+		btd.definedFields.Add("CacheDisabledReason")
+		btd.TaskDefinition.CacheDisabledReason = *task.CacheDisabledReason
+		if task.Cache != nil && btd.TaskDefinition.ShouldCache {
+			btd.warnings = append(btd.warnings, fmt.Sprintf("\"cacheDisabledReason\" (%v) is set on a task with \"cache\" enabled and will be ignored", *task.CacheDisabledReason))
+		}
+	}
+
+	if task.CacheKey != nil {
+		btd.definedFields.Add("CacheKey")
+		btd.TaskDefinition.CacheKey = *task.CacheKey
 	}
 
 	envVarDependencies := make(util.Set)
@@ -433,13 +2488,26 @@ func (btd *BookkeepingTaskDefinition) UnmarshalJSON(data []byte) error {
 	btd.TaskDefinition.TaskDependencies = []string{}        // TODO @mehulkar: this should be a set
 
 	for _, dependency := range task.DependsOn {
-		if strings.HasPrefix(dependency, envPipelineDelimiter) {
-			log.Printf("[DEPRECATED] Declaring an environment variable in \"dependsOn\" is deprecated, found %s. Use the \"env\" key or use `npx @turbo/codemod migrate-env-var-dependencies`.\n", dependency)
+		if dependency == inheritedDependsOn {
+			btd.inheritsDependsOn = true
+		} else if strings.HasPrefix(dependency, envPipelineDelimiter) {
+			btd.warnings = append(btd.warnings, fmt.Sprintf(deprecatedEnvInDependsOn, dependency))
 			envVarDependencies.Add(strings.TrimPrefix(dependency, envPipelineDelimiter))
 		} else if strings.HasPrefix(dependency, topologicalPipelineDelimiter) {
 			// Note: This will get assigned multiple times in the loop, but we only care that it's true
 			btd.definedFields.Add("TopologicalDependencies")
 			btd.TaskDefinition.TopologicalDependencies = append(btd.TaskDefinition.TopologicalDependencies, strings.TrimPrefix(dependency, topologicalPipelineDelimiter))
+		} else if strings.HasPrefix(dependency, util.RootPkgName) && !util.IsPackageTask(dependency) {
+			// "//lint" is shorthand for the root workspace's "lint" task. Note that
+			// "//#lint" is a regular package task and is handled below.
+			btd.definedFields.Add("RootTaskDependencies")
+			btd.TaskDefinition.RootTaskDependencies = append(btd.TaskDefinition.RootTaskDependencies, strings.TrimPrefix(dependency, util.RootPkgName))
+		} else if isTaskDependencyGlob(dependency) {
+			if _, err := path.Match(dependency, ""); err != nil {
+				return &invalidTaskValueError{key: "dependsOn", value: strconv.Quote(dependency), err: err}
+			}
+			btd.definedFields.Add("TaskDependencyGlobs")
+			btd.TaskDefinition.TaskDependencyGlobs = append(btd.TaskDefinition.TaskDependencyGlobs, dependency)
 		} else {
 			// Note: This will get assigned multiple times in the loop, but we only care that it's true
 			btd.definedFields.Add("TaskDependencies")
@@ -449,18 +2517,21 @@ func (btd *BookkeepingTaskDefinition) UnmarshalJSON(data []byte) error {
 
 	sort.Strings(btd.TaskDefinition.TaskDependencies)
 	sort.Strings(btd.TaskDefinition.TopologicalDependencies)
+	sort.Strings(btd.TaskDefinition.RootTaskDependencies)
+	sort.Strings(btd.TaskDefinition.TaskDependencyGlobs)
 
 	// Append env key into EnvVarDependencies
 	if task.Env != nil {
 		btd.definedFields.Add("EnvVarDependencies")
 		for _, value := range task.Env {
-			if strings.HasPrefix(value, envPipelineDelimiter) {
-				// Hard error to help people specify this correctly during migration.
-				// TODO: Remove this error after we have run summary.
-				return fmt.Errorf("You specified \"%s\" in the \"env\" key. You should not prefix your environment variables with \"$\"", value)
+			// Entries may be wildcard patterns (e.g. "MY_APP_*" or "!MY_APP_SECRET"),
+			// which we store as-is and match against the environment later.
+			envVar, err := parseEnvDeclaration("env", value)
+			if err != nil {
+				return err
 			}
 
-			envVarDependencies.Add(value)
+			envVarDependencies.Add(envVar)
 		}
 	}
 
@@ -468,17 +2539,39 @@ func (btd *BookkeepingTaskDefinition) UnmarshalJSON(data []byte) error {
 
 	sort.Strings(btd.TaskDefinition.EnvVarDependencies)
 
+	if task.PassThroughEnv != nil {
+		btd.definedFields.Add("PassThroughEnv")
+		for _, value := range task.PassThroughEnv {
+			if strings.HasPrefix(value, envPipelineDelimiter) {
+				return fmt.Errorf("You specified \"%s\" in the \"passThroughEnv\" key. You should not prefix your environment variables with \"%s\"", value, envPipelineDelimiter)
+			}
+		}
+		btd.TaskDefinition.PassThroughEnv = task.PassThroughEnv
+		sort.Strings(btd.TaskDefinition.PassThroughEnv)
+	}
+
 	if task.Inputs != nil {
 		// Note that we don't require Inputs to be sorted, we're going to
 		// hash the resulting files and sort that instead
 		btd.definedFields.Add("Inputs")
+		btd.TaskDefinition.ExplicitInputs = true
+		inputs := []string{}
 		// TODO: during rust port, this should be moved to a post-parse validation step
 		for _, input := range task.Inputs {
+			if input == turboDefaultInputs {
+				btd.TaskDefinition.DefaultInputs = true
+				continue
+			}
+			if input == inheritedInputs {
+				btd.inheritsInputs = true
+				continue
+			}
 			if filepath.IsAbs(input) {
-				log.Printf("[WARNING] Using an absolute path in \"inputs\" (%v) will not work and will be an error in a future version", input)
+				btd.warnings = append(btd.warnings, fmt.Sprintf("Using an absolute path in \"inputs\" (%v) will not work and will be an error in a future version", input))
 			}
+			inputs = append(inputs, input)
 		}
-		btd.TaskDefinition.Inputs = task.Inputs
+		btd.TaskDefinition.Inputs = inputs
 	}
 
 	if task.OutputMode != nil {
@@ -486,17 +2579,92 @@ func (btd *BookkeepingTaskDefinition) UnmarshalJSON(data []byte) error {
 		btd.TaskDefinition.OutputMode = *task.OutputMode
 	}
 
+	if task.OutputLogs != nil {
+		btd.definedFields.Add("OutputLogs")
+		btd.TaskDefinition.OutputLogs = *task.OutputLogs
+	}
+
+	if task.EnvMode != nil {
+		btd.definedFields.Add("EnvMode")
+		btd.TaskDefinition.EnvMode = *task.EnvMode
+	}
+
 	if task.Persistent != nil {
 		btd.definedFields.Add("Persistent")
 		btd.TaskDefinition.Persistent = *task.Persistent
 	} else {
 		btd.TaskDefinition.Persistent = false
 	}
+
+	if task.Interactive != nil {
+		btd.definedFields.Add("Interactive")
+		btd.TaskDefinition.Interactive = *task.Interactive
+		if *task.Interactive && task.Cache != nil && btd.TaskDefinition.ShouldCache {
+			btd.warnings = append(btd.warnings, "Interactive tasks should not be cached, set \"cache\" to false for tasks with \"interactive\" enabled")
+		}
+	}
+
+	if task.With != nil {
+		btd.definedFields.Add("With")
+		btd.TaskDefinition.With = append([]string{}, task.With...)
+		sort.Strings(btd.TaskDefinition.With)
+	}
+
+	if task.DotEnv != nil {
+		btd.definedFields.Add("DotEnv")
+		// TODO: during rust port, this should be moved to a post-parse validation step
+		for _, dotEnvPath := range task.DotEnv {
+			if filepath.IsAbs(dotEnvPath) {
+				btd.warnings = append(btd.warnings, fmt.Sprintf("Using an absolute path in \"dotEnv\" (%v) will not work and will be an error in a future version", dotEnvPath))
+			}
+		}
+		btd.TaskDefinition.DotEnv = append([]string{}, task.DotEnv...)
+	}
+
+	if task.Timeout != nil {
+		timeout, err := time.ParseDuration(*task.Timeout)
+		if err != nil {
+			return &invalidTaskValueError{key: "timeout", value: strconv.Quote(*task.Timeout), err: err}
+		}
+		if timeout < 0 {
+			return &invalidTaskValueError{key: "timeout", value: strconv.Quote(*task.Timeout), err: fmt.Errorf("must not be negative")}
+		}
+		btd.definedFields.Add("Timeout")
+		btd.TaskDefinition.Timeout = timeout
+	}
+
+	if task.Weight != nil {
+		if *task.Weight < 1 {
+			return &invalidTaskValueError{key: "weight", value: strconv.Itoa(*task.Weight), err: fmt.Errorf("must be a positive integer")}
+		}
+		btd.definedFields.Add("Weight")
+		btd.TaskDefinition.Weight = *task.Weight
+	} else {
+		btd.TaskDefinition.Weight = defaultTaskWeight
+	}
+
+	if task.Retries != nil {
+		if *task.Retries < 0 {
+			return &invalidTaskValueError{key: "retries", value: strconv.Itoa(*task.Retries), err: fmt.Errorf("must not be negative")}
+		}
+		btd.definedFields.Add("Retries")
+		btd.TaskDefinition.Retries = *task.Retries
+	}
+
+	if task.Description != nil {
+		btd.definedFields.Add("Description")
+		btd.TaskDefinition.Description = *task.Description
+	}
 	return nil
 }
 
 // MarshalJSON serializes TaskDefinition struct into json
 func (c TaskDefinition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.withDefaults())
+}
+
+// withDefaults converts the TaskDefinition into the shape it is serialized in
+func (c TaskDefinition) withDefaults() rawTaskWithDefaults {
 	// Initialize with empty arrays, so we get empty arrays serialized into JSON
 	task := rawTaskWithDefaults{
 		Outputs:   []string{},
@@ -506,42 +2674,80 @@ func (c TaskDefinition) MarshalJSON() ([]byte, error) {
 	}
 
 	task.Persistent = c.Persistent
-	task.Cache = &c.ShouldCache
+	task.Interactive = c.Interactive
+	if len(c.With) > 0 {
+		task.With = sortedStrings(c.With)
+	}
+	if len(c.DotEnv) > 0 {
+		task.DotEnv = append([]string{}, c.DotEnv...)
+	}
+	if c.Timeout > 0 {
+		task.Timeout = c.Timeout.String()
+	}
+	// Only tasks that take up more than the default slot are worth calling out
+	if c.Weight > defaultTaskWeight {
+		task.Weight = c.Weight
+	}
+	task.Retries = c.Retries
+	task.Description = c.Description
+	// Only use the object form when local and remote caching differ
+	if c.ShouldCache && c.Cache.Local != c.Cache.Remote {
+		task.Cache = c.Cache
+	} else {
+		task.Cache = &c.ShouldCache
+	}
+	task.CacheDisabledReason = c.CacheDisabledReason
+	task.CacheKey = c.CacheKey
 	task.OutputMode = c.OutputMode
+	task.OutputLogs = c.OutputLogs
+	// Loose is the default, so only strict tasks call it out
+	if c.EnvMode != util.LooseEnvMode {
+		envMode := c.EnvMode
+		task.EnvMode = &envMode
+	}
 
 	if len(c.Inputs) > 0 {
-		task.Inputs = c.Inputs
+		task.Inputs = append(task.Inputs, c.Inputs...)
 	}
 
-	if len(c.EnvVarDependencies) > 0 {
-		task.Env = append(task.Env, c.EnvVarDependencies...)
+	if c.DefaultInputs {
+		task.Inputs = append(task.Inputs, turboDefaultInputs)
 	}
 
-	if len(c.Outputs.Inclusions) > 0 {
-		task.Outputs = append(task.Outputs, c.Outputs.Inclusions...)
+	if len(c.EnvVarDependencies) > 0 {
+		task.Env = escapeEnvDeclarations(c.EnvVarDependencies)
 	}
 
-	for _, i := range c.Outputs.Exclusions {
-		task.Outputs = append(task.Outputs, "!"+i)
+	if len(c.PassThroughEnv) > 0 {
+		task.PassThroughEnv = append([]string{}, c.PassThroughEnv...)
 	}
 
+	task.Outputs = c.Outputs.Globs()
+
 	if len(c.TaskDependencies) > 0 {
 		task.DependsOn = append(task.DependsOn, c.TaskDependencies...)
 	}
 
+	task.DependsOn = append(task.DependsOn, c.TaskDependencyGlobs...)
+
 	for _, i := range c.TopologicalDependencies {
 		task.DependsOn = append(task.DependsOn, "^"+i)
 	}
 
+	for _, i := range c.RootTaskDependencies {
+		task.DependsOn = append(task.DependsOn, util.RootPkgName+i)
+	}
+
 	// These _should_ already be sorted when the TaskDefinition struct was unmarshaled,
 	// but we want to ensure they're sorted on the way out also, just in case something
 	// in the middle mutates the items.
 	sort.Strings(task.DependsOn)
 	sort.Strings(task.Outputs)
 	sort.Strings(task.Env)
+	sort.Strings(task.PassThroughEnv)
 	sort.Strings(task.Inputs)
 
-	return json.Marshal(task)
+	return task
 }
 
 // UnmarshalJSON deserializes the contents of turbo.json into a TurboJSON struct
@@ -550,28 +2756,49 @@ func (c *TurboJSON) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
+	c.warnings = nil
 
 	envVarDependencies := make(util.Set)
 	globalFileDependencies := make(util.Set)
 
 	for _, value := range raw.GlobalEnv {
-		if strings.HasPrefix(value, envPipelineDelimiter) {
-			// Hard error to help people specify this correctly during migration.
-			// TODO: Remove this error after we have run summary.
-			return fmt.Errorf("You specified \"%s\" in the \"env\" key. You should not prefix your environment variables with \"%s\"", value, envPipelineDelimiter)
+		envVar, err := parseEnvDeclaration("env", value)
+		if err != nil {
+			return err
+		}
+
+		envVarDependencies.Add(envVar)
+	}
+
+	if raw.GlobalPassThroughEnv != nil {
+		for _, value := range raw.GlobalPassThroughEnv {
+			if strings.HasPrefix(value, envPipelineDelimiter) {
+				// Hard error to help people specify this correctly during migration.
+				// TODO: Remove this error after we have run summary.
+				return fmt.Errorf("You specified \"%s\" in the \"globalPassThroughEnv\" key. You should not prefix your environment variables with \"%s\"", value, envPipelineDelimiter)
+			}
 		}
+		c.GlobalPassThroughEnv = append([]string{}, raw.GlobalPassThroughEnv...)
+		sort.Strings(c.GlobalPassThroughEnv)
+	}
 
-		envVarDependencies.Add(value)
+	if raw.GlobalDotEnv != nil {
+		for _, value := range raw.GlobalDotEnv {
+			if filepath.IsAbs(value) {
+				c.warnings = append(c.warnings, fmt.Sprintf("Using an absolute path in \"globalDotEnv\" (%v) will not work and will be an error in a future version", value))
+			}
+		}
+		c.GlobalDotEnv = append([]string{}, raw.GlobalDotEnv...)
 	}
 
 	// TODO: In the rust port, warnings should be refactored to a post-parse validation step
 	for _, value := range raw.GlobalDependencies {
 		if strings.HasPrefix(value, envPipelineDelimiter) {
-			log.Printf("[DEPRECATED] Declaring an environment variable in \"globalDependencies\" is deprecated, found %s. Use the \"globalEnv\" key or use `npx @turbo/codemod migrate-env-var-dependencies`.\n", value)
+			c.warnings = append(c.warnings, fmt.Sprintf(deprecatedEnvInGlobalDependencies, value))
 			envVarDependencies.Add(strings.TrimPrefix(value, envPipelineDelimiter))
 		} else {
 			if filepath.IsAbs(value) {
-				log.Printf("[WARNING] Using an absolute path in \"globalDependencies\" (%v) will not work and will be an error in a future version", value)
+				c.warnings = append(c.warnings, fmt.Sprintf("Using an absolute path in \"globalDependencies\" (%v) will not work and will be an error in a future version", value))
 			}
 			globalFileDependencies.Add(value)
 		}
@@ -584,9 +2811,80 @@ func (c *TurboJSON) UnmarshalJSON(data []byte) error {
 	sort.Strings(c.GlobalDeps)
 
 	// copy these over, we don't need any changes here.
+	c.Schema = raw.Schema
 	c.Pipeline = raw.Pipeline
+	c.Overrides = raw.Overrides
 	c.RemoteCacheOptions = raw.RemoteCacheOptions
 	c.Extends = raw.Extends
+	c.OutputMode = raw.OutputMode
+	c.EnvMode = raw.EnvMode
+	if raw.Experimental != nil {
+		warnings, err := validateExperimentalOptions(raw.Experimental)
+		if err != nil {
+			return err
+		}
+		c.warnings = append(c.warnings, warnings...)
+		c.Experimental = raw.Experimental
+	}
+
+	// Move the warnings of each task to the config, where they can be reported with
+	// the ID of the task they belong to
+	for _, taskID := range c.Pipeline.SortedTaskIDs() {
+		task := c.Pipeline[taskID]
+		for _, warning := range task.warnings {
+			c.warnings = append(c.warnings, fmt.Sprintf("task \"%s\": %s", taskID, warning))
+		}
+		task.warnings = nil
+		c.Pipeline[taskID] = task
+	}
+	conditions := make([]string, 0, len(c.Overrides))
+	for condition := range c.Overrides {
+		conditions = append(conditions, condition)
+	}
+	sort.Strings(conditions)
+	for _, condition := range conditions {
+		override := c.Overrides[condition]
+		for _, taskID := range override.SortedTaskIDs() {
+			task := override[taskID]
+			for _, warning := range task.warnings {
+				c.warnings = append(c.warnings, fmt.Sprintf("overrides \"%s\": task \"%s\": %s", condition, taskID, warning))
+			}
+			task.warnings = nil
+			override[taskID] = task
+		}
+	}
+
+	if raw.ExcludeScripts != nil {
+		c.ExcludeScripts = append([]string{}, raw.ExcludeScripts...)
+		sort.Strings(c.ExcludeScripts)
+	}
+
+	// Tasks that don't set their own outputMode or envMode inherit the global one. It is
+	// kept apart from the keys the task defines, so that it doesn't override the tasks of
+	// configurations that this extends.
+	for taskID, bookkeepingTaskDef := range c.Pipeline {
+		if c.OutputMode != nil && !bookkeepingTaskDef.hasField("OutputMode") && !bookkeepingTaskDef.hasDeletedField("OutputMode") {
+			bookkeepingTaskDef.TaskDefinition.OutputMode = *c.OutputMode
+			bookkeepingTaskDef.defaultOutputMode = c.OutputMode
+		}
+		if c.EnvMode != nil && !bookkeepingTaskDef.hasField("EnvMode") && !bookkeepingTaskDef.hasDeletedField("EnvMode") {
+			bookkeepingTaskDef.TaskDefinition.EnvMode = *c.EnvMode
+			bookkeepingTaskDef.defaultEnvMode = c.EnvMode
+		}
+		c.Pipeline[taskID] = bookkeepingTaskDef
+	}
+
+	// Keep track of which remoteCache keys were set, so that they can be merged by presence
+	rawRemoteCache := struct {
+		RemoteCache map[string]json.RawMessage `json:"remoteCache"`
+	}{}
+	if err := json.Unmarshal(data, &rawRemoteCache); err != nil {
+		return err
+	}
+	c.remoteCacheFields = make(util.Set)
+	for key := range rawRemoteCache.RemoteCache {
+		c.remoteCacheFields.Add(key)
+	}
 
 	return nil
 }
@@ -595,10 +2893,143 @@ func (c *TurboJSON) UnmarshalJSON(data []byte) error {
 // note: we go via rawTurboJSON so that the output format is correct
 func (c *TurboJSON) MarshalJSON() ([]byte, error) {
 	raw := pristineTurboJSON{}
-	raw.GlobalDependencies = c.GlobalDeps
-	raw.GlobalEnv = c.GlobalEnv
-	raw.Pipeline = c.Pipeline.Pristine()
+	raw.Schema = c.Schema
+	raw.GlobalDependencies = sortedStrings(c.GlobalDeps)
+	raw.GlobalEnv = escapeEnvDeclarations(sortedStrings(c.GlobalEnv))
+	if len(c.GlobalPassThroughEnv) > 0 {
+		raw.GlobalPassThroughEnv = append([]string{}, c.GlobalPassThroughEnv...)
+		sort.Strings(raw.GlobalPassThroughEnv)
+	}
+	if len(c.GlobalDotEnv) > 0 {
+		raw.GlobalDotEnv = append([]string{}, c.GlobalDotEnv...)
+	}
+	raw.Pipeline = make(map[string]rawTaskWithDefaults, len(c.Pipeline))
+	for taskID, bookkeepingTaskDef := range c.Pipeline {
+		task := bookkeepingTaskDef.TaskDefinition.withDefaults()
+		// Loose tasks have to say so when there is a global envMode, otherwise they
+		// would inherit it when the config is read back
+		if c.EnvMode != nil {
+			envMode := bookkeepingTaskDef.TaskDefinition.EnvMode
+			task.EnvMode = &envMode
+		}
+		raw.Pipeline[taskID] = task
+	}
 	raw.RemoteCacheOptions = c.RemoteCacheOptions
+	raw.OutputMode = c.OutputMode
+	raw.EnvMode = c.EnvMode
+	if len(c.ExcludeScripts) > 0 {
+		raw.ExcludeScripts = sortedStrings(c.ExcludeScripts)
+	}
+	raw.Experimental = c.Experimental
+	if len(c.Overrides) > 0 {
+		raw.Overrides = map[string]definedPipeline{}
+		for condition, pipeline := range c.Overrides {
+			raw.Overrides[condition] = definedPipeline{}
+			for taskID, bookkeepingTaskDef := range pipeline {
+				values, err := bookkeepingTaskDef.definedValues()
+				if err != nil {
+					return nil, err
+				}
+				raw.Overrides[condition][taskID] = values
+			}
+		}
+	}
+	raw.Extends = c.Extends
 
 	return json.Marshal(&raw)
 }
+
+// MarshalMinimal is MarshalJSON without the keys that were not set in configFile, e.g.
+// for writing back a hand-written configFile after editing it in code. The tasks only
+// have the keys that they set (see BookkeepingTaskDefinition.MarshalMinimal), and an
+// empty "remoteCache" is omitted.
+func (c *TurboJSON) MarshalMinimal() ([]byte, error) {
+	data, err := c.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	if c.RemoteCacheOptions == (RemoteCacheOptions{}) {
+		delete(raw, "remoteCache")
+	}
+	pipeline := map[string]json.RawMessage{}
+	for taskID, bookkeepingTaskDef := range c.Pipeline {
+		task, err := bookkeepingTaskDef.MarshalMinimal()
+		if err != nil {
+			return nil, err
+		}
+		pipeline[taskID] = task
+	}
+	pipelineData, err := json.Marshal(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	raw["pipeline"] = pipelineData
+
+	return json.Marshal(raw)
+}
+
+// MarshalMinimal serializes only the keys of the task that were set in configFile,
+// including empty lists and keys that were set to null. Unlike MarshalJSON, this
+// keeps "..." in "dependsOn" and "inputs".
+func (btd BookkeepingTaskDefinition) MarshalMinimal() ([]byte, error) {
+	values, err := btd.definedValues()
+	if err != nil {
+		return nil, err
+	}
+	if btd.inheritsDependsOn {
+		if err := prependEntry(values, "dependsOn", inheritedDependsOn); err != nil {
+			return nil, err
+		}
+	}
+	if btd.inheritsInputs {
+		if err := prependEntry(values, "inputs", inheritedInputs); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(values)
+}
+
+// prependEntry adds entry to the start of the list that is the value of key in values
+func prependEntry(values map[string]json.RawMessage, key string, entry string) error {
+	var entries []string
+	if value, ok := values[key]; ok {
+		if err := json.Unmarshal(value, &entries); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(append([]string{entry}, entries...))
+	if err != nil {
+		return err
+	}
+	values[key] = data
+	return nil
+}
+
+// MarshalIndentCanonical serializes the TurboJSON with 2-space indentation and a
+// trailing newline, in a fixed order so that the output is reproducible:
+//   - top-level keys are in the order of the fields of pristineTurboJSON, starting with "$schema"
+//   - task keys are in the order of the fields of rawTaskWithDefaults
+//   - pipeline tasks are sorted alphabetically, as are the entries of every array
+func (c *TurboJSON) MarshalIndentCanonical() ([]byte, error) {
+	// encoding/json emits struct fields in declaration order and sorts map keys
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteToFile serializes the TurboJSON and writes it to path. The contents are written
+// to a temporary file in the same directory and renamed into place to avoid partial writes.
+func (c *TurboJSON) WriteToFile(path turbopath.AbsoluteSystemPath) error {
+	data, err := c.MarshalIndentCanonical()
+	if err != nil {
+		return err
+	}
+	return writeFileFromStream(bytes.NewReader(data), path.ToString(), 0)
+}
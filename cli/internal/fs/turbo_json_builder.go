@@ -0,0 +1,161 @@
+package fs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+// TaskDefinitionBuilder builds a BookkeepingTaskDefinition in code, e.g. for generating
+// configFile. Each method sets a key the same way it would be set in configFile, so the
+// result keeps track of which fields were defined and merges like a parsed task.
+// Calling a method again replaces the value it set.
+type TaskDefinitionBuilder struct {
+	task        rawTask
+	description string
+}
+
+// NewTaskDefinition starts building a task with no keys set
+func NewTaskDefinition() *TaskDefinitionBuilder {
+	return &TaskDefinitionBuilder{}
+}
+
+// WithOutputs sets "outputs"
+func (b *TaskDefinitionBuilder) WithOutputs(globs ...string) *TaskDefinitionBuilder {
+	b.task.Outputs = append([]string{}, globs...)
+	return b
+}
+
+// WithCache sets "cache", which toggles local and remote caching together
+func (b *TaskDefinitionBuilder) WithCache(enabled bool) *TaskDefinitionBuilder {
+	b.task.Cache = &rawCacheConfig{Local: &enabled, Remote: &enabled}
+	return b
+}
+
+// WithCacheConfig sets "cache", toggling local and remote caching separately
+func (b *TaskDefinitionBuilder) WithCacheConfig(cache CacheConfig) *TaskDefinitionBuilder {
+	b.task.Cache = &rawCacheConfig{Local: &cache.Local, Remote: &cache.Remote}
+	return b
+}
+
+// WithCacheDisabledReason sets "cacheDisabledReason"
+func (b *TaskDefinitionBuilder) WithCacheDisabledReason(reason string) *TaskDefinitionBuilder {
+	b.task.CacheDisabledReason = &reason
+	return b
+}
+
+// WithCacheKey sets "cacheKey"
+func (b *TaskDefinitionBuilder) WithCacheKey(cacheKey string) *TaskDefinitionBuilder {
+	b.task.CacheKey = &cacheKey
+	return b
+}
+
+// WithDependsOn sets "dependsOn". Dependencies are written as in configFile,
+// e.g. "^build" for a topological dependency.
+func (b *TaskDefinitionBuilder) WithDependsOn(dependencies ...string) *TaskDefinitionBuilder {
+	b.task.DependsOn = append([]string{}, dependencies...)
+	return b
+}
+
+// WithInputs sets "inputs"
+func (b *TaskDefinitionBuilder) WithInputs(globs ...string) *TaskDefinitionBuilder {
+	b.task.Inputs = append([]string{}, globs...)
+	return b
+}
+
+// WithOutputMode sets "outputMode"
+func (b *TaskDefinitionBuilder) WithOutputMode(outputMode util.TaskOutputMode) *TaskDefinitionBuilder {
+	b.task.OutputMode = &outputMode
+	return b
+}
+
+// WithOutputLogs sets "outputLogs"
+func (b *TaskDefinitionBuilder) WithOutputLogs(outputLogs util.TaskOutputLogs) *TaskDefinitionBuilder {
+	b.task.OutputLogs = &outputLogs
+	return b
+}
+
+// WithEnvMode sets "envMode"
+func (b *TaskDefinitionBuilder) WithEnvMode(envMode util.EnvMode) *TaskDefinitionBuilder {
+	b.task.EnvMode = &envMode
+	return b
+}
+
+// WithEnv sets "env"
+func (b *TaskDefinitionBuilder) WithEnv(envVars ...string) *TaskDefinitionBuilder {
+	b.task.Env = append([]string{}, envVars...)
+	return b
+}
+
+// WithPassThroughEnv sets "passThroughEnv"
+func (b *TaskDefinitionBuilder) WithPassThroughEnv(envVars ...string) *TaskDefinitionBuilder {
+	b.task.PassThroughEnv = append([]string{}, envVars...)
+	return b
+}
+
+// WithPersistent sets "persistent"
+func (b *TaskDefinitionBuilder) WithPersistent(persistent bool) *TaskDefinitionBuilder {
+	b.task.Persistent = &persistent
+	return b
+}
+
+// WithInteractive sets "interactive"
+func (b *TaskDefinitionBuilder) WithInteractive(interactive bool) *TaskDefinitionBuilder {
+	b.task.Interactive = &interactive
+	return b
+}
+
+// WithSiblingTasks sets "with"
+func (b *TaskDefinitionBuilder) WithSiblingTasks(tasks ...string) *TaskDefinitionBuilder {
+	b.task.With = append([]string{}, tasks...)
+	return b
+}
+
+// WithDotEnv sets "dotEnv"
+func (b *TaskDefinitionBuilder) WithDotEnv(paths ...string) *TaskDefinitionBuilder {
+	b.task.DotEnv = append([]string{}, paths...)
+	return b
+}
+
+// WithTimeout sets "timeout"
+func (b *TaskDefinitionBuilder) WithTimeout(timeout time.Duration) *TaskDefinitionBuilder {
+	value := timeout.String()
+	b.task.Timeout = &value
+	return b
+}
+
+// WithWeight sets "weight"
+func (b *TaskDefinitionBuilder) WithWeight(weight int) *TaskDefinitionBuilder {
+	b.task.Weight = &weight
+	return b
+}
+
+// WithRetries sets "retries"
+func (b *TaskDefinitionBuilder) WithRetries(retries int) *TaskDefinitionBuilder {
+	b.task.Retries = &retries
+	return b
+}
+
+// WithDescription sets the comment that is written before the task in configFile
+func (b *TaskDefinitionBuilder) WithDescription(description string) *TaskDefinitionBuilder {
+	b.description = description
+	return b
+}
+
+// Build returns the task definition, or an error if a value is invalid
+// (e.g. a negative weight), in the same form as when parsing configFile
+func (b *TaskDefinitionBuilder) Build() (BookkeepingTaskDefinition, error) {
+	// Round trip through the configFile representation, so that values are
+	// parsed and validated exactly as they would be when reading configFile
+	data, err := json.Marshal(b.task)
+	if err != nil {
+		return BookkeepingTaskDefinition{}, err
+	}
+	btd := BookkeepingTaskDefinition{}
+	if err := json.Unmarshal(data, &btd); err != nil {
+		return BookkeepingTaskDefinition{}, err
+	}
+	btd.Description = b.description
+	return btd, nil
+}
@@ -0,0 +1,88 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+func Test_NewTaskDefinition(t *testing.T) {
+	testCases := []struct {
+		name          string
+		builder       *TaskDefinitionBuilder
+		definedFields []string
+	}{
+		{
+			name:          "no keys",
+			builder:       NewTaskDefinition(),
+			definedFields: []string{},
+		},
+		{
+			name:          "outputs and cache",
+			builder:       NewTaskDefinition().WithOutputs("dist/**", "!dist/cache/**").WithCache(false),
+			definedFields: []string{"Outputs", "ShouldCache"},
+		},
+		{
+			name:          "dependsOn",
+			builder:       NewTaskDefinition().WithDependsOn("^build", "codegen", "//lint"),
+			definedFields: []string{"TopologicalDependencies", "TaskDependencies", "RootTaskDependencies"},
+		},
+		{
+			name: "everything else",
+			builder: NewTaskDefinition().
+				WithInputs("src/**").
+				WithEnv("NODE_ENV").
+				WithPassThroughEnv("AWS_SECRET").
+				WithOutputMode(util.HashTaskOutput).
+				WithPersistent(true).
+				WithTimeout(5 * time.Minute).
+				WithWeight(2).
+				WithRetries(1),
+			definedFields: []string{"Inputs", "EnvVarDependencies", "PassThroughEnv", "OutputMode", "Persistent", "Timeout", "Weight", "Retries"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			btd, err := tc.builder.Build()
+			assert.NoError(t, err)
+			assert.Equal(t, util.SetFromStrings(tc.definedFields), btd.definedFields)
+		})
+	}
+}
+
+func Test_NewTaskDefinition_Values(t *testing.T) {
+	btd, err := NewTaskDefinition().
+		WithOutputs("dist/**", "!dist/cache/**").
+		WithCache(false).
+		WithDependsOn("^build", "codegen").
+		WithTimeout(90 * time.Second).
+		WithDescription("Builds the app").
+		Build()
+	assert.NoError(t, err)
+
+	// Built tasks match the same task parsed from configFile
+	parsed := parseTurboJSON(t, `{"pipeline": {"build": {
+		"outputs": ["dist/**", "!dist/cache/**"],
+		"cache": false,
+		"dependsOn": ["^build", "codegen"],
+		"timeout": "90s"
+	}}}`).Pipeline["build"]
+	assert.Equal(t, parsed.TaskDefinition, btd.TaskDefinition)
+	assert.Equal(t, parsed.definedFields, btd.definedFields)
+	assert.Equal(t, "Builds the app", btd.Description)
+
+	// Unset keys are inherited when merging, set keys override
+	base := parseTurboJSON(t, `{"pipeline": {"build": {"outputs": [".next/**"], "env": ["NODE_ENV"]}}}`).Pipeline["build"]
+	override, err := NewTaskDefinition().WithOutputs("dist/**").Build()
+	assert.NoError(t, err)
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, override})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dist/**"}, merged.Outputs.Inclusions)
+	assert.Equal(t, []string{"NODE_ENV"}, merged.EnvVarDependencies)
+
+	_, err = NewTaskDefinition().WithWeight(0).Build()
+	assert.EqualError(t, err, `invalid "weight" value 0: must be a positive integer`)
+}
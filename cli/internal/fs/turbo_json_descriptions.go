@@ -0,0 +1,86 @@
+package fs
+
+import (
+	"bytes"
+	"strings"
+)
+
+// taskDescriptions returns the comment immediately preceding each task key in the
+// pipeline of a JSONC document, keyed by task name. A comment that trails the
+// previous task on the same line belongs to that task, not the next one.
+func taskDescriptions(data []byte) map[string]string {
+	descriptions := map[string]string{}
+
+	root, err := parseJSONCPositions(data)
+	if err != nil || !root.isObject(data) {
+		return descriptions
+	}
+	pipeline := root.find([]string{"pipeline"})
+	if pipeline == nil || !pipeline.isObject(data) {
+		return descriptions
+	}
+
+	gapStart := pipeline.start + 1
+	for _, member := range pipeline.members {
+		if description := precedingComment(data, gapStart, member.keyStart); description != "" {
+			descriptions[member.key] = description
+		}
+		gapStart = member.value.end
+	}
+
+	return descriptions
+}
+
+// precedingComment returns the text of the comments directly before end. Everything
+// between start and end is whitespace, a comma, or a comment.
+func precedingComment(data []byte, start int, end int) string {
+	lines := []string{}
+	pos := start
+	for pos < end {
+		switch {
+		case bytes.HasPrefix(data[pos:end], []byte("//")) || bytes.HasPrefix(data[pos:end], []byte("/*")):
+			commentEnd := commentEnd(data[:end], pos)
+			// Skip comments that are on the same line as the previous task
+			if bytes.ContainsRune(data[start:pos], '\n') {
+				lines = append(lines, commentLines(string(data[pos:commentEnd]))...)
+			}
+			pos = commentEnd
+		case strings.ContainsRune(" \t\r\n", rune(data[pos])):
+			pos++
+		default:
+			lines = []string{}
+			pos++
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// commentEnd returns the offset just past the comment that starts at pos
+func commentEnd(data []byte, pos int) int {
+	if bytes.HasPrefix(data[pos:], []byte("//")) {
+		if end := bytes.IndexByte(data[pos:], '\n'); end != -1 {
+			return pos + end
+		}
+		return len(data)
+	}
+	if end := bytes.Index(data[pos+2:], []byte("*/")); end != -1 {
+		return pos + 2 + end + 2
+	}
+	return len(data)
+}
+
+// commentLines returns the text of a comment without its delimiters, line by line
+func commentLines(comment string) []string {
+	if strings.HasPrefix(comment, "//") {
+		return []string{strings.TrimSpace(strings.TrimPrefix(comment, "//"))}
+	}
+
+	lines := []string{}
+	body := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,108 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TaskDescriptions(t *testing.T) {
+	data := []byte(`{
+  // Not a task
+  "globalEnv": ["CI"],
+  "pipeline": {
+    // Build everything
+    // for production
+    "build": {
+      // Not a task either
+      "outputs": ["dist/**"]
+    }, // trails build
+    "lint": {},
+    /*
+     * Runs the tests
+     */
+    "test": {}, "web#dev": {}
+  }
+}`)
+
+	assert.Equal(t, map[string]string{
+		"build": "Build everything\nfor production",
+		"test":  "Runs the tests",
+	}, taskDescriptions(data))
+}
+
+func Test_ReadTurboConfig_Descriptions(t *testing.T) {
+	testDir := getTestDir(t, "commented")
+	turboJSON, err := readTurboConfig(testDir.UntypedJoin(configFile), ParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Build everything", turboJSON.Pipeline["build"].Description)
+	assert.Equal(t, "", turboJSON.Pipeline["lint"].Description)
+}
+
+func Test_DescriptionKey(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {"description": "Builds the app", "outputs": ["dist/**"]},
+		"lint": {}
+	}}`)
+	build := turboJSON.Pipeline["build"]
+	assert.Equal(t, "Builds the app", build.TaskDefinition.Description)
+	assert.True(t, build.hasField("Description"))
+	assert.False(t, turboJSON.Pipeline["lint"].hasField("Description"))
+
+	serialized, err := json.Marshal(build.TaskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, "Builds the app", roundTripped.TaskDefinition.Description)
+	assert.True(t, build.TaskDefinition.Equal(roundTripped.TaskDefinition))
+
+	// Only the keys that change how a task runs affect its hash
+	undescribed := build.TaskDefinition.clone()
+	undescribed.Description = ""
+	undescribed.ExplicitOutputs = false
+	describedHash, err := build.TaskDefinition.Hash()
+	assert.NoError(t, err)
+	undescribedHash, err := undescribed.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, undescribedHash, describedHash)
+	assert.Equal(t, undescribed, turboJSON.Pipeline.HashablePristine()["build"])
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{build, turboJSON.Pipeline["lint"]})
+	assert.NoError(t, err)
+	assert.Equal(t, "Builds the app", merged.Description)
+}
+
+func Test_DescriptionKeyAndComment(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	turboJSON, err := ReadTurboConfigFromReader(strings.NewReader(`{
+  "pipeline": {
+    // Runs the tests
+    "test": {},
+    "lint": {"description": "Checks the code style"}
+  }
+}`))
+	assert.NoError(t, err)
+	assert.Empty(t, logs.String())
+	assert.Equal(t, "Runs the tests", turboJSON.Pipeline["test"].Description)
+	assert.Equal(t, "Checks the code style", turboJSON.Pipeline["lint"].TaskDefinition.Description)
+
+	turboJSON, err = ReadTurboConfigFromReader(strings.NewReader(`{
+  "pipeline": {
+    // Builds everything
+    "build": {"description": "Builds the app"}
+  }
+}`))
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), `[WARNING] Task "build" is described by both a comment and "description", remove one of them`)
+	assert.EqualValues(t, []string{`Task "build" is described by both a comment and "description", remove one of them`}, turboJSON.Warnings())
+	assert.Equal(t, "Builds everything", turboJSON.Pipeline["build"].Description)
+	assert.Equal(t, "Builds the app", turboJSON.Pipeline["build"].TaskDefinition.Description)
+}
@@ -0,0 +1,164 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+// unorderedKeys are the keys of configFile, at the top level or in a task, whose
+// values are lists where the order doesn't matter. Diff reports the entries that
+// were added and removed rather than the whole list.
+var unorderedKeys = util.SetFromStrings([]string{
+	"globalDependencies",
+	"globalEnv",
+	"globalPassThroughEnv",
+	"excludeScripts",
+	"outputs",
+	"dependsOn",
+	"inputs",
+	"env",
+	"passThroughEnv",
+	"with",
+})
+
+// Diff returns human-readable descriptions of the changes from before to after, e.g.
+// for reviewing changes to configFile. Tasks are compared by the keys they set, and
+// reordering lists where the order doesn't matter is not a change. Top-level changes
+// come first, followed by the changes to each task, sorted by task ID and key.
+func Diff(before *TurboJSON, after *TurboJSON) ([]string, error) {
+	oldGlobals, err := globalValues(before)
+	if err != nil {
+		return nil, err
+	}
+	newGlobals, err := globalValues(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := diffValues("", oldGlobals, newGlobals)
+
+	taskIDs := make(util.Set)
+	for taskID := range before.Pipeline {
+		taskIDs.Add(taskID)
+	}
+	for taskID := range after.Pipeline {
+		taskIDs.Add(taskID)
+	}
+	sortedTaskIDs := taskIDs.UnsafeListOfStrings()
+	sort.Strings(sortedTaskIDs)
+
+	for _, taskID := range sortedTaskIDs {
+		oldTask, inOld := before.Pipeline[taskID]
+		newTask, inNew := after.Pipeline[taskID]
+		if !inOld {
+			changes = append(changes, fmt.Sprintf("added task \"%s\"", taskID))
+			continue
+		}
+		if !inNew {
+			changes = append(changes, fmt.Sprintf("removed task \"%s\"", taskID))
+			continue
+		}
+
+		oldValues, err := oldTask.definedValues()
+		if err != nil {
+			return nil, err
+		}
+		newValues, err := newTask.definedValues()
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, diffValues(fmt.Sprintf("task \"%s\": ", taskID), oldValues, newValues)...)
+	}
+
+	return changes, nil
+}
+
+// globalValues returns the top-level keys of tj as they are marshaled, without the
+// pipeline. The keys of "remoteCache" and "experimental" are flattened (e.g.
+// "remoteCache.teamId"), so that they are compared individually. "overrides" is
+// compared as a whole.
+func globalValues(tj *TurboJSON) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(tj)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	delete(values, "pipeline")
+
+	for _, key := range []string{"remoteCache", "experimental"} {
+		nested := map[string]json.RawMessage{}
+		if value, ok := values[key]; ok {
+			if err := json.Unmarshal(value, &nested); err != nil {
+				return nil, err
+			}
+		}
+		delete(values, key)
+		for nestedKey, value := range nested {
+			values[key+"."+nestedKey] = value
+		}
+	}
+	return values, nil
+}
+
+// diffValues describes the differences between the keys of before and after, each
+// prefixed with prefix
+func diffValues(prefix string, before map[string]json.RawMessage, after map[string]json.RawMessage) []string {
+	keys := make(util.Set)
+	for key := range before {
+		keys.Add(key)
+	}
+	for key := range after {
+		keys.Add(key)
+	}
+	sortedKeys := keys.UnsafeListOfStrings()
+	sort.Strings(sortedKeys)
+
+	changes := []string{}
+	for _, key := range sortedKeys {
+		oldValue, inOld := before[key]
+		newValue, inNew := after[key]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("%sset \"%s\" to %s", prefix, key, newValue))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("%sunset \"%s\" (was %s)", prefix, key, oldValue))
+		case unorderedKeys.Includes(key):
+			changes = append(changes, diffUnordered(prefix, key, oldValue, newValue)...)
+		case !bytes.Equal(oldValue, newValue):
+			changes = append(changes, fmt.Sprintf("%schanged \"%s\" from %s to %s", prefix, key, oldValue, newValue))
+		}
+	}
+	return changes
+}
+
+// diffUnordered describes the entries that were added to and removed from the list key
+func diffUnordered(prefix string, key string, oldValue json.RawMessage, newValue json.RawMessage) []string {
+	var oldEntries, newEntries []string
+	if err := json.Unmarshal(oldValue, &oldEntries); err != nil {
+		return []string{fmt.Sprintf("%schanged \"%s\" from %s to %s", prefix, key, oldValue, newValue)}
+	}
+	if err := json.Unmarshal(newValue, &newEntries); err != nil {
+		return []string{fmt.Sprintf("%schanged \"%s\" from %s to %s", prefix, key, oldValue, newValue)}
+	}
+	oldSet := util.SetFromStrings(oldEntries)
+	newSet := util.SetFromStrings(newEntries)
+
+	changes := []string{}
+	for _, entry := range normalizedStrings(newEntries) {
+		if !oldSet.Includes(entry) {
+			changes = append(changes, fmt.Sprintf("%sadded \"%s\" to \"%s\"", prefix, entry, key))
+		}
+	}
+	for _, entry := range normalizedStrings(oldEntries) {
+		if !newSet.Includes(entry) {
+			changes = append(changes, fmt.Sprintf("%sremoved \"%s\" from \"%s\"", prefix, entry, key))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,59 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Diff(t *testing.T) {
+	before := parseTurboJSON(t, `{
+		"globalEnv": ["CI", "API_URL"],
+		"remoteCache": {"teamId": "team_abc"},
+		"pipeline": {
+			"build": {"outputs": ["dist/**", ".next/**"], "env": ["NODE_ENV"]},
+			"test": {"outputMode": "full"},
+			"dev": {"cache": false}
+		}
+	}`)
+	after := parseTurboJSON(t, `{
+		"globalEnv": ["CI"],
+		"remoteCache": {"teamId": "team_xyz"},
+		"pipeline": {
+			"build": {"outputs": [".next/**", "dist/**"], "env": ["NODE_ENV", "APP_*"]},
+			"test": {"outputMode": "errors-only"},
+			"dev": {"cache": false, "persistent": true},
+			"lint": {}
+		}
+	}`)
+
+	changes, err := Diff(before, after)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		`removed "API_URL" from "globalEnv"`,
+		`changed "remoteCache.teamId" from "team_abc" to "team_xyz"`,
+		`task "build": added "APP_*" to "env"`,
+		`task "dev": set "persistent" to true`,
+		`added task "lint"`,
+		`task "test": changed "outputMode" from "full" to "errors-only"`,
+	}, changes)
+
+	changes, err = Diff(after, before)
+	assert.NoError(t, err)
+	assert.Contains(t, changes, `removed task "lint"`)
+	assert.Contains(t, changes, `added "API_URL" to "globalEnv"`)
+	assert.Contains(t, changes, `task "dev": unset "persistent" (was true)`)
+
+	// Reordering is not a change
+	changes, err = Diff(before, parseTurboJSON(t, `{
+		"globalEnv": ["API_URL", "CI"],
+		"remoteCache": {"teamId": "team_abc"},
+		"pipeline": {
+			"dev": {"cache": false},
+			"test": {"outputMode": "full"},
+			"build": {"env": ["NODE_ENV"], "outputs": [".next/**", "dist/**"]}
+		}
+	}`))
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,67 @@
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// experimentalUI is the "experimental" key that opts in to the new terminal UI
+const experimentalUI = "ui"
+
+// experimentalImplicitInputsWarning is the "experimental" key that opts out of the
+// warning from ValidateCachedTasksHaveInputs when set to false
+const experimentalImplicitInputsWarning = "implicitInputsWarning"
+
+// knownExperimentalOptions maps the keys of "experimental" that turbo understands to a
+// zero value of their type, which is used to check the values in configFile
+var knownExperimentalOptions = map[string]func() interface{}{
+	experimentalUI:                    func() interface{} { return new(bool) },
+	experimentalImplicitInputsWarning: func() interface{} { return new(bool) },
+}
+
+// validateExperimentalOptions checks that the known options in "experimental" have the
+// right type. Unknown options are ignored with a warning, since they are likely meant
+// for a different version of turbo.
+func validateExperimentalOptions(options map[string]json.RawMessage) ([]string, error) {
+	var warnings []string
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		newValue, ok := knownExperimentalOptions[key]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("Unknown experimental option \"%s\" will be ignored", key))
+			continue
+		}
+		if err := json.Unmarshal(options[key], newValue()); err != nil {
+			return nil, fmt.Errorf("invalid \"experimental.%s\" value %s: %w", key, options[key], err)
+		}
+	}
+	return warnings, nil
+}
+
+// experimentalBool returns the value of the boolean option key of "experimental", or
+// defaultValue if it isn't set
+func (tj *TurboJSON) experimentalBool(key string, defaultValue bool) bool {
+	enabled := defaultValue
+	if value, ok := tj.Experimental[key]; ok {
+		// The value was checked when parsing configFile
+		_ = json.Unmarshal(value, &enabled)
+	}
+	return enabled
+}
+
+// ExperimentalUI returns true if "experimental.ui" is enabled
+func (tj *TurboJSON) ExperimentalUI() bool {
+	return tj.experimentalBool(experimentalUI, false)
+}
+
+// ImplicitInputsWarning returns false if the repository opted out of the warning about
+// cached tasks without "inputs" with "experimental.implicitInputsWarning"
+func (tj *TurboJSON) ImplicitInputsWarning() bool {
+	return tj.experimentalBool(experimentalImplicitInputsWarning, true)
+}
@@ -0,0 +1,65 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turbo/cli/internal/turbopath"
+)
+
+func Test_ExperimentalOptions(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"experimental": {"ui": true}, "pipeline": {}}`)
+	assert.True(t, turboJSON.ExperimentalUI())
+	assert.Empty(t, turboJSON.Warnings())
+
+	turboJSON = parseTurboJSON(t, `{"pipeline": {}}`)
+	assert.False(t, turboJSON.ExperimentalUI())
+
+	// Unknown options are kept, but only warned about
+	turboJSON = parseTurboJSON(t, `{"experimental": {"teleport": {"enabled": true}}, "pipeline": {}}`)
+	assert.False(t, turboJSON.ExperimentalUI())
+	assert.Equal(t, `{"enabled": true}`, string(turboJSON.Experimental["teleport"]))
+	assert.EqualValues(t, []string{`Unknown experimental option "teleport" will be ignored`}, turboJSON.Warnings())
+}
+
+func Test_ExperimentalOptions_InvalidValue(t *testing.T) {
+	var turboJSON TurboJSON
+	err := json.Unmarshal([]byte(`{"experimental": {"ui": "yes"}, "pipeline": {}}`), &turboJSON)
+	assert.ErrorContains(t, err, `invalid "experimental.ui" value "yes"`)
+}
+
+func Test_ExperimentalOptions_DisallowUnknownFields(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	turboJSONPath := turbopath.AbsoluteSystemPath(t.TempDir()).UntypedJoin(configFile)
+	assert.NoError(t, turboJSONPath.WriteFile([]byte(`{"experimental": {"ui": true, "teleport": true}, "pipeline": {}}`), 0644))
+
+	turboJSON, err := readTurboConfig(turboJSONPath, ParseOptions{DisallowUnknownFields: true})
+	assert.NoError(t, err)
+	assert.True(t, turboJSON.ExperimentalUI())
+}
+
+func Test_ExperimentalOptions_RoundTripAndMerge(t *testing.T) {
+	base := parseTurboJSON(t, `{"experimental": {"ui": true}, "pipeline": {}}`)
+	override := parseTurboJSON(t, `{"experimental": {"ui": false}, "pipeline": {}}`)
+	unrelated := parseTurboJSON(t, `{"pipeline": {}}`)
+
+	serialized, err := json.Marshal(base)
+	assert.NoError(t, err)
+	roundTripped := parseTurboJSON(t, string(serialized))
+	assert.True(t, roundTripped.ExperimentalUI())
+
+	merged, err := MergeTurboJSON([]*TurboJSON{base, unrelated})
+	assert.NoError(t, err)
+	assert.True(t, merged.ExperimentalUI())
+
+	merged, err = MergeTurboJSON([]*TurboJSON{base, override})
+	assert.NoError(t, err)
+	assert.False(t, merged.ExperimentalUI())
+}
@@ -0,0 +1,263 @@
+package fs
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+// FuzzTurboJSONRoundTrip checks that parsing a valid turbo.json, marshaling it and
+// parsing it again results in the same configuration.
+func FuzzTurboJSONRoundTrip(f *testing.F) {
+	for seed := int64(0); seed < 32; seed++ {
+		f.Add(seed)
+	}
+
+	// Generated configs can legitimately trigger warnings, e.g. for interactive tasks
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	f.Fuzz(func(t *testing.T, seed int64) {
+		data, err := json.Marshal(randomTurboJSON(rand.New(rand.NewSource(seed))))
+		if err != nil {
+			t.Fatalf("failed to marshal generated config: %v", err)
+		}
+
+		var parsed TurboJSON
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			t.Fatalf("failed to parse generated config %s: %v", data, err)
+		}
+		marshaled, err := json.Marshal(&parsed)
+		if err != nil {
+			t.Fatalf("failed to marshal %s: %v", data, err)
+		}
+		var reparsed TurboJSON
+		if err := json.Unmarshal(marshaled, &reparsed); err != nil {
+			t.Fatalf("failed to parse marshaled config %s: %v", marshaled, err)
+		}
+
+		want := normalizeTurboJSON(&parsed)
+		got := normalizeTurboJSON(&reparsed)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("round trip of %s changed the config\nmarshaled: %s\nwant: %+v\ngot:  %+v", data, marshaled, want, got)
+		}
+	})
+}
+
+// generatedTurboJSON is a rawTurboJSON with tasks that can be marshaled as they
+// would be written in configFile
+type generatedTurboJSON struct {
+	rawTurboJSON
+	Pipeline map[string]rawTask `json:"pipeline"`
+}
+
+var (
+	generatedTaskNames      = []string{"build", "test", "lint", "dev", "web#build", "docs#test", "//#format"}
+	generatedDependsOn      = []string{"build", "^build", "^codegen", "lint", "web#build", "//#format", "//typecheck", "test:*"}
+	generatedEnv            = []string{"NODE_ENV", "CI", "MY_APP_*", "!MY_APP_SECRET", "LITERAL\\*", "\\$DOLLAR_VAR"}
+	generatedPassThrough    = []string{"HOME", "AWS_SECRET_KEY", "SSH_AUTH_SOCK"}
+	generatedOutputs        = []string{"dist/**", ".next/**", "!dist/cache/**", "!.next/cache/**", "coverage/**", "$TURBO_ROOT$/coverage/**", "!$TURBO_ROOT$/coverage/tmp/**"}
+	generatedOutputsExclude = []string{"dist/cache/**", "coverage/tmp/**", "$TURBO_ROOT$/coverage/tmp/**"}
+	generatedInputs         = []string{"src/**", "test/**", "package.json", turboDefaultInputs}
+	generatedDotEnv         = []string{".env", ".env.local", ".env.production"}
+	generatedGlobalDeps     = []string{"tsconfig.json", ".eslintrc.js", "$LEGACY_VAR"}
+	generatedTimeouts       = []string{"30s", "5m", "1h30m"}
+)
+
+// randomTurboJSON generates a valid configFile document
+func randomTurboJSON(r *rand.Rand) generatedTurboJSON {
+	doc := generatedTurboJSON{Pipeline: map[string]rawTask{}}
+	if r.Intn(2) == 0 {
+		doc.Schema = "https://turbo.build/schema.json"
+	}
+	doc.GlobalDependencies = randomSubset(r, generatedGlobalDeps)
+	doc.GlobalEnv = randomSubset(r, generatedEnv)
+	doc.GlobalPassThroughEnv = randomSubset(r, generatedPassThrough)
+	doc.GlobalDotEnv = randomSubset(r, generatedDotEnv)
+	doc.ExcludeScripts = randomSubset(r, []string{"postinstall", "prepare"})
+	if r.Intn(2) == 0 {
+		doc.RemoteCacheOptions = RemoteCacheOptions{
+			TeamID:    "team_" + randomElement(r, []string{"abc", "xyz"}),
+			Signature: r.Intn(2) == 0,
+			APIURL:    randomElement(r, []string{"", "https://cache.example.com"}),
+			Enabled:   randomBool(r),
+		}
+	}
+	if r.Intn(3) == 0 {
+		outputMode, _ := util.FromTaskOutputModeString(randomElement(r, util.TaskOutputModeStrings))
+		doc.OutputMode = &outputMode
+	}
+	if r.Intn(3) == 0 {
+		envMode, _ := util.FromEnvModeString(randomElement(r, util.EnvModeStrings))
+		doc.EnvMode = &envMode
+	}
+
+	for _, taskName := range randomSubset(r, generatedTaskNames) {
+		doc.Pipeline[taskName] = randomTask(r)
+	}
+	return doc
+}
+
+func randomTask(r *rand.Rand) rawTask {
+	task := rawTask{
+		Outputs:        randomSubset(r, generatedOutputs),
+		OutputsExclude: randomSubset(r, generatedOutputsExclude),
+		DependsOn:      randomSubset(r, generatedDependsOn),
+		Inputs:         randomSubset(r, generatedInputs),
+		Env:            randomSubset(r, generatedEnv),
+		PassThroughEnv: randomSubset(r, generatedPassThrough),
+		With:           randomSubset(r, []string{"mock-server", "db"}),
+		DotEnv:         randomSubset(r, generatedDotEnv),
+		Persistent:     randomBool(r),
+		Interactive:    randomBool(r),
+	}
+	switch r.Intn(3) {
+	case 0:
+		task.Cache = &rawCacheConfig{Local: randomBool(r), Remote: randomBool(r)}
+	case 1:
+		enabled := r.Intn(2) == 0
+		task.Cache = &rawCacheConfig{Local: &enabled, Remote: &enabled}
+	}
+	if r.Intn(3) == 0 {
+		reason := "it is fast enough"
+		task.CacheDisabledReason = &reason
+	}
+	if r.Intn(3) == 0 {
+		cacheKey := randomElement(r, []string{"v1", "v2", "node-20"})
+		task.CacheKey = &cacheKey
+	}
+	if r.Intn(3) == 0 {
+		outputMode, _ := util.FromTaskOutputModeString(randomElement(r, util.TaskOutputModeStrings))
+		task.OutputMode = &outputMode
+	}
+	if r.Intn(3) == 0 {
+		outputLogs, _ := util.FromTaskOutputLogsString(randomElement(r, util.TaskOutputLogsStrings))
+		task.OutputLogs = &outputLogs
+	}
+	if r.Intn(3) == 0 {
+		envMode, _ := util.FromEnvModeString(randomElement(r, util.EnvModeStrings))
+		task.EnvMode = &envMode
+	}
+	if r.Intn(3) == 0 {
+		timeout := randomElement(r, generatedTimeouts)
+		task.Timeout = &timeout
+	}
+	if r.Intn(3) == 0 {
+		weight := 1 + r.Intn(8)
+		task.Weight = &weight
+	}
+	if r.Intn(3) == 0 {
+		retries := r.Intn(4)
+		task.Retries = &retries
+	}
+	if r.Intn(3) == 0 {
+		description := randomElement(r, []string{"Builds the app", "Runs the \"unit\" tests"})
+		task.Description = &description
+	}
+	return task
+}
+
+// randomSubset returns nil, or a random selection of values in a random order
+func randomSubset(r *rand.Rand, values []string) []string {
+	if r.Intn(3) == 0 {
+		return nil
+	}
+	subset := []string{}
+	for _, i := range r.Perm(len(values)) {
+		if r.Intn(2) == 0 {
+			subset = append(subset, values[i])
+		}
+	}
+	return subset
+}
+
+func randomElement(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
+
+// randomBool returns nil, true or false
+func randomBool(r *rand.Rand) *bool {
+	if r.Intn(3) == 0 {
+		return nil
+	}
+	value := r.Intn(2) == 0
+	return &value
+}
+
+// normalizedTurboJSON is the part of a TurboJSON that is expected to survive a
+// round trip. The bookkeeping of which fields were set does not, since marshaling
+// writes out the default values of the fields that weren't.
+type normalizedTurboJSON struct {
+	Schema               string
+	GlobalDeps           []string
+	GlobalEnv            []string
+	GlobalPassThroughEnv []string
+	GlobalDotEnv         []string
+	Pipeline             map[string]TaskDefinition
+	RemoteCacheOptions   RemoteCacheOptions
+	OutputMode           *util.TaskOutputMode
+	EnvMode              *util.EnvMode
+	ExcludeScripts       []string
+	Extends              []string
+}
+
+// normalizeTurboJSON makes tj comparable with reflect.DeepEqual, ignoring the order of
+// unordered lists and the difference between nil and empty lists
+func normalizeTurboJSON(tj *TurboJSON) normalizedTurboJSON {
+	normalized := normalizedTurboJSON{
+		Schema:               tj.Schema,
+		GlobalDeps:           normalizedSet(tj.GlobalDeps),
+		GlobalEnv:            normalizedSet(tj.GlobalEnv),
+		GlobalPassThroughEnv: normalizedSet(tj.GlobalPassThroughEnv),
+		GlobalDotEnv:         normalizedList(tj.GlobalDotEnv),
+		Pipeline:             map[string]TaskDefinition{},
+		RemoteCacheOptions:   tj.RemoteCacheOptions,
+		OutputMode:           tj.OutputMode,
+		EnvMode:              tj.EnvMode,
+		ExcludeScripts:       normalizedSet(tj.ExcludeScripts),
+		Extends:              normalizedList(tj.Extends),
+	}
+
+	for taskName, bookkeepingTaskDef := range tj.Pipeline {
+		taskDefinition := bookkeepingTaskDef.TaskDefinition.clone()
+		taskDefinition.Normalize()
+		taskDefinition.Outputs.Inclusions = normalizedList(taskDefinition.Outputs.Inclusions)
+		taskDefinition.Outputs.Exclusions = normalizedList(taskDefinition.Outputs.Exclusions)
+		taskDefinition.Outputs.RootInclusions = normalizedList(taskDefinition.Outputs.RootInclusions)
+		taskDefinition.Outputs.RootExclusions = normalizedList(taskDefinition.Outputs.RootExclusions)
+		taskDefinition.EnvVarDependencies = normalizedList(taskDefinition.EnvVarDependencies)
+		taskDefinition.PassThroughEnv = normalizedList(taskDefinition.PassThroughEnv)
+		taskDefinition.TopologicalDependencies = normalizedList(taskDefinition.TopologicalDependencies)
+		taskDefinition.TaskDependencies = normalizedList(taskDefinition.TaskDependencies)
+		taskDefinition.RootTaskDependencies = normalizedList(taskDefinition.RootTaskDependencies)
+		taskDefinition.TaskDependencyGlobs = normalizedList(taskDefinition.TaskDependencyGlobs)
+		taskDefinition.Inputs = normalizedList(taskDefinition.Inputs)
+		taskDefinition.With = normalizedList(taskDefinition.With)
+		taskDefinition.DotEnv = normalizedList(taskDefinition.DotEnv)
+		// Unset inputs are marshalled as an empty list, the same as "inputs": []
+		taskDefinition.ExplicitInputs = false
+		taskDefinition.ExplicitOutputs = false
+		normalized.Pipeline[taskName] = taskDefinition
+	}
+
+	return normalized
+}
+
+// normalizedSet sorts and dedupes values, treating nil as empty
+func normalizedSet(values []string) []string {
+	return normalizedList(normalizedStrings(values))
+}
+
+// normalizedList treats nil as empty, keeping the order of values
+func normalizedList(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,73 @@
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// defaultSource labels the fields of a resolved task that no layer set
+const defaultSource = "default"
+
+// resolvedTaskWithSources is the output of PrintResolvedTask
+type resolvedTaskWithSources struct {
+	Task                   string            `json:"task"`
+	ResolvedTaskDefinition *TaskDefinition   `json:"resolvedTaskDefinition"`
+	Sources                map[string]string `json:"sources"`
+}
+
+// PrintResolvedTask merges taskName across the extends chain of tj, like ResolvePipeline,
+// and returns it as JSON along with the source of each key: either the workspace of the
+// layer that set it, or "default". Layers that weren't returned by ResolveExtends are
+// labeled by their position in the chain instead.
+func (tj *TurboJSON) PrintResolvedTask(taskName string, chain []*TurboJSON) ([]byte, error) {
+	layers := []*TurboJSON{}
+	for _, turboJSON := range chain {
+		if turboJSON != tj {
+			layers = append(layers, turboJSON)
+		}
+	}
+	layers = append(layers, tj)
+
+	taskDefinitions := []BookkeepingTaskDefinition{}
+	layerNames := []string{}
+	for i, layer := range layers {
+		if bookkeepingTaskDef, ok := layer.Pipeline[taskName]; ok {
+			taskDefinitions = append(taskDefinitions, bookkeepingTaskDef)
+			layerName := layer.workspaceName
+			if layerName == "" {
+				layerName = fmt.Sprintf("layer %d", i)
+			}
+			layerNames = append(layerNames, layerName)
+		}
+	}
+	if len(taskDefinitions) == 0 {
+		return nil, fmt.Errorf("Could not find task \"%s\" in any of the %d configurations in its extends chain", taskName, len(layers))
+	}
+
+	merged, trace, err := MergeTaskDefinitionsWithTrace(taskDefinitions)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[string]string{}
+	for key, fields := range nullableTaskFields {
+		// A key that sets several fields (e.g. "dependsOn") comes from the last layer to set any of them
+		source := -1
+		for _, field := range fields {
+			if i, ok := trace[field]; ok && i > source {
+				source = i
+			}
+		}
+		if source == -1 {
+			sources[key] = defaultSource
+		} else {
+			sources[key] = layerNames[source]
+		}
+	}
+
+	return json.MarshalIndent(resolvedTaskWithSources{
+		Task:                   taskName,
+		ResolvedTaskDefinition: merged,
+		Sources:                sources,
+	}, "", "  ")
+}
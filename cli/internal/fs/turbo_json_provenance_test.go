@@ -0,0 +1,53 @@
+package fs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrintResolvedTask(t *testing.T) {
+	root := parseTurboJSON(t, `{"pipeline": {"build": {"outputs": ["dist/**"], "dependsOn": ["^build"], "env": ["NODE_ENV"]}}}`)
+	web := parseTurboJSON(t, `{"extends": ["//"], "pipeline": {"build": {"outputs": [".next/**"], "dependsOn": ["codegen"]}}}`)
+	chain, err := web.ResolveExtends("web", func(workspaceName string) (*TurboJSON, error) {
+		return root, nil
+	})
+	assert.NoError(t, err)
+
+	output, err := web.PrintResolvedTask("build", chain)
+	assert.NoError(t, err)
+
+	var resolved struct {
+		Task                   string                 `json:"task"`
+		ResolvedTaskDefinition map[string]interface{} `json:"resolvedTaskDefinition"`
+		Sources                map[string]string      `json:"sources"`
+	}
+	assert.NoError(t, json.Unmarshal(output, &resolved))
+	assert.Equal(t, "build", resolved.Task)
+	assert.Equal(t, []interface{}{".next/**"}, resolved.ResolvedTaskDefinition["outputs"])
+	assert.Equal(t, []interface{}{"^build", "codegen"}, resolved.ResolvedTaskDefinition["dependsOn"])
+
+	// Overridden fields point to the last layer that set them
+	assert.Equal(t, "web", resolved.Sources["outputs"])
+	assert.Equal(t, "web", resolved.Sources["dependsOn"])
+	assert.Equal(t, "//", resolved.Sources["env"])
+	// Everything else is a default
+	assert.Equal(t, defaultSource, resolved.Sources["cache"])
+	assert.Equal(t, defaultSource, resolved.Sources["outputMode"])
+	assert.Equal(t, defaultSource, resolved.Sources["inputs"])
+	assert.Len(t, resolved.Sources, len(nullableTaskFields))
+}
+
+func Test_PrintResolvedTask_UnnamedLayers(t *testing.T) {
+	base := parseTurboJSON(t, `{"pipeline": {"lint": {"cache": false}}}`)
+	child := parseTurboJSON(t, `{"pipeline": {"lint": {"outputs": []}}}`)
+
+	output, err := child.PrintResolvedTask("lint", []*TurboJSON{base})
+	assert.NoError(t, err)
+	assert.Contains(t, string(output), `"cache": "layer 0"`)
+	assert.Contains(t, string(output), `"outputs": "layer 1"`)
+
+	_, err = child.PrintResolvedTask("test", []*TurboJSON{base})
+	assert.EqualError(t, err, "Could not find task \"test\" in any of the 2 configurations in its extends chain")
+}
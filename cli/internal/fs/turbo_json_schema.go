@@ -0,0 +1,133 @@
+package fs
+
+import (
+	"encoding/json"
+
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema is the subset of JSON Schema (draft-07) that we need to describe configFile
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Minimum              interface{}            `json:"minimum,omitempty"`
+}
+
+func stringArraySchema(description string) *jsonSchema {
+	return &jsonSchema{
+		Type:        "array",
+		Description: description,
+		Items:       &jsonSchema{Type: "string"},
+	}
+}
+
+// taskSchema describes a single entry in the pipeline (i.e. rawTask)
+func taskSchema() *jsonSchema {
+	return &jsonSchema{
+		Type:                 "object",
+		Description:          "The configuration for a task in the pipeline.",
+		AdditionalProperties: false,
+		Properties: map[string]*jsonSchema{
+			"outputs":        stringArraySchema("The set of glob patterns of a task's cacheable filesystem outputs. Prefix a pattern with \"!\" to exclude it, and start it with \"$TURBO_ROOT$/\" to make it relative to the repository root instead of the package. Use an empty list for a cached task that only has logs."),
+			"outputsExclude": stringArraySchema("Glob patterns to exclude from \"outputs\", without the \"!\" prefix. The same as prefixing them with \"!\" in \"outputs\"."),
+			"cache": {
+				Description: "Whether or not to cache the outputs of the task. Use an object to toggle local and remote caching separately.",
+				Default:     true,
+				OneOf: []*jsonSchema{
+					{Type: "boolean"},
+					{
+						Type:                 "object",
+						AdditionalProperties: false,
+						Properties: map[string]*jsonSchema{
+							"local":  {Type: "boolean", Default: true},
+							"remote": {Type: "boolean", Default: true},
+						},
+					},
+				},
+			},
+			"cacheDisabledReason": {Type: "string", Description: "An informational note explaining why caching is disabled for the task."},
+			"cacheKey":            {Type: "string", Description: "An arbitrary string that is included in the task's hash. Change it to invalidate the cached outputs of the task."},
+			"dependsOn":           stringArraySchema("The list of tasks this task depends on. Prefix a task with \"^\" to depend on that task in the workspace's dependencies. Use a glob (e.g. \"test:*\") to depend on every matching task in the pipeline. Include \"...\" to add to the dependencies of the configurations this extends instead of replacing them."),
+			"inputs":              stringArraySchema("The set of glob patterns to consider as inputs to the task. Defaults to all files in the workspace. Include \"...\" to add to the inputs of the configurations this extends instead of replacing them."),
+			"outputMode":          {Type: "string", Description: "How the output of the task should be logged.", Enum: util.TaskOutputModeStrings},
+			"outputLogs":          {Type: "string", Description: "How the logs of the task should be written to the cache.", Enum: util.TaskOutputLogsStrings},
+			"envMode":             {Type: "string", Description: "Whether the task's hash includes the environment variables inferred from its framework (\"loose\"), or only the ones it declares (\"strict\"). Strict tasks also only receive the declared and global environment variables.", Enum: util.EnvModeStrings},
+			"env":                 stringArraySchema("A list of environment variables that the task's hash depends on."),
+			"passThroughEnv":      stringArraySchema("A list of environment variables that are made available to the task but do not affect its hash."),
+			"interactive":         {Type: "boolean", Description: "Whether the task reads from stdin, in which case stdin is connected to the task directly.", Default: false},
+			"dotEnv":              stringArraySchema("The .env files, relative to the workspace, whose contents affect the task's hash. Later files take precedence."),
+			"with":                stringArraySchema("Tasks to start alongside the task, without waiting for them to finish or including them in its hash."),
+			"persistent":          {Type: "boolean", Description: "Whether the task is long-running (e.g. a dev server) and is not expected to exit.", Default: false},
+			"timeout":             {Type: "string", Description: "How long the task may run before it is killed, as a duration (e.g. \"30s\" or \"5m\")."},
+			"weight":              {Type: "integer", Description: "The number of concurrency slots the task takes up while it runs.", Default: defaultTaskWeight, Minimum: 1},
+			"retries":             {Type: "integer", Description: "How many more times to run the task if it fails, e.g. for flaky tests.", Default: 0, Minimum: 0},
+			"description":         {Type: "string", Description: "Documents the task for tooling. Unlike a comment, it survives reformatting. It does not affect the hash."},
+		},
+	}
+}
+
+// GenerateSchema returns a JSON Schema (draft-07) describing configFile
+func GenerateSchema() ([]byte, error) {
+	schema := &jsonSchema{
+		Schema:               jsonSchemaDraft,
+		Title:                configFile,
+		Type:                 "object",
+		AdditionalProperties: false,
+		Properties: map[string]*jsonSchema{
+			"$schema":              {Type: "string", Description: "The JSON Schema to validate this file against, e.g. in an editor."},
+			"globalDependencies":   stringArraySchema("A list of globs of files that affect the hash of every task."),
+			"globalEnv":            stringArraySchema("A list of environment variables that affect the hash of every task."),
+			"globalPassThroughEnv": stringArraySchema("A list of environment variables that are made available to every task but do not affect hashes."),
+			"outputMode":           {Type: "string", Description: "The outputMode of every task that doesn't set its own.", Enum: util.TaskOutputModeStrings},
+			"globalDotEnv":         stringArraySchema("The .env files, relative to the repository root, whose contents affect the hash of every task. Later files take precedence."),
+			"envMode":              {Type: "string", Description: "The envMode of every task that doesn't set its own.", Enum: util.EnvModeStrings},
+			"excludeScripts":       stringArraySchema("Root package.json scripts (e.g. \"postinstall\") that should not become tasks in a single-package repository."),
+			"pipeline": {
+				Type:                 "object",
+				Description:          "A map of task names (or <workspace>#<task> IDs) to their configuration.",
+				AdditionalProperties: taskSchema(),
+			},
+			"remoteCache": {
+				Type:                 "object",
+				Description:          "Configuration options when interfacing with the remote cache.",
+				AdditionalProperties: false,
+				Properties: map[string]*jsonSchema{
+					"teamId":    {Type: "string", Description: "The team to use for the remote cache. References to environment variables (e.g. \"$VERCEL_TEAM_ID\") are resolved when the configuration is loaded."},
+					"signature": {Type: "boolean", Description: "Whether to sign artifacts uploaded to the remote cache.", Default: false},
+					"apiUrl":    {Type: "string", Description: "The URL of the remote cache, e.g. for a self-hosted cache. References to environment variables are resolved when the configuration is loaded."},
+					"enabled":   {Type: "boolean", Description: "Whether to use the remote cache. Defaults to the CLI and environment configuration."},
+				},
+			},
+			"extends": stringArraySchema("The workspaces this configuration extends from."),
+			"overrides": {
+				Type:        "object",
+				Description: "Partial pipelines, keyed by the name of a condition (e.g. \"ci\"), that are merged over the pipeline when the condition is active.",
+				AdditionalProperties: &jsonSchema{
+					Type:                 "object",
+					AdditionalProperties: taskSchema(),
+				},
+			},
+			"experimental": {
+				Type:                 "object",
+				Description:          "Feature flags for experimental features. Unknown flags are ignored with a warning.",
+				AdditionalProperties: true,
+				Properties: map[string]*jsonSchema{
+					experimentalUI:                    {Type: "boolean", Description: "Whether to use the new terminal UI.", Default: false},
+					experimentalImplicitInputsWarning: {Type: "boolean", Description: "Whether to warn about cached tasks with outputs that don't declare their inputs.", Default: true},
+				},
+			},
+		},
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
@@ -0,0 +1,47 @@
+package fs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+func Test_GenerateSchema(t *testing.T) {
+	generated, err := GenerateSchema()
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(generated, &schema); err != nil {
+		t.Fatalf("generated schema is not valid json: %v", err)
+	}
+
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema["$schema"])
+
+	properties := schema["properties"].(map[string]interface{})
+	for _, key := range []string{"globalDependencies", "globalEnv", "globalPassThroughEnv", "pipeline", "remoteCache", "extends"} {
+		assert.Contains(t, properties, key)
+	}
+
+	remoteCache := properties["remoteCache"].(map[string]interface{})
+	remoteCacheProperties := remoteCache["properties"].(map[string]interface{})
+	assert.Contains(t, remoteCacheProperties, "teamId")
+	assert.Contains(t, remoteCacheProperties, "signature")
+
+	pipeline := properties["pipeline"].(map[string]interface{})
+	task := pipeline["additionalProperties"].(map[string]interface{})
+	taskProperties := task["properties"].(map[string]interface{})
+	for _, key := range []string{"outputs", "cache", "dependsOn", "inputs", "outputMode", "env", "persistent"} {
+		assert.Contains(t, taskProperties, key)
+	}
+
+	outputMode := taskProperties["outputMode"].(map[string]interface{})
+	outputModeEnum := []string{}
+	for _, value := range outputMode["enum"].([]interface{}) {
+		outputModeEnum = append(outputModeEnum, value.(string))
+	}
+	assert.EqualValues(t, util.TaskOutputModeStrings, outputModeEnum)
+}
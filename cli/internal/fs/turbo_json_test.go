@@ -1,16 +1,21 @@
 package fs
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vercel/turbo/cli/internal/turbopath"
 	"github.com/vercel/turbo/cli/internal/util"
-	"gotest.tools/v3/assert/cmp"
 )
 
 func assertIsSorted(t *testing.T, arr []string, msg string) {
@@ -29,7 +34,7 @@ func assertIsSorted(t *testing.T, arr []string, msg string) {
 
 func Test_ReadTurboConfig(t *testing.T) {
 	testDir := getTestDir(t, "correct")
-	turboJSON, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"))
+	turboJSON, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
 
 	if turboJSONReadErr != nil {
 		t.Fatalf("invalid parse: %#v", turboJSONReadErr)
@@ -40,22 +45,28 @@ func Test_ReadTurboConfig(t *testing.T) {
 			definedFields: util.SetFromStrings([]string{"Outputs", "OutputMode", "TopologicalDependencies"}),
 			TaskDefinition: TaskDefinition{
 				Outputs:                 TaskOutputs{Inclusions: []string{".next/**", "dist/**"}, Exclusions: []string{"dist/assets/**"}},
+				ExplicitOutputs:         true,
 				TopologicalDependencies: []string{"build"},
 				EnvVarDependencies:      []string{},
 				TaskDependencies:        []string{},
 				ShouldCache:             true,
+				Cache:                   CacheConfig{Local: true, Remote: true},
 				OutputMode:              util.NewTaskOutput,
+				Weight:                  1,
 			},
 		},
 		"lint": {
 			definedFields: util.SetFromStrings([]string{"Outputs", "OutputMode", "ShouldCache"}),
 			TaskDefinition: TaskDefinition{
 				Outputs:                 TaskOutputs{},
+				ExplicitOutputs:         true,
 				TopologicalDependencies: []string{},
 				EnvVarDependencies:      []string{"MY_VAR"},
 				TaskDependencies:        []string{},
 				ShouldCache:             true,
+				Cache:                   CacheConfig{Local: true, Remote: true},
 				OutputMode:              util.NewTaskOutput,
+				Weight:                  1,
 			},
 		},
 		"dev": {
@@ -66,25 +77,32 @@ func Test_ReadTurboConfig(t *testing.T) {
 				EnvVarDependencies:      []string{},
 				TaskDependencies:        []string{},
 				ShouldCache:             false,
+				Cache:                   CacheConfig{Local: false, Remote: false},
 				OutputMode:              util.FullTaskOutput,
+				Weight:                  1,
 			},
 		},
 		"publish": {
 			definedFields: util.SetFromStrings([]string{"Inputs", "Outputs", "TaskDependencies", "TopologicalDependencies", "ShouldCache"}),
+			Description:   "mocked test comment",
 			TaskDefinition: TaskDefinition{
 				Outputs:                 TaskOutputs{Inclusions: []string{"dist/**"}},
+				ExplicitOutputs:         true,
 				TopologicalDependencies: []string{"build", "publish"},
 				EnvVarDependencies:      []string{},
 				TaskDependencies:        []string{"admin#lint", "build"},
 				ShouldCache:             false,
+				Cache:                   CacheConfig{Local: false, Remote: false},
 				Inputs:                  []string{"build/**/*"},
+				ExplicitInputs:          true,
 				OutputMode:              util.FullTaskOutput,
+				Weight:                  1,
 			},
 		},
 	}
 
 	validateOutput(t, turboJSON, pipelineExpected)
-	remoteCacheOptionsExpected := RemoteCacheOptions{"team_id", true}
+	remoteCacheOptionsExpected := RemoteCacheOptions{TeamID: "team_id", Signature: true}
 	assert.EqualValues(t, remoteCacheOptionsExpected, turboJSON.RemoteCacheOptions)
 }
 
@@ -97,7 +115,7 @@ func Test_LoadTurboConfig_Legacy(t *testing.T) {
 		t.Fatalf("invalid parse: %#v", pkgJSONReadErr)
 	}
 
-	_, turboJSONReadErr := LoadTurboConfig(testDir, rootPackageJSON, false)
+	_, _, turboJSONReadErr := LoadTurboConfig(testDir, rootPackageJSON, false)
 	expectedErrorMsg := "Could not find turbo.json. Follow directions at https://turbo.build/repo/docs to create one: file does not exist"
 	assert.EqualErrorf(t, turboJSONReadErr, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, turboJSONReadErr)
 }
@@ -112,7 +130,7 @@ func Test_LoadTurboConfig_BothCorrectAndLegacy(t *testing.T) {
 		t.Fatalf("invalid parse: %#v", pkgJSONReadErr)
 	}
 
-	turboJSON, turboJSONReadErr := LoadTurboConfig(testDir, rootPackageJSON, false)
+	turboJSON, warnings, turboJSONReadErr := LoadTurboConfig(testDir, rootPackageJSON, false)
 
 	if turboJSONReadErr != nil {
 		t.Fatalf("invalid parse: %#v", turboJSONReadErr)
@@ -123,47 +141,51 @@ func Test_LoadTurboConfig_BothCorrectAndLegacy(t *testing.T) {
 			definedFields: util.SetFromStrings([]string{"Outputs", "OutputMode", "TopologicalDependencies"}),
 			TaskDefinition: TaskDefinition{
 				Outputs:                 TaskOutputs{Inclusions: []string{".next/**", "dist/**"}, Exclusions: []string{"dist/assets/**"}},
+				ExplicitOutputs:         true,
 				TopologicalDependencies: []string{"build"},
 				EnvVarDependencies:      []string{},
 				TaskDependencies:        []string{},
 				ShouldCache:             true,
+				Cache:                   CacheConfig{Local: true, Remote: true},
 				OutputMode:              util.NewTaskOutput,
+				Weight:                  1,
 			},
 		},
 	}
 
 	validateOutput(t, turboJSON, pipelineExpected)
 
-	remoteCacheOptionsExpected := RemoteCacheOptions{"team_id", true}
+	remoteCacheOptionsExpected := RemoteCacheOptions{TeamID: "team_id", Signature: true}
 	assert.EqualValues(t, remoteCacheOptionsExpected, turboJSON.RemoteCacheOptions)
 	assert.Equal(t, rootPackageJSON.LegacyTurboConfig == nil, true)
+	assert.EqualValues(t, []string{"\"turbo\" in package.json is no longer supported. Migrate to turbo.json by running \"npx @turbo/codemod create-turbo-config\""}, warnings)
 }
 
 func Test_ReadTurboConfig_InvalidEnvDeclarations1(t *testing.T) {
 	testDir := getTestDir(t, "invalid-env-1")
-	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"))
+	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
 
-	expectedErrorMsg := "turbo.json: You specified \"$A\" in the \"env\" key. You should not prefix your environment variables with \"$\""
+	expectedErrorMsg := "turbo.json: You specified \"$A\" in the \"env\" key. You should not prefix your environment variables with \"$\". If the variable name starts with \"$\", escape it as \"\\$\""
 	assert.EqualErrorf(t, turboJSONReadErr, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, turboJSONReadErr)
 }
 
 func Test_ReadTurboConfig_InvalidEnvDeclarations2(t *testing.T) {
 	testDir := getTestDir(t, "invalid-env-2")
-	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"))
-	expectedErrorMsg := "turbo.json: You specified \"$A\" in the \"env\" key. You should not prefix your environment variables with \"$\""
+	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
+	expectedErrorMsg := "turbo.json: You specified \"$A\" in the \"env\" key. You should not prefix your environment variables with \"$\". If the variable name starts with \"$\", escape it as \"\\$\""
 	assert.EqualErrorf(t, turboJSONReadErr, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, turboJSONReadErr)
 }
 
 func Test_ReadTurboConfig_InvalidGlobalEnvDeclarations(t *testing.T) {
 	testDir := getTestDir(t, "invalid-global-env")
-	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"))
-	expectedErrorMsg := "turbo.json: You specified \"$QUX\" in the \"env\" key. You should not prefix your environment variables with \"$\""
+	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
+	expectedErrorMsg := "turbo.json: You specified \"$QUX\" in the \"env\" key. You should not prefix your environment variables with \"$\". If the variable name starts with \"$\", escape it as \"\\$\""
 	assert.EqualErrorf(t, turboJSONReadErr, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, turboJSONReadErr)
 }
 
 func Test_ReadTurboConfig_EnvDeclarations(t *testing.T) {
 	testDir := getTestDir(t, "legacy-env")
-	turboJSON, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"))
+	turboJSON, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
 
 	if turboJSONReadErr != nil {
 		t.Fatalf("invalid parse: %#v", turboJSONReadErr)
@@ -187,13 +209,2463 @@ func Test_ReadTurboConfig_EnvDeclarations(t *testing.T) {
 }
 
 func Test_TaskOutputsSort(t *testing.T) {
-	inclusions := []string{"foo/**", "bar"}
-	exclusions := []string{"special-file", ".hidden/**"}
-	taskOutputs := TaskOutputs{Inclusions: inclusions, Exclusions: exclusions}
-	sortedOutputs := taskOutputs.Sort()
-	assertIsSorted(t, sortedOutputs.Inclusions, "Inclusions")
-	assertIsSorted(t, sortedOutputs.Exclusions, "Exclusions")
-	assert.False(t, cmp.DeepEqual(taskOutputs, sortedOutputs)().Success())
+	testCases := []struct {
+		name     string
+		input    TaskOutputs
+		expected TaskOutputs
+	}{
+		{
+			name:     "unsorted inclusions and exclusions",
+			input:    TaskOutputs{Inclusions: []string{"foo/**", "bar"}, Exclusions: []string{"special-file", ".hidden/**"}},
+			expected: TaskOutputs{Inclusions: []string{"bar", "foo/**"}, Exclusions: []string{".hidden/**", "special-file"}},
+		},
+		{
+			name:     "already sorted",
+			input:    TaskOutputs{Inclusions: []string{"a", "b"}, Exclusions: []string{"c"}},
+			expected: TaskOutputs{Inclusions: []string{"a", "b"}, Exclusions: []string{"c"}},
+		},
+		{
+			name:     "empty",
+			input:    TaskOutputs{Inclusions: []string{}, Exclusions: []string{}},
+			expected: TaskOutputs{Inclusions: []string{}, Exclusions: []string{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			original := TaskOutputs{
+				Inclusions: append([]string{}, tc.input.Inclusions...),
+				Exclusions: append([]string{}, tc.input.Exclusions...),
+			}
+			sortedOutputs := tc.input.Sort()
+			assertIsSorted(t, sortedOutputs.Inclusions, "Inclusions")
+			assertIsSorted(t, sortedOutputs.Exclusions, "Exclusions")
+			assert.EqualValues(t, tc.expected, sortedOutputs)
+			// The receiver should not be mutated
+			assert.EqualValues(t, original, tc.input)
+		})
+	}
+}
+
+func Test_PassThroughEnv_RoundTrip(t *testing.T) {
+	var bookkeepingTaskDef BookkeepingTaskDefinition
+	err := json.Unmarshal([]byte(`{"passThroughEnv": ["B_VAR", "A_VAR"]}`), &bookkeepingTaskDef)
+	assert.NoError(t, err)
+	assert.True(t, bookkeepingTaskDef.hasField("PassThroughEnv"))
+	assert.EqualValues(t, []string{"A_VAR", "B_VAR"}, bookkeepingTaskDef.TaskDefinition.PassThroughEnv)
+
+	serialized, err := json.Marshal(bookkeepingTaskDef.TaskDefinition)
+	assert.NoError(t, err)
+
+	var roundTripped BookkeepingTaskDefinition
+	err = json.Unmarshal(serialized, &roundTripped)
+	assert.NoError(t, err)
+	assert.EqualValues(t, bookkeepingTaskDef.TaskDefinition.PassThroughEnv, roundTripped.TaskDefinition.PassThroughEnv)
+}
+
+func Test_PassThroughEnv_InvalidDeclaration(t *testing.T) {
+	var bookkeepingTaskDef BookkeepingTaskDefinition
+	err := json.Unmarshal([]byte(`{"passThroughEnv": ["$A"]}`), &bookkeepingTaskDef)
+	expectedErrorMsg := "You specified \"$A\" in the \"passThroughEnv\" key. You should not prefix your environment variables with \"$\""
+	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, err)
+}
+
+func Test_MergeTaskDefinitions_PassThroughEnv(t *testing.T) {
+	base := BookkeepingTaskDefinition{
+		definedFields:  util.SetFromStrings([]string{"PassThroughEnv"}),
+		TaskDefinition: TaskDefinition{PassThroughEnv: []string{"BASE_VAR"}},
+	}
+	withoutOverride := BookkeepingTaskDefinition{
+		definedFields:  util.SetFromStrings([]string{"ShouldCache"}),
+		TaskDefinition: TaskDefinition{ShouldCache: true},
+	}
+	withOverride := BookkeepingTaskDefinition{
+		definedFields:  util.SetFromStrings([]string{"PassThroughEnv"}),
+		TaskDefinition: TaskDefinition{PassThroughEnv: []string{"WORKSPACE_VAR"}},
+	}
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, withoutOverride})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"BASE_VAR"}, merged.PassThroughEnv)
+
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base, withOverride})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"WORKSPACE_VAR"}, merged.PassThroughEnv)
+}
+
+func Test_ReadTurboConfig_InvalidGlobalPassThroughEnvDeclarations(t *testing.T) {
+	testDir := getTestDir(t, "invalid-global-passthrough-env")
+	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
+	expectedErrorMsg := "turbo.json: You specified \"$BAR\" in the \"globalPassThroughEnv\" key. You should not prefix your environment variables with \"$\""
+	assert.EqualErrorf(t, turboJSONReadErr, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, turboJSONReadErr)
+}
+
+func Test_GlobalPassThroughEnv_RoundTrip(t *testing.T) {
+	var turboJSON TurboJSON
+	err := json.Unmarshal([]byte(`{"globalPassThroughEnv": ["SECRET_B", "SECRET_A"], "pipeline": {}}`), &turboJSON)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"SECRET_A", "SECRET_B"}, turboJSON.GlobalPassThroughEnv)
+
+	serialized, err := json.Marshal(&turboJSON)
+	assert.NoError(t, err)
+
+	var roundTripped TurboJSON
+	err = json.Unmarshal(serialized, &roundTripped)
+	assert.NoError(t, err)
+	assert.EqualValues(t, turboJSON.GlobalPassThroughEnv, roundTripped.GlobalPassThroughEnv)
+}
+
+func Test_ResolveExtends(t *testing.T) {
+	root := &TurboJSON{Pipeline: Pipeline{}}
+	shared := &TurboJSON{Pipeline: Pipeline{}, Extends: []string{"//"}}
+	workspace := &TurboJSON{Pipeline: Pipeline{}, Extends: []string{"shared"}}
+	configs := map[string]*TurboJSON{
+		"//":     root,
+		"shared": shared,
+	}
+	lookup := func(name string) (*TurboJSON, error) {
+		if turboJSON, ok := configs[name]; ok {
+			return turboJSON, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	chain, err := workspace.ResolveExtends("web", lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, []*TurboJSON{root, shared, workspace}, chain)
+
+	missing := &TurboJSON{Pipeline: Pipeline{}, Extends: []string{"nope"}}
+	_, err = missing.ResolveExtends("web", lookup)
+	assert.EqualError(t, err, "could not resolve \"nope\" extended by \"web\": file does not exist")
+}
+
+func Test_ResolveExtends_Cycle(t *testing.T) {
+	configs := map[string]*TurboJSON{
+		"a": {Pipeline: Pipeline{}, Extends: []string{"b"}},
+		"b": {Pipeline: Pipeline{}, Extends: []string{"c"}},
+		"c": {Pipeline: Pipeline{}, Extends: []string{"a"}},
+	}
+	lookup := func(name string) (*TurboJSON, error) {
+		return configs[name], nil
+	}
+
+	_, err := configs["a"].ResolveExtends("a", lookup)
+	assert.EqualError(t, err, "cyclic extends detected: a -> b -> c -> a")
+}
+
+func Test_ReadTurboConfig_SyntaxErrorPosition(t *testing.T) {
+	testDir := getTestDir(t, "invalid-syntax")
+	turboJSONPath := testDir.UntypedJoin("turbo.json")
+	_, turboJSONReadErr := readTurboConfig(turboJSONPath, ParseOptions{})
+
+	var parseErr *ParseError
+	if !errors.As(turboJSONReadErr, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", turboJSONReadErr)
+	}
+	assert.Equal(t, 6, parseErr.Line)
+	assert.Equal(t, 5, parseErr.Column)
+	expectedErrorMsg := turboJSONPath.ToString() + ":6:5: invalid character '}' looking for beginning of object key string"
+	assert.EqualErrorf(t, turboJSONReadErr, expectedErrorMsg, "Error should be: %v, got: %v", expectedErrorMsg, turboJSONReadErr)
+}
+
+func Test_ReadTurboConfig_TypeErrorPosition(t *testing.T) {
+	testDir := getTestDir(t, "invalid-type")
+	_, turboJSONReadErr := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
+
+	var parseErr *ParseError
+	if !errors.As(turboJSONReadErr, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", turboJSONReadErr)
+	}
+	assert.Equal(t, 5, parseErr.Line)
+	assert.Equal(t, 18, parseErr.Column)
+}
+
+func Test_ParseErrorPosition_TopLevelTypeError(t *testing.T) {
+	data := []byte("{\n  // global env must be a list\n  \"globalEnv\": \"NODE_ENV\",\n  \"pipeline\": {}\n}")
+	var turboJSON *TurboJSON
+	err := withParseErrorPosition("turbo.json", data, json.Unmarshal(data, &turboJSON))
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Equal(t, 16, parseErr.Column)
+	assert.Equal(t, "turbo.json", parseErr.Path)
+}
+
+func Test_TaskDefinitionEqual(t *testing.T) {
+	base := TaskDefinition{
+		Outputs:                 TaskOutputs{Inclusions: []string{"dist/**", ".next/**"}, Exclusions: []string{"dist/cache/**", ".next/cache/**"}},
+		ShouldCache:             true,
+		EnvVarDependencies:      []string{"A", "B"},
+		PassThroughEnv:          []string{"C", "D"},
+		TopologicalDependencies: []string{"build", "codegen"},
+		TaskDependencies:        []string{"lint", "//#check"},
+		Inputs:                  []string{"src/**", "package.json"},
+		OutputMode:              util.NewTaskOutput,
+		Persistent:              false,
+	}
+	reordered := TaskDefinition{
+		Outputs:                 TaskOutputs{Inclusions: []string{".next/**", "dist/**"}, Exclusions: []string{".next/cache/**", "dist/cache/**"}},
+		ShouldCache:             true,
+		EnvVarDependencies:      []string{"B", "A"},
+		PassThroughEnv:          []string{"D", "C"},
+		TopologicalDependencies: []string{"codegen", "build"},
+		TaskDependencies:        []string{"//#check", "lint"},
+		Inputs:                  []string{"package.json", "src/**"},
+		OutputMode:              util.NewTaskOutput,
+		Persistent:              false,
+	}
+	assert.True(t, base.Equal(reordered))
+	assert.True(t, reordered.Equal(base))
+	assert.True(t, TaskDefinition{}.Equal(TaskDefinition{Inputs: []string{}, EnvVarDependencies: []string{}}))
+
+	testCases := []struct {
+		name   string
+		modify func(td *TaskDefinition)
+	}{
+		{name: "Outputs.Inclusions", modify: func(td *TaskDefinition) { td.Outputs.Inclusions = []string{"dist/**"} }},
+		{name: "Outputs.Exclusions", modify: func(td *TaskDefinition) { td.Outputs.Exclusions = []string{"other/**"} }},
+		{name: "ShouldCache", modify: func(td *TaskDefinition) { td.ShouldCache = false }},
+		{name: "EnvVarDependencies", modify: func(td *TaskDefinition) { td.EnvVarDependencies = []string{"A", "C"} }},
+		{name: "PassThroughEnv", modify: func(td *TaskDefinition) { td.PassThroughEnv = nil }},
+		{name: "TopologicalDependencies", modify: func(td *TaskDefinition) { td.TopologicalDependencies = []string{"build"} }},
+		{name: "TaskDependencies", modify: func(td *TaskDefinition) { td.TaskDependencies = []string{"lint", "check"} }},
+		{name: "Inputs", modify: func(td *TaskDefinition) { td.Inputs = append(td.Inputs, "README.md") }},
+		{name: "OutputMode", modify: func(td *TaskDefinition) { td.OutputMode = util.FullTaskOutput }},
+		{name: "Persistent", modify: func(td *TaskDefinition) { td.Persistent = true }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			other := reordered
+			tc.modify(&other)
+			assert.False(t, base.Equal(other))
+			assert.False(t, other.Equal(base))
+		})
+	}
+}
+
+func Test_ExtendsLookup(t *testing.T) {
+	testDir := getTestDir(t, "extends-package")
+	turboJSON, err := readTurboConfig(testDir.UntypedJoin("turbo.json"), ParseOptions{})
+	if err != nil {
+		t.Fatalf("invalid parse: %v", err)
+	}
+
+	workspace := &TurboJSON{Pipeline: Pipeline{}}
+	lookup := ExtendsLookup(testDir, func(workspaceName string) (*TurboJSON, bool, error) {
+		if workspaceName == "shared-workspace" {
+			return workspace, true, nil
+		}
+		return nil, false, nil
+	})
+
+	chain, err := turboJSON.ResolveExtends("//", lookup)
+	assert.NoError(t, err)
+	if assert.Len(t, chain, 2) {
+		assert.EqualValues(t, []string{"CI"}, chain[0].GlobalEnv)
+		assert.EqualValues(t, []string{"dist/**"}, chain[0].Pipeline["build"].TaskDefinition.Outputs.Inclusions)
+		assert.Equal(t, turboJSON, chain[1])
+	}
+
+	// Workspaces take precedence over packages
+	resolved, err := lookup("shared-workspace")
+	assert.NoError(t, err)
+	assert.Equal(t, workspace, resolved)
+
+	_, err = lookup("@acme/missing")
+	expectedErrorMsg := "\"@acme/missing\" is not a workspace, and no turbo.json was found at " + testDir.UntypedJoin("node_modules", "@acme/missing", "turbo.json").ToString()
+	assert.EqualError(t, err, expectedErrorMsg)
+}
+
+func Test_TaskDefinitionHash(t *testing.T) {
+	taskDefinition := TaskDefinition{
+		Outputs:                 TaskOutputs{Inclusions: []string{"dist/**", ".next/**"}, Exclusions: []string{"dist/cache/**"}},
+		ShouldCache:             true,
+		EnvVarDependencies:      []string{"B", "A"},
+		TopologicalDependencies: []string{"codegen", "build"},
+		TaskDependencies:        []string{"lint", "//#check"},
+		Inputs:                  []string{"src/**", "package.json"},
+		OutputMode:              util.NewTaskOutput,
+	}
+	reordered := TaskDefinition{
+		Outputs:                 TaskOutputs{Inclusions: []string{".next/**", "dist/**"}, Exclusions: []string{"dist/cache/**"}},
+		ShouldCache:             true,
+		EnvVarDependencies:      []string{"A", "B"},
+		TopologicalDependencies: []string{"build", "codegen"},
+		TaskDependencies:        []string{"//#check", "lint"},
+		Inputs:                  []string{"package.json", "src/**"},
+		OutputMode:              util.NewTaskOutput,
+	}
+
+	hash, err := taskDefinition.Hash()
+	assert.NoError(t, err)
+	reorderedHash, err := reordered.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, hash, reorderedHash)
+
+	// Hashing should be stable and should not mutate the definition
+	rehashed, err := taskDefinition.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, hash, rehashed)
+	assert.Equal(t, []string{"B", "A"}, taskDefinition.EnvVarDependencies)
+
+	uncached := reordered
+	uncached.ShouldCache = false
+	uncachedHash, err := uncached.Hash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, uncachedHash)
+
+	fullOutput := reordered
+	fullOutput.OutputMode = util.FullTaskOutput
+	fullOutputHash, err := fullOutput.Hash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, fullOutputHash)
+}
+
+func Test_CacheConfig(t *testing.T) {
+	testCases := []struct {
+		name                string
+		json                string
+		expectedCache       CacheConfig
+		expectedShouldCache bool
+		expectedSerialized  string
+	}{
+		{
+			name:                "unset",
+			json:                `{}`,
+			expectedCache:       CacheConfig{Local: true, Remote: true},
+			expectedShouldCache: true,
+			expectedSerialized:  `true`,
+		},
+		{
+			name:                "bool true",
+			json:                `{"cache": true}`,
+			expectedCache:       CacheConfig{Local: true, Remote: true},
+			expectedShouldCache: true,
+			expectedSerialized:  `true`,
+		},
+		{
+			name:                "bool false",
+			json:                `{"cache": false}`,
+			expectedCache:       CacheConfig{Local: false, Remote: false},
+			expectedShouldCache: false,
+			expectedSerialized:  `false`,
+		},
+		{
+			name:                "local only",
+			json:                `{"cache": {"local": true, "remote": false}}`,
+			expectedCache:       CacheConfig{Local: true, Remote: false},
+			expectedShouldCache: true,
+			expectedSerialized:  `{"local":true,"remote":false}`,
+		},
+		{
+			name:                "remote only with an omitted key",
+			json:                `{"cache": {"local": false}}`,
+			expectedCache:       CacheConfig{Local: false, Remote: true},
+			expectedShouldCache: true,
+			expectedSerialized:  `{"local":false,"remote":true}`,
+		},
+		{
+			name:                "object with both disabled",
+			json:                `{"cache": {"local": false, "remote": false}}`,
+			expectedCache:       CacheConfig{Local: false, Remote: false},
+			expectedShouldCache: false,
+			expectedSerialized:  `false`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bookkeepingTaskDef BookkeepingTaskDefinition
+			err := json.Unmarshal([]byte(tc.json), &bookkeepingTaskDef)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCache, bookkeepingTaskDef.TaskDefinition.Cache)
+			assert.Equal(t, tc.expectedShouldCache, bookkeepingTaskDef.TaskDefinition.ShouldCache)
+
+			serialized, err := json.Marshal(bookkeepingTaskDef.TaskDefinition)
+			assert.NoError(t, err)
+			var raw map[string]json.RawMessage
+			err = json.Unmarshal(serialized, &raw)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSerialized, string(raw["cache"]))
+
+			var roundTripped BookkeepingTaskDefinition
+			err = json.Unmarshal(serialized, &roundTripped)
+			assert.NoError(t, err)
+			assert.Equal(t, bookkeepingTaskDef.TaskDefinition.Cache, roundTripped.TaskDefinition.Cache)
+		})
+	}
+
+	var bookkeepingTaskDef BookkeepingTaskDefinition
+	err := json.Unmarshal([]byte(`{"cache": "yes"}`), &bookkeepingTaskDef)
+	assert.ErrorContains(t, err, "\"cache\" must be a boolean or an object with \"local\" and \"remote\" keys")
+}
+
+func Test_MergeTaskDefinitions_Cache(t *testing.T) {
+	var base BookkeepingTaskDefinition
+	var localOnly BookkeepingTaskDefinition
+	var unrelated BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"cache": false}`), &base))
+	assert.NoError(t, json.Unmarshal([]byte(`{"cache": {"remote": false}}`), &localOnly))
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputs": ["dist/**"]}`), &unrelated))
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, unrelated})
+	assert.NoError(t, err)
+	assert.False(t, merged.ShouldCache)
+	assert.Equal(t, CacheConfig{Local: false, Remote: false}, merged.Cache)
+
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base, localOnly})
+	assert.NoError(t, err)
+	assert.True(t, merged.ShouldCache)
+	assert.Equal(t, CacheConfig{Local: true, Remote: false}, merged.Cache)
+
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{unrelated})
+	assert.NoError(t, err)
+	assert.True(t, merged.ShouldCache)
+	assert.Equal(t, CacheConfig{Local: true, Remote: true}, merged.Cache)
+}
+
+func Test_OutputLogs(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected util.TaskOutputLogs
+	}{
+		{value: "full", expected: util.FullTaskOutputLogs},
+		{value: "hash-only", expected: util.HashTaskOutputLogs},
+		{value: "none", expected: util.NoTaskOutputLogs},
+		{value: "errors-only", expected: util.ErrorTaskOutputLogs},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			var bookkeepingTaskDef BookkeepingTaskDefinition
+			err := json.Unmarshal([]byte(`{"outputMode": "new-only", "outputLogs": "`+tc.value+`"}`), &bookkeepingTaskDef)
+			assert.NoError(t, err)
+			assert.True(t, bookkeepingTaskDef.hasField("OutputLogs"))
+			assert.Equal(t, tc.expected, bookkeepingTaskDef.TaskDefinition.OutputLogs)
+			assert.Equal(t, util.NewTaskOutput, bookkeepingTaskDef.TaskDefinition.OutputMode)
+
+			serialized, err := json.Marshal(bookkeepingTaskDef.TaskDefinition)
+			assert.NoError(t, err)
+			var roundTripped BookkeepingTaskDefinition
+			err = json.Unmarshal(serialized, &roundTripped)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, roundTripped.TaskDefinition.OutputLogs)
+		})
+	}
+
+	var bookkeepingTaskDef BookkeepingTaskDefinition
+	err := json.Unmarshal([]byte(`{"outputLogs": "new-only"}`), &bookkeepingTaskDef)
+	assert.EqualError(t, err, "invalid task output logs: new-only. Valid values are: full, hash-only, none, errors-only")
+}
+
+func Test_MergeTaskDefinitions_OutputLogs(t *testing.T) {
+	var base BookkeepingTaskDefinition
+	var override BookkeepingTaskDefinition
+	var unrelated BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputLogs": "errors-only"}`), &base))
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputLogs": "none"}`), &override))
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputMode": "none"}`), &unrelated))
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, unrelated})
+	assert.NoError(t, err)
+	assert.Equal(t, util.ErrorTaskOutputLogs, merged.OutputLogs)
+	assert.Equal(t, util.NoTaskOutput, merged.OutputMode)
+
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base, override})
+	assert.NoError(t, err)
+	assert.Equal(t, util.NoTaskOutputLogs, merged.OutputLogs)
+}
+
+func Test_RootTaskDependencies(t *testing.T) {
+	var bookkeepingTaskDef BookkeepingTaskDefinition
+	err := json.Unmarshal([]byte(`{"dependsOn": ["test", "//lint", "^build", "//#format", "//typecheck"]}`), &bookkeepingTaskDef)
+	assert.NoError(t, err)
+
+	taskDefinition := bookkeepingTaskDef.TaskDefinition
+	assert.True(t, bookkeepingTaskDef.hasField("RootTaskDependencies"))
+	assert.EqualValues(t, []string{"lint", "typecheck"}, taskDefinition.RootTaskDependencies)
+	assert.EqualValues(t, []string{"build"}, taskDefinition.TopologicalDependencies)
+	assert.EqualValues(t, []string{"//#format", "test"}, taskDefinition.TaskDependencies)
+
+	serialized, err := json.Marshal(taskDefinition)
+	assert.NoError(t, err)
+	var raw map[string]interface{}
+	assert.NoError(t, json.Unmarshal(serialized, &raw))
+	assert.EqualValues(t, []interface{}{"//#format", "//lint", "//typecheck", "^build", "test"}, raw["dependsOn"])
+}
+
+func Test_MergeTaskDefinitionsWithTrace(t *testing.T) {
+	layers := []string{
+		`{"outputs": ["dist/**"], "cache": false, "dependsOn": ["^build"]}`,
+		`{"outputs": ["build/**"], "env": ["API_URL"]}`,
+		`{"cache": true, "persistent": true}`,
+	}
+	taskDefinitions := []BookkeepingTaskDefinition{}
+	for _, layer := range layers {
+		var bookkeepingTaskDef BookkeepingTaskDefinition
+		assert.NoError(t, json.Unmarshal([]byte(layer), &bookkeepingTaskDef))
+		taskDefinitions = append(taskDefinitions, bookkeepingTaskDef)
+	}
+
+	merged, trace, err := MergeTaskDefinitionsWithTrace(taskDefinitions)
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[string]int{
+		"Outputs":                 1,
+		"ShouldCache":             2,
+		"TopologicalDependencies": 0,
+		"EnvVarDependencies":      1,
+		"Persistent":              2,
+	}, trace)
+
+	untraced, err := MergeTaskDefinitions(taskDefinitions)
+	assert.NoError(t, err)
+	assert.EqualValues(t, untraced, merged)
+}
+
+func Test_Timeout(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "30s", expected: 30 * time.Second},
+		{value: "5m", expected: 5 * time.Minute},
+		{value: "1h30m", expected: 90 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {"test": {"timeout": "`+tc.value+`"}}}`)
+			taskDefinition := turboJSON.Pipeline["test"].TaskDefinition
+			assert.Equal(t, tc.expected, taskDefinition.Timeout)
+
+			serialized, err := json.Marshal(taskDefinition)
+			assert.NoError(t, err)
+			var roundTripped BookkeepingTaskDefinition
+			assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+			assert.Equal(t, tc.expected, roundTripped.TaskDefinition.Timeout)
+		})
+	}
+}
+
+func Test_Timeout_Invalid(t *testing.T) {
+	testCases := []struct {
+		json     string
+		expected string
+	}{
+		{
+			json:     `{"pipeline": {"test": {"timeout": "soon"}}}`,
+			expected: `task "test": invalid "timeout" value "soon": time: invalid duration "soon"`,
+		},
+		{
+			json:     `{"pipeline": {"build": {}, "web#test": {"timeout": "-5m"}}}`,
+			expected: `task "web#test": invalid "timeout" value "-5m": must not be negative`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var turboJSON TurboJSON
+		err := json.Unmarshal([]byte(tc.json), &turboJSON)
+		assert.EqualError(t, err, tc.expected)
+	}
+}
+
+func Test_MergeTaskDefinitions_Timeout(t *testing.T) {
+	var base BookkeepingTaskDefinition
+	var override BookkeepingTaskDefinition
+	var unrelated BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"timeout": "10m"}`), &base))
+	assert.NoError(t, json.Unmarshal([]byte(`{"timeout": "30s"}`), &override))
+	assert.NoError(t, json.Unmarshal([]byte(`{"persistent": false}`), &unrelated))
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, unrelated})
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, merged.Timeout)
+
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base, override})
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, merged.Timeout)
+}
+
+func Test_AllEnvVarDependencies(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalEnv": ["CI", "NODE_ENV"],
+		"pipeline": {
+			"build": {"env": ["NODE_ENV", "API_URL"]},
+			"test": {"env": ["API_URL", "TEST_SEED"]},
+			"lint": {}
+		}
+	}`)
+	assert.EqualValues(t, []string{"API_URL", "CI", "NODE_ENV", "TEST_SEED"}, turboJSON.AllEnvVarDependencies())
+
+	empty := parseTurboJSON(t, `{"pipeline": {}}`)
+	assert.EqualValues(t, []string{}, empty.AllEnvVarDependencies())
+}
+
+func Test_CacheDisabledReason(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"deploy": {"cache": false, "cacheDisabledReason": "deploys have side effects"}}}`)
+	taskDefinition := turboJSON.Pipeline["deploy"].TaskDefinition
+	assert.Equal(t, "deploys have side effects", taskDefinition.CacheDisabledReason)
+	assert.Empty(t, turboJSON.Warnings())
+
+	serialized, err := json.Marshal(taskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, "deploys have side effects", roundTripped.TaskDefinition.CacheDisabledReason)
+	assert.False(t, roundTripped.TaskDefinition.ShouldCache)
+
+	ignored := parseTurboJSON(t, `{"pipeline": {"build": {"cache": true, "cacheDisabledReason": "stale"}}}`)
+	assert.EqualValues(t, []string{"task \"build\": \"cacheDisabledReason\" (stale) is set on a task with \"cache\" enabled and will be ignored"}, ignored.Warnings())
+}
+
+func Test_PipelineTaskNamesAndPackageTasks(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"test": {},
+		"build": {},
+		"web#build": {},
+		"web#dev": {},
+		"docs#lint": {},
+		"//#format": {}
+	}}`)
+
+	assert.EqualValues(t, []string{"build", "test"}, turboJSON.Pipeline.TaskNames())
+	assert.EqualValues(t, map[string][]string{
+		"web":  {"web#build", "web#dev"},
+		"docs": {"docs#lint"},
+		"//":   {"//#format"},
+	}, turboJSON.Pipeline.PackageTasks())
+
+	empty := Pipeline{}
+	assert.EqualValues(t, []string{}, empty.TaskNames())
+	assert.EqualValues(t, map[string][]string{}, empty.PackageTasks())
+}
+
+func Test_RemoteCacheOptions_APIURLAndEnabled(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {}, "remoteCache": {"apiUrl": "https://cache.example.com"}}`)
+	assert.Equal(t, "https://cache.example.com", turboJSON.RemoteCacheOptions.APIURL)
+	assert.Nil(t, turboJSON.RemoteCacheOptions.Enabled)
+
+	serialized, err := turboJSON.MarshalJSON()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(serialized), "enabled")
+	roundTripped := parseTurboJSON(t, string(serialized))
+	assert.Equal(t, "https://cache.example.com", roundTripped.RemoteCacheOptions.APIURL)
+	assert.Nil(t, roundTripped.RemoteCacheOptions.Enabled)
+
+	disabled := parseTurboJSON(t, `{"pipeline": {}, "remoteCache": {"enabled": false}}`)
+	serialized, err = disabled.MarshalJSON()
+	assert.NoError(t, err)
+	roundTripped = parseTurboJSON(t, string(serialized))
+	if assert.NotNil(t, roundTripped.RemoteCacheOptions.Enabled) {
+		assert.False(t, *roundTripped.RemoteCacheOptions.Enabled)
+	}
+	assert.Empty(t, roundTripped.RemoteCacheOptions.APIURL)
+}
+
+func Test_WriteToFile(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalEnv": ["CI"],
+		"pipeline": {
+			"build": {"outputs": ["dist/**"], "dependsOn": ["^build"]},
+			"test": {"cache": false, "inputs": ["src/**"]}
+		},
+		"remoteCache": {"teamId": "team_id"}
+	}`)
+
+	path := turbopath.AbsoluteSystemPath(t.TempDir()).UntypedJoin("turbo.json")
+	assert.NoError(t, turboJSON.WriteToFile(path))
+
+	contents, err := path.ReadFile()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(contents), "}\n"))
+
+	written, err := readTurboJSON(path, ParseOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, turboJSON.GlobalEnv, written.GlobalEnv)
+	assert.EqualValues(t, turboJSON.RemoteCacheOptions, written.RemoteCacheOptions)
+	assert.Len(t, written.Pipeline, len(turboJSON.Pipeline))
+	for taskName, bookkeepingTaskDef := range turboJSON.Pipeline {
+		assert.True(t, bookkeepingTaskDef.TaskDefinition.Equal(written.Pipeline[taskName].TaskDefinition), taskName)
+	}
+}
+
+func Test_WriteToFile_MarshalError(t *testing.T) {
+	path := turbopath.AbsoluteSystemPath(t.TempDir()).UntypedJoin("turbo.json")
+	original := []byte(`{"pipeline": {}}`)
+	assert.NoError(t, path.WriteFile(original, 0644))
+
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {}}}`)
+	build := turboJSON.Pipeline["build"]
+	build.TaskDefinition.OutputMode = util.TaskOutputMode(-1)
+	turboJSON.Pipeline["build"] = build
+
+	assert.Error(t, turboJSON.WriteToFile(path))
+	contents, err := path.ReadFile()
+	assert.NoError(t, err)
+	assert.Equal(t, original, contents)
+	entries, err := os.ReadDir(path.Dir().ToString())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_MergeTaskDefinitions_NullRemovesInheritedFields(t *testing.T) {
+	base := parseTurboJSON(t, `{"pipeline": {"build": {
+		"outputs": ["dist/**"],
+		"env": ["API_URL"],
+		"inputs": ["src/**"],
+		"dependsOn": ["^build", "codegen"]
+	}}}`)
+	child := parseTurboJSON(t, `{"pipeline": {"build": {
+		"outputs": null,
+		"env": null,
+		"dependsOn": null
+	}}}`)
+
+	childTask := child.Pipeline["build"]
+	assert.False(t, childTask.hasField("Outputs"))
+	assert.True(t, childTask.hasDeletedField("Outputs"))
+	assert.True(t, childTask.hasDeletedField("EnvVarDependencies"))
+	assert.True(t, childTask.hasDeletedField("TaskDependencies"))
+	assert.False(t, childTask.hasDeletedField("Inputs"))
+
+	merged, trace, err := MergeTaskDefinitionsWithTrace([]BookkeepingTaskDefinition{base.Pipeline["build"], childTask})
+	assert.NoError(t, err)
+	assert.Empty(t, merged.Outputs.Inclusions)
+	assert.Empty(t, merged.EnvVarDependencies)
+	assert.Empty(t, merged.TopologicalDependencies)
+	assert.Empty(t, merged.TaskDependencies)
+	assert.EqualValues(t, []string{"src/**"}, merged.Inputs)
+	assert.EqualValues(t, map[string]int{"Inputs": 0}, trace)
+
+	// A later layer can set the field again after it has been removed
+	grandchild := parseTurboJSON(t, `{"pipeline": {"build": {"outputs": ["out/**"]}}}`)
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base.Pipeline["build"], childTask, grandchild.Pipeline["build"]})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"out/**"}, merged.Outputs.Inclusions)
+	assert.Empty(t, merged.EnvVarDependencies)
+}
+
+func Test_ResolvePipeline(t *testing.T) {
+	root := parseTurboJSON(t, `{"pipeline": {
+		"build": {"outputs": ["dist/**"], "dependsOn": ["^build"], "env": ["API_URL"]},
+		"lint": {"outputMode": "errors-only"}
+	}}`)
+	shared := parseTurboJSON(t, `{"extends": ["//"], "pipeline": {
+		"build": {"outputs": ["out/**"], "cache": false},
+		"test": {"inputs": ["src/**"]}
+	}}`)
+	workspace := parseTurboJSON(t, `{"extends": ["shared"], "pipeline": {
+		"build": {"persistent": true},
+		"lint": {"env": ["LINT_MODE"]}
+	}}`)
+
+	resolved, err := workspace.ResolvePipeline([]*TurboJSON{root, shared, workspace})
+	assert.NoError(t, err)
+	assert.Len(t, resolved, 3)
+
+	build := resolved["build"]
+	assert.EqualValues(t, []string{"out/**"}, build.Outputs.Inclusions)
+	assert.False(t, build.ShouldCache)
+	assert.EqualValues(t, []string{"build"}, build.TopologicalDependencies)
+	assert.EqualValues(t, []string{"API_URL"}, build.EnvVarDependencies)
+	assert.True(t, build.Persistent)
+
+	lint := resolved["lint"]
+	assert.True(t, lint.ShouldCache)
+	assert.Equal(t, util.ErrorTaskOutput, lint.OutputMode)
+	assert.EqualValues(t, []string{"LINT_MODE"}, lint.EnvVarDependencies)
+
+	test := resolved["test"]
+	assert.True(t, test.ShouldCache)
+	assert.Equal(t, CacheConfig{Local: true, Remote: true}, test.Cache)
+	assert.EqualValues(t, []string{"src/**"}, test.Inputs)
+
+	// tj is merged last even if it is not part of the chain
+	withoutSelf, err := workspace.ResolvePipeline([]*TurboJSON{root, shared})
+	assert.NoError(t, err)
+	assert.True(t, withoutSelf["build"].Equal(build))
+}
+
+func Test_TurboDefaultInputs(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputs         string
+		expectedInputs []string
+		expectedFlag   bool
+	}{
+		{
+			name:           "token only",
+			inputs:         `["$TURBO_DEFAULT$"]`,
+			expectedInputs: []string{},
+			expectedFlag:   true,
+		},
+		{
+			name:           "token with other patterns",
+			inputs:         `["$TURBO_DEFAULT$", "../shared/**", "!README.md"]`,
+			expectedInputs: []string{"../shared/**", "!README.md"},
+			expectedFlag:   true,
+		},
+		{
+			name:           "no token",
+			inputs:         `["src/**"]`,
+			expectedInputs: []string{"src/**"},
+			expectedFlag:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bookkeepingTaskDef BookkeepingTaskDefinition
+			assert.NoError(t, json.Unmarshal([]byte(`{"inputs": `+tc.inputs+`}`), &bookkeepingTaskDef))
+			taskDefinition := bookkeepingTaskDef.TaskDefinition
+			assert.EqualValues(t, tc.expectedInputs, taskDefinition.Inputs)
+			assert.Equal(t, tc.expectedFlag, taskDefinition.DefaultInputs)
+
+			serialized, err := json.Marshal(taskDefinition)
+			assert.NoError(t, err)
+			var roundTripped BookkeepingTaskDefinition
+			assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+			assert.True(t, taskDefinition.Equal(roundTripped.TaskDefinition))
+		})
+	}
+}
+
+func Test_MergeTurboJSON(t *testing.T) {
+	base := parseTurboJSON(t, `{
+		"globalDependencies": ["tsconfig.json", ".env"],
+		"globalEnv": ["CI", "NODE_ENV"],
+		"pipeline": {},
+		"remoteCache": {"teamId": "team_base", "signature": true, "apiUrl": "https://base.example.com"}
+	}`)
+	child := parseTurboJSON(t, `{
+		"globalDependencies": [".env", "babel.config.js"],
+		"globalEnv": ["NODE_ENV", "API_URL"],
+		"globalPassThroughEnv": ["AWS_SECRET"],
+		"pipeline": {},
+		"remoteCache": {"signature": false, "enabled": false}
+	}`)
+
+	merged, err := MergeTurboJSON([]*TurboJSON{base, child})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{".env", "babel.config.js", "tsconfig.json"}, merged.GlobalDeps)
+	assert.EqualValues(t, []string{"API_URL", "CI", "NODE_ENV"}, merged.GlobalEnv)
+	assert.EqualValues(t, []string{"AWS_SECRET"}, merged.GlobalPassThroughEnv)
+
+	// Fields the child set win, the rest are inherited from the base
+	assert.Equal(t, "team_base", merged.RemoteCacheOptions.TeamID)
+	assert.False(t, merged.RemoteCacheOptions.Signature)
+	assert.Equal(t, "https://base.example.com", merged.RemoteCacheOptions.APIURL)
+	if assert.NotNil(t, merged.RemoteCacheOptions.Enabled) {
+		assert.False(t, *merged.RemoteCacheOptions.Enabled)
+	}
+
+	// Order matters for overrides but not for unions
+	merged, err = MergeTurboJSON([]*TurboJSON{child, base})
+	assert.NoError(t, err)
+	assert.True(t, merged.RemoteCacheOptions.Signature)
+	assert.EqualValues(t, []string{"API_URL", "CI", "NODE_ENV"}, merged.GlobalEnv)
+}
+
+func Test_ReadTurboConfig_Deprecations(t *testing.T) {
+	testDir := getTestDir(t, "deprecated-env-dependency")
+	turboJSONPath := testDir.UntypedJoin("turbo.json")
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	turboJSON, err := readTurboConfig(turboJSONPath, ParseOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"MY_VAR"}, turboJSON.Pipeline["build"].TaskDefinition.EnvVarDependencies)
+	assert.Contains(t, logs.String(), "[WARNING] task \"build\": Declaring an environment variable in \"dependsOn\" is deprecated, found $MY_VAR.")
+	assert.Contains(t, turboJSON.Warnings(), "task \"build\": Declaring an environment variable in \"dependsOn\" is deprecated, found $MY_VAR. Use the \"env\" key or use `npx @turbo/codemod migrate-env-var-dependencies`.")
+
+	logs.Reset()
+	silenced, err := readTurboConfig(turboJSONPath, ParseOptions{SilenceWarnings: true})
+	assert.NoError(t, err)
+	assert.EqualValues(t, turboJSON.Warnings(), silenced.Warnings())
+	assert.Empty(t, logs.String())
+
+	logs.Reset()
+	_, err = readTurboConfig(turboJSONPath, ParseOptions{StrictDeprecations: true})
+	assert.EqualError(t, err, "turbo.json: task \"build\": Declaring an environment variable in \"dependsOn\" is deprecated, found $MY_VAR. Use the \"env\" key or use `npx @turbo/codemod migrate-env-var-dependencies`.")
+	assert.Empty(t, logs.String())
+}
+
+func Test_PipelineFilter(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {},
+		"dev": {"cache": false},
+		"web#build": {},
+		"docs#lint": {"cache": false}
+	}}`)
+
+	cacheable := turboJSON.Pipeline.Filter(func(taskID string, def BookkeepingTaskDefinition) bool {
+		return def.TaskDefinition.ShouldCache
+	})
+	assert.EqualValues(t, []string{"build", "web#build"}, cacheable.SortedTaskIDs())
+
+	packageTasks := turboJSON.Pipeline.Filter(func(taskID string, def BookkeepingTaskDefinition) bool {
+		return util.IsPackageTask(taskID)
+	})
+	assert.EqualValues(t, []string{"docs#lint", "web#build"}, packageTasks.SortedTaskIDs())
+
+	// The original pipeline is left untouched
+	assert.Len(t, turboJSON.Pipeline, 4)
+}
+
+func Test_UnusedTasks(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {},
+		"test": {},
+		"deploy": {},
+		"web#build": {},
+		"docs#build": {},
+		"docs#typecheck": {},
+		"//#format": {}
+	}}`)
+
+	packageScripts := map[string][]string{
+		"web":  {"build", "test"},
+		"docs": {"build", "lint"},
+		"//":   {"format"},
+	}
+	assert.EqualValues(t, []string{"deploy", "docs#typecheck"}, turboJSON.UnusedTasks(packageScripts))
+	assert.EqualValues(t, []string{}, parseTurboJSON(t, `{"pipeline": {}}`).UnusedTasks(packageScripts))
+}
+
+func Test_Interactive(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"db:migrate": {"interactive": true, "cache": false}}}`)
+	bookkeepingTaskDef := turboJSON.Pipeline["db:migrate"]
+	assert.True(t, bookkeepingTaskDef.hasField("Interactive"))
+	assert.True(t, bookkeepingTaskDef.TaskDefinition.Interactive)
+	assert.Empty(t, turboJSON.Warnings())
+
+	serialized, err := json.Marshal(bookkeepingTaskDef.TaskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.True(t, roundTripped.TaskDefinition.Interactive)
+	assert.False(t, roundTripped.TaskDefinition.ShouldCache)
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{bookkeepingTaskDef, parseTurboJSON(t, `{"pipeline": {"db:migrate": {"interactive": false}}}`).Pipeline["db:migrate"]})
+	assert.NoError(t, err)
+	assert.False(t, merged.Interactive)
+
+	cached := parseTurboJSON(t, `{"pipeline": {"db:migrate": {"interactive": true, "cache": true}}}`)
+	assert.EqualValues(t, []string{"task \"db:migrate\": Interactive tasks should not be cached, set \"cache\" to false for tasks with \"interactive\" enabled"}, cached.Warnings())
+}
+
+func Test_GetResolvedTask(t *testing.T) {
+	base := parseTurboJSON(t, `{"pipeline": {
+		"build": {"outputs": ["dist/**"], "dependsOn": ["^build"]},
+		"web#build": {"env": ["WEB_URL"]}
+	}}`)
+	child := parseTurboJSON(t, `{"pipeline": {
+		"build": {"cache": false, "inputs": ["src/**"]}
+	}}`)
+	chain := []*TurboJSON{base, child}
+
+	build, err := GetResolvedTask("docs#build", "build", chain)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"dist/**"}, build.Outputs.Inclusions)
+	assert.EqualValues(t, []string{"build"}, build.TopologicalDependencies)
+	assert.False(t, build.ShouldCache)
+	assert.EqualValues(t, []string{"src/**"}, build.Inputs)
+
+	// The package task wins over the task in the base, but the child still applies
+	webBuild, err := GetResolvedTask("web#build", "build", chain)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"WEB_URL"}, webBuild.EnvVarDependencies)
+	assert.Empty(t, webBuild.Outputs.Inclusions)
+	assert.False(t, webBuild.ShouldCache)
+
+	_, err = GetResolvedTask("web#deploy", "deploy", chain)
+	assert.EqualError(t, err, "Could not find task \"web#deploy\" in any of the 2 configurations in its extends chain")
+}
+
+func Test_MarshalIndentCanonical(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"remoteCache": {"signature": true, "teamId": "team_id"},
+		"pipeline": {
+			"web#build": {"outputs": ["dist/**", ".next/**"]},
+			"build": {"dependsOn": ["^build", "codegen"]},
+			"lint": {},
+			"dev": {"cache": false, "persistent": true}
+		},
+		"globalEnv": ["NODE_ENV", "CI"],
+		"extends": ["//"]
+	}`)
+
+	first, err := turboJSON.MarshalIndentCanonical()
+	assert.NoError(t, err)
+	second, err := turboJSON.MarshalIndentCanonical()
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.True(t, strings.HasSuffix(string(first), "}\n"))
+	assert.Contains(t, string(first), "\n  \"pipeline\": {\n    \"build\": {\n      \"outputs\": [],")
+
+	output := string(first)
+	keys := []string{`"globalEnv"`, `"pipeline"`, `"build"`, `"dev"`, `"lint"`, `"web#build"`, `"remoteCache"`, `"extends"`}
+	lastIndex := -1
+	for _, key := range keys {
+		index := strings.Index(output, key)
+		assert.Greater(t, index, lastIndex, key)
+		lastIndex = index
+	}
+	assert.Less(t, strings.Index(output, `"CI"`), strings.Index(output, `"NODE_ENV"`))
+}
+
+func Test_With(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"test": {"with": ["mock", "db"]}, "mock": {}, "db": {}}}`)
+	bookkeepingTaskDef := turboJSON.Pipeline["test"]
+	assert.True(t, bookkeepingTaskDef.hasField("With"))
+	assert.EqualValues(t, []string{"db", "mock"}, bookkeepingTaskDef.TaskDefinition.With)
+	assert.Empty(t, bookkeepingTaskDef.TaskDefinition.TaskDependencies)
+
+	serialized, err := json.Marshal(bookkeepingTaskDef.TaskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.EqualValues(t, []string{"db", "mock"}, roundTripped.TaskDefinition.With)
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{bookkeepingTaskDef, parseTurboJSON(t, `{"pipeline": {"test": {"with": ["mock"]}}}`).Pipeline["test"]})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"mock"}, merged.With)
+}
+
+func Test_DefinedFields(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {"outputs": ["dist/**"], "dependsOn": ["^build", "codegen"], "env": ["NODE_ENV"], "persistent": false},
+		"codegen": {}
+	}}`)
+	assert.EqualValues(t, []string{"EnvVarDependencies", "Outputs", "Persistent", "TaskDependencies", "TopologicalDependencies"}, turboJSON.Pipeline["build"].DefinedFields())
+	assert.Empty(t, turboJSON.Pipeline["codegen"].DefinedFields())
+}
+
+func Test_GlobalOutputMode(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"outputMode": "new-only",
+		"pipeline": {
+			"build": {},
+			"lint": {"outputMode": "errors-only"},
+			"test": {"outputMode": null}
+		}
+	}`)
+	assert.Equal(t, util.NewTaskOutput, *turboJSON.OutputMode)
+	assert.Equal(t, util.NewTaskOutput, turboJSON.Pipeline["build"].TaskDefinition.OutputMode)
+	assert.Equal(t, util.ErrorTaskOutput, turboJSON.Pipeline["lint"].TaskDefinition.OutputMode)
+	assert.Equal(t, util.FullTaskOutput, turboJSON.Pipeline["test"].TaskDefinition.OutputMode)
+
+	// The global value is a default, not a key that the task sets
+	assert.False(t, turboJSON.Pipeline["build"].hasField("OutputMode"))
+	assert.Empty(t, turboJSON.Pipeline["build"].DefinedFields())
+
+	// It doesn't override the tasks of configurations it extends, but is used for the
+	// ones that don't set outputMode either
+	root := parseTurboJSON(t, `{"outputMode": "errors-only", "pipeline": {"build": {"outputMode": "hash-only"}, "lint": {}, "test": {"outputMode": "hash-only"}}}`)
+	resolved, err := turboJSON.ResolvePipeline([]*TurboJSON{root})
+	assert.NoError(t, err)
+	assert.Equal(t, util.HashTaskOutput, resolved["build"].OutputMode)
+	assert.Equal(t, util.ErrorTaskOutput, resolved["lint"].OutputMode)
+	assert.Equal(t, util.FullTaskOutput, resolved["test"].OutputMode)
+	extended := parseTurboJSON(t, `{"pipeline": {"build": {}}}`)
+	resolved, err = extended.ResolvePipeline([]*TurboJSON{turboJSON})
+	assert.NoError(t, err)
+	assert.Equal(t, util.NewTaskOutput, resolved["build"].OutputMode)
+
+	// Overrides that don't set outputMode keep the default
+	overridden := parseTurboJSON(t, `{"outputMode": "new-only", "pipeline": {"build": {}}, "overrides": {"ci": {"build": {"persistent": true}}}}`)
+	assert.NoError(t, overridden.ApplyOverrides([]string{"ci"}))
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{overridden.Pipeline["build"]})
+	assert.NoError(t, err)
+	assert.Equal(t, util.NewTaskOutput, merged.OutputMode)
+	assert.False(t, overridden.Pipeline["build"].hasField("OutputMode"))
+
+	withoutGlobal := parseTurboJSON(t, `{"pipeline": {"build": {}}}`)
+	assert.Nil(t, withoutGlobal.OutputMode)
+	assert.Equal(t, util.FullTaskOutput, withoutGlobal.Pipeline["build"].TaskDefinition.OutputMode)
+	assert.False(t, withoutGlobal.Pipeline["build"].hasField("OutputMode"))
+}
+
+func Test_LoadTurboConfigFromPath(t *testing.T) {
+	testDir := getTestDir(t, "custom-config-path")
+	rootPackageJSON, err := ReadPackageJSON(testDir.UntypedJoin("package.json"))
+	assert.NoError(t, err)
+
+	turboJSON, err := LoadTurboConfigFromPath(testDir.UntypedJoin("turbo.ci.json"), rootPackageJSON, false, ParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"build"}, turboJSON.Pipeline.TaskNames())
+	assert.Equal(t, []string{"dist/**"}, turboJSON.Pipeline["build"].TaskDefinition.Outputs.Inclusions)
+
+	// Synthesizing from package.json is still optional
+	synthesized, err := LoadTurboConfigFromPath(testDir.UntypedJoin("turbo.ci.json"), rootPackageJSON, true, ParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"//": {"//#build", "//#deploy"}}, synthesized.Pipeline.PackageTasks())
+
+	_, err = LoadTurboConfigFromPath(testDir.UntypedJoin("turbo.missing.json"), rootPackageJSON, false, ParseOptions{})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Contains(t, err.Error(), "Could not find turbo.missing.json")
+}
+
+func Test_DotEnv(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalDotEnv": [".env", ".env.local"],
+		"pipeline": {
+			"build": {"dotEnv": [".env.production", ".env"]},
+			"lint": {}
+		}
+	}`)
+	assert.Equal(t, []string{".env", ".env.local"}, turboJSON.GlobalDotEnv)
+	build := turboJSON.Pipeline["build"]
+	assert.True(t, build.hasField("DotEnv"))
+	// The order is preserved, since later files take precedence
+	assert.Equal(t, []string{".env.production", ".env"}, build.TaskDefinition.DotEnv)
+	assert.False(t, turboJSON.Pipeline["lint"].hasField("DotEnv"))
+
+	serialized, err := json.Marshal(turboJSON)
+	assert.NoError(t, err)
+	var roundTripped TurboJSON
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, turboJSON.GlobalDotEnv, roundTripped.GlobalDotEnv)
+	assert.True(t, build.TaskDefinition.Equal(roundTripped.Pipeline["build"].TaskDefinition))
+	assert.False(t, build.TaskDefinition.Equal(TaskDefinition{DotEnv: []string{".env", ".env.production"}}))
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{build, parseTurboJSON(t, `{"pipeline": {"build": {"dotEnv": null}}}`).Pipeline["build"]})
+	assert.NoError(t, err)
+	assert.Nil(t, merged.DotEnv)
+}
+
+func Test_DotEnv_AbsolutePaths(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalDotEnv": ["/etc/.env"],
+		"pipeline": {"build": {"dotEnv": [".env", "/home/user/.env"]}}
+	}`)
+	assert.EqualValues(t, []string{
+		"Using an absolute path in \"globalDotEnv\" (/etc/.env) will not work and will be an error in a future version",
+		"task \"build\": Using an absolute path in \"dotEnv\" (/home/user/.env) will not work and will be an error in a future version",
+	}, turboJSON.Warnings())
+}
+
+func Test_MergeTaskDefinitions_InheritedDependsOn(t *testing.T) {
+	base := parseTurboJSON(t, `{"pipeline": {"build": {"dependsOn": ["^build", "codegen"]}}}`).Pipeline["build"]
+
+	testCases := []struct {
+		name                    string
+		dependsOn               string
+		taskDependencies        []string
+		topologicalDependencies []string
+	}{
+		{
+			name:                    "prepend",
+			dependsOn:               `["lint", "..."]`,
+			taskDependencies:        []string{"codegen", "lint"},
+			topologicalDependencies: []string{"build"},
+		},
+		{
+			name:                    "append",
+			dependsOn:               `["...", "^prepare", "codegen"]`,
+			taskDependencies:        []string{"codegen"},
+			topologicalDependencies: []string{"build", "prepare"},
+		},
+		{
+			name:                    "missing token replaces",
+			dependsOn:               `["lint"]`,
+			taskDependencies:        []string{"lint"},
+			topologicalDependencies: []string{"build"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			child := parseTurboJSON(t, `{"pipeline": {"build": {"dependsOn": `+tc.dependsOn+`}}}`).Pipeline["build"]
+			assert.NotContains(t, child.TaskDefinition.TaskDependencies, inheritedDependsOn)
+
+			merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, child})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.taskDependencies, merged.TaskDependencies)
+			assert.Equal(t, tc.topologicalDependencies, merged.TopologicalDependencies)
+		})
+	}
+}
+
+func Test_DependencyClosure(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pipeline string
+		task     string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "linear chain",
+			pipeline: `{"deploy": {"dependsOn": ["test"]}, "test": {"dependsOn": ["build"]}, "build": {"dependsOn": ["^build"]}}`,
+			task:     "deploy",
+			want:     []string{"^build", "build", "test"},
+		},
+		{
+			name:     "diamond",
+			pipeline: `{"deploy": {"dependsOn": ["lint", "test"]}, "lint": {"dependsOn": ["codegen"]}, "test": {"dependsOn": ["codegen", "//check"]}, "codegen": {}, "//#check": {}}`,
+			task:     "deploy",
+			want:     []string{"//#check", "codegen", "lint", "test"},
+		},
+		{
+			name:     "no dependencies",
+			pipeline: `{"build": {}}`,
+			task:     "build",
+			want:     []string{},
+		},
+		{
+			name:     "cycle",
+			pipeline: `{"deploy": {"dependsOn": ["build"]}, "build": {"dependsOn": ["test"]}, "test": {"dependsOn": ["build"]}}`,
+			task:     "deploy",
+			wantErr:  "cyclic task dependency detected: build -> test -> build",
+		},
+		{
+			name:     "missing task",
+			pipeline: `{"build": {}}`,
+			task:     "deploy",
+			wantErr:  "Could not find task \"deploy\" in pipeline",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": `+tc.pipeline+`}`)
+			closure, err := turboJSON.DependencyClosure(tc.task)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, closure)
+		})
+	}
+}
+
+func Test_LoadTurboConfig_YAML(t *testing.T) {
+	testDir := getTestDir(t, "yaml-only")
+	rootPackageJSON, err := ReadPackageJSON(testDir.UntypedJoin("package.json"))
+	assert.NoError(t, err)
+
+	turboJSON, _, err := LoadTurboConfig(testDir, rootPackageJSON, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CI"}, turboJSON.GlobalEnv)
+	assert.Equal(t, []string{"build", "test"}, turboJSON.Pipeline.TaskNames())
+
+	build := turboJSON.Pipeline["build"].TaskDefinition
+	assert.Equal(t, []string{"build"}, build.TopologicalDependencies)
+	assert.Equal(t, []string{"dist/**"}, build.Outputs.Inclusions)
+	test := turboJSON.Pipeline["test"].TaskDefinition
+	assert.Equal(t, []string{"build"}, test.TaskDependencies)
+	assert.False(t, test.ShouldCache)
+}
+
+func Test_LoadTurboConfig_YAMLAndJSON(t *testing.T) {
+	testDir := getTestDir(t, "yaml-and-json")
+	rootPackageJSON, err := ReadPackageJSON(testDir.UntypedJoin("package.json"))
+	assert.NoError(t, err)
+
+	_, _, err = LoadTurboConfig(testDir, rootPackageJSON, false)
+	assert.EqualError(t, err, "Found both turbo.json and turbo.yaml in "+testDir.ToString()+", remove one of them")
+}
+
+func Test_ReadTurboConfig_DisallowUnknownFields(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{
+			name:     "misspelled top-level key",
+			contents: `{"globalDependecies": [".env"], "pipeline": {"build": {}}}`,
+			wantErr:  "turbo.json: json: unknown field \"globalDependecies\"",
+		},
+		{
+			name:     "misspelled task key",
+			contents: `{"pipeline": {"build": {}, "test": {"depondsOn": ["build"]}}}`,
+			wantErr:  "turbo.json: task \"test\": json: unknown field \"depondsOn\"",
+		},
+		{
+			name: "known keys with comments",
+			contents: `{
+				// Build everything
+				"pipeline": {"build": {"outputs": ["dist/**"], "cache": {"remote": false}}},
+				"remoteCache": {"signature": true}
+			}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSONPath := turbopath.AbsoluteSystemPath(t.TempDir()).UntypedJoin(configFile)
+			assert.NoError(t, turboJSONPath.WriteFile([]byte(tc.contents), 0644))
+
+			// Unknown keys are ignored by default
+			_, err := readTurboConfig(turboJSONPath, ParseOptions{})
+			assert.NoError(t, err)
+
+			_, err = readTurboConfig(turboJSONPath, ParseOptions{DisallowUnknownFields: true})
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_PipelineClone(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {"outputs": ["dist/**", "!dist/cache/**"], "dependsOn": ["^build"], "env": ["NODE_ENV"]},
+		"test": {"inputs": null}
+	}}`)
+	original := turboJSON.Pipeline
+	clone := original.Clone()
+	assert.True(t, reflect.DeepEqual(original, clone))
+
+	build := clone["build"]
+	build.TaskDefinition.Outputs.Inclusions[0] = "out/**"
+	build.TaskDefinition.Outputs.Exclusions = append(build.TaskDefinition.Outputs.Exclusions, "!out/cache/**")
+	build.TaskDefinition.TopologicalDependencies[0] = "prepare"
+	build.TaskDefinition.EnvVarDependencies[0] = "CI"
+	build.definedFields.Add("Inputs")
+	clone["build"] = build
+	clone["test"].deletedFields.Delete("Inputs")
+	clone["lint"] = BookkeepingTaskDefinition{}
+
+	assert.Equal(t, []string{"dist/**"}, original["build"].TaskDefinition.Outputs.Inclusions)
+	assert.Equal(t, []string{"dist/cache/**"}, original["build"].TaskDefinition.Outputs.Exclusions)
+	assert.Equal(t, []string{"build"}, original["build"].TaskDefinition.TopologicalDependencies)
+	assert.Equal(t, []string{"NODE_ENV"}, original["build"].TaskDefinition.EnvVarDependencies)
+	assert.False(t, original["build"].hasField("Inputs"))
+	assert.True(t, original["test"].hasDeletedField("Inputs"))
+	assert.False(t, original.HasTask("lint"))
+}
+
+func Test_EnvMode(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"envMode": "strict",
+		"pipeline": {
+			"build": {},
+			"dev": {"envMode": "loose"},
+			"test": {"envMode": null}
+		}
+	}`)
+	assert.Equal(t, util.StrictEnvMode, *turboJSON.EnvMode)
+	assert.Equal(t, util.StrictEnvMode, turboJSON.Pipeline["build"].TaskDefinition.EnvMode)
+	assert.Equal(t, util.LooseEnvMode, turboJSON.Pipeline["dev"].TaskDefinition.EnvMode)
+	assert.Equal(t, util.LooseEnvMode, turboJSON.Pipeline["test"].TaskDefinition.EnvMode)
+
+	serialized, err := json.Marshal(turboJSON)
+	assert.NoError(t, err)
+	assert.Contains(t, string(serialized), `"envMode":"strict"`)
+	var roundTripped TurboJSON
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, util.StrictEnvMode, *roundTripped.EnvMode)
+	assert.Equal(t, util.StrictEnvMode, roundTripped.Pipeline["build"].TaskDefinition.EnvMode)
+	assert.Equal(t, util.LooseEnvMode, roundTripped.Pipeline["dev"].TaskDefinition.EnvMode)
+
+	// A task's envMode overrides the one it inherits
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{turboJSON.Pipeline["build"], parseTurboJSON(t, `{"pipeline": {"build": {"envMode": "loose"}}}`).Pipeline["build"]})
+	assert.NoError(t, err)
+	assert.Equal(t, util.LooseEnvMode, merged.EnvMode)
+
+	// The global envMode is a default: it isn't a key the task sets, and doesn't override
+	// the tasks of configurations it extends
+	assert.False(t, turboJSON.Pipeline["build"].hasField("EnvMode"))
+	assert.Empty(t, turboJSON.Pipeline["build"].DefinedFields())
+	root := parseTurboJSON(t, `{"pipeline": {"build": {"envMode": "loose"}, "lint": {}}}`)
+	extending := parseTurboJSON(t, `{"envMode": "strict", "pipeline": {"build": {}, "lint": {}}}`)
+	resolved, err := extending.ResolvePipeline([]*TurboJSON{root})
+	assert.NoError(t, err)
+	assert.Equal(t, util.LooseEnvMode, resolved["build"].EnvMode)
+	assert.Equal(t, util.StrictEnvMode, resolved["lint"].EnvMode)
+
+	withoutEnvMode := parseTurboJSON(t, `{"pipeline": {"build": {}}}`)
+	assert.Nil(t, withoutEnvMode.EnvMode)
+	assert.Equal(t, util.LooseEnvMode, withoutEnvMode.Pipeline["build"].TaskDefinition.EnvMode)
+
+	// Without a global envMode, loose tasks leave it out of their serialized form
+	serialized, err = json.Marshal(withoutEnvMode)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(serialized), `"envMode"`)
+}
+
+func Test_EnvMode_Invalid(t *testing.T) {
+	var turboJSON TurboJSON
+	err := json.Unmarshal([]byte(`{"pipeline": {"build": {"envMode": "relaxed"}}}`), &turboJSON)
+	assert.ErrorContains(t, err, "invalid env mode: relaxed. Valid values are: loose, strict")
+
+	err = json.Unmarshal([]byte(`{"envMode": "relaxed", "pipeline": {}}`), &turboJSON)
+	assert.ErrorContains(t, err, "invalid env mode: relaxed")
+}
+
+func Test_TasksDependingOnEnv(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalEnv": ["CI", "VERCEL_*"],
+		"pipeline": {
+			"build": {"env": ["DATABASE_URL", "NEXT_PUBLIC_*"]},
+			"web#build": {"env": ["NEXT_PUBLIC_*", "!NEXT_PUBLIC_DEBUG"]},
+			"test": {"env": ["DATABASE_*"]},
+			"lint": {}
+		}
+	}`)
+
+	testCases := []struct {
+		name       string
+		envVar     string
+		wantTasks  []string
+		wantGlobal bool
+	}{
+		{name: "direct match", envVar: "DATABASE_URL", wantTasks: []string{"build", "test"}},
+		{name: "wildcard match", envVar: "NEXT_PUBLIC_API_URL", wantTasks: []string{"build", "web#build"}},
+		{name: "negated", envVar: "NEXT_PUBLIC_DEBUG", wantTasks: []string{"build"}},
+		{name: "global match", envVar: "VERCEL_URL", wantTasks: []string{}, wantGlobal: true},
+		{name: "no match", envVar: "HOME", wantTasks: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tasks, global := turboJSON.TasksDependingOnEnv(tc.envVar)
+			assert.Equal(t, tc.wantTasks, tasks)
+			assert.Equal(t, tc.wantGlobal, global)
+		})
+	}
+}
+
+func Test_LoadTurboConfig_ExcludeScripts(t *testing.T) {
+	testDir := getTestDir(t, "exclude-scripts")
+	rootPackageJSON, err := ReadPackageJSON(testDir.UntypedJoin("package.json"))
+	assert.NoError(t, err)
+
+	turboJSON, _, err := LoadTurboConfig(testDir, rootPackageJSON, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"postinstall", "prepare"}, turboJSON.ExcludeScripts)
+	assert.Equal(t, map[string][]string{"//": {"//#build", "//#lint"}}, turboJSON.Pipeline.PackageTasks())
+}
+
+func Test_TaskDefinitionNormalize(t *testing.T) {
+	taskDefinition := TaskDefinition{
+		Outputs:                 TaskOutputs{Inclusions: []string{"dist/**", ".next/**", "dist/**"}, Exclusions: []string{"dist/b/**", "dist/a/**"}},
+		EnvVarDependencies:      []string{"NODE_ENV", "CI", "NODE_ENV"},
+		TaskDependencies:        []string{"lint", "codegen", "lint"},
+		TopologicalDependencies: []string{"build", "build"},
+		Inputs:                  []string{"src/**", "package.json", "src/**"},
+		DotEnv:                  []string{".env.local", ".env"},
+	}
+	inclusions := taskDefinition.Outputs.Inclusions
+
+	taskDefinition.Normalize()
+	assert.Equal(t, TaskDefinition{
+		Outputs:                 TaskOutputs{Inclusions: []string{".next/**", "dist/**"}, Exclusions: []string{"dist/a/**", "dist/b/**"}},
+		EnvVarDependencies:      []string{"CI", "NODE_ENV"},
+		TaskDependencies:        []string{"codegen", "lint"},
+		TopologicalDependencies: []string{"build"},
+		Inputs:                  []string{"package.json", "src/**"},
+		DotEnv:                  []string{".env.local", ".env"},
+	}, taskDefinition)
+	// The original slices are not modified
+	assert.Equal(t, []string{"dist/**", ".next/**", "dist/**"}, inclusions)
+}
+
+func Test_MigrateEnvVarDependencies(t *testing.T) {
+	original := &TurboJSON{
+		GlobalDeps: []string{"$GLOBAL_VAR", "tsconfig.json"},
+		GlobalEnv:  []string{"CI"},
+		Pipeline: Pipeline{
+			"build": {
+				definedFields: util.SetFromStrings([]string{"TaskDependencies", "TopologicalDependencies"}),
+				TaskDefinition: TaskDefinition{
+					TaskDependencies:        []string{"$MY_VAR", "codegen"},
+					TopologicalDependencies: []string{"build"},
+					EnvVarDependencies:      []string{"NODE_ENV"},
+				},
+			},
+			"lint": {
+				TaskDefinition: TaskDefinition{TaskDependencies: []string{"codegen"}},
+			},
+		},
+	}
+
+	migrated := MigrateEnvVarDependencies(original)
+	assert.Equal(t, []string{"tsconfig.json"}, migrated.GlobalDeps)
+	assert.Equal(t, []string{"CI", "GLOBAL_VAR"}, migrated.GlobalEnv)
+
+	build := migrated.Pipeline["build"]
+	assert.Equal(t, []string{"codegen"}, build.TaskDefinition.TaskDependencies)
+	assert.Equal(t, []string{"build"}, build.TaskDefinition.TopologicalDependencies)
+	assert.Equal(t, []string{"MY_VAR", "NODE_ENV"}, build.TaskDefinition.EnvVarDependencies)
+	assert.True(t, build.hasField("EnvVarDependencies"))
+	assert.Equal(t, original.Pipeline["lint"], migrated.Pipeline["lint"])
+
+	// The original is left as is
+	assert.Equal(t, []string{"$GLOBAL_VAR", "tsconfig.json"}, original.GlobalDeps)
+	assert.Equal(t, []string{"$MY_VAR", "codegen"}, original.Pipeline["build"].TaskDefinition.TaskDependencies)
+	assert.False(t, original.Pipeline["build"].hasField("EnvVarDependencies"))
+}
+
+func Test_PipelineMerge(t *testing.T) {
+	base := parseTurboJSON(t, `{"pipeline": {
+		"build": {"outputs": ["dist/**"], "dependsOn": ["^build"]},
+		"lint": {"inputs": ["src/**"], "cache": false}
+	}}`).Pipeline
+	child := parseTurboJSON(t, `{"pipeline": {
+		"build": {"env": ["NODE_ENV"]},
+		"lint": {"cache": true},
+		"test": {"dependsOn": ["build"]}
+	}}`).Pipeline
+
+	merged, err := base.Merge(child)
+	assert.NoError(t, err)
+	assert.Len(t, merged, 3)
+
+	// Disjoint keys are copied from both pipelines
+	assert.Equal(t, []string{"build"}, merged["test"].TaskDefinition.TaskDependencies)
+	assert.True(t, merged["test"].hasField("TaskDependencies"))
+
+	// Complementary fields are combined
+	build := merged["build"]
+	assert.Equal(t, []string{"dist/**"}, build.TaskDefinition.Outputs.Inclusions)
+	assert.Equal(t, []string{"build"}, build.TaskDefinition.TopologicalDependencies)
+	assert.Equal(t, []string{"NODE_ENV"}, build.TaskDefinition.EnvVarDependencies)
+	assert.Equal(t, []string{"EnvVarDependencies", "Outputs", "TopologicalDependencies"}, build.DefinedFields())
+
+	// Conflicting fields are taken from other
+	lint := merged["lint"]
+	assert.True(t, lint.TaskDefinition.ShouldCache)
+	assert.Equal(t, []string{"src/**"}, lint.TaskDefinition.Inputs)
+	assert.True(t, lint.hasField("ShouldCache"))
+	assert.True(t, lint.hasField("Inputs"))
+
+	// Neither input is modified
+	assert.False(t, base["build"].hasField("EnvVarDependencies"))
+	assert.False(t, base["lint"].TaskDefinition.ShouldCache)
+	assert.False(t, child.HasTask("build") && child["build"].hasField("Outputs"))
+}
+
+func Test_Weight(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {"weight": 4}, "lint": {}}}`)
+	build := turboJSON.Pipeline["build"]
+	assert.Equal(t, 4, build.TaskDefinition.Weight)
+	assert.True(t, build.hasField("Weight"))
+
+	lint := turboJSON.Pipeline["lint"]
+	assert.Equal(t, 1, lint.TaskDefinition.Weight)
+	assert.False(t, lint.hasField("Weight"))
+
+	serialized, err := json.Marshal(build.TaskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, 4, roundTripped.TaskDefinition.Weight)
+
+	// An inherited weight is kept unless it is overridden or removed
+	var override BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"weight": null}`), &override))
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{build, lint})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, merged.Weight)
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{build, override})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, merged.Weight)
+}
+
+func Test_Weight_Invalid(t *testing.T) {
+	testCases := []struct {
+		json     string
+		expected string
+	}{
+		{
+			json:     `{"pipeline": {"build": {"weight": 0}}}`,
+			expected: `task "build": invalid "weight" value 0: must be a positive integer`,
+		},
+		{
+			json:     `{"pipeline": {"web#build": {"weight": -2}}}`,
+			expected: `task "web#build": invalid "weight" value -2: must be a positive integer`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var turboJSON TurboJSON
+		err := json.Unmarshal([]byte(tc.json), &turboJSON)
+		assert.EqualError(t, err, tc.expected)
+	}
+}
+
+func Test_ExplicitInputs(t *testing.T) {
+	testCases := []struct {
+		name             string
+		task             string
+		expectedInputs   []string
+		expectedExplicit bool
+	}{
+		{
+			name:             "unset",
+			task:             `{}`,
+			expectedInputs:   nil,
+			expectedExplicit: false,
+		},
+		{
+			name:             "empty array",
+			task:             `{"inputs": []}`,
+			expectedInputs:   []string{},
+			expectedExplicit: true,
+		},
+		{
+			name:             "patterns",
+			task:             `{"inputs": ["src/**"]}`,
+			expectedInputs:   []string{"src/**"},
+			expectedExplicit: true,
+		},
+		{
+			name:             "null",
+			task:             `{"inputs": null}`,
+			expectedInputs:   nil,
+			expectedExplicit: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bookkeepingTaskDef BookkeepingTaskDefinition
+			assert.NoError(t, json.Unmarshal([]byte(tc.task), &bookkeepingTaskDef))
+			assert.EqualValues(t, tc.expectedInputs, bookkeepingTaskDef.TaskDefinition.Inputs)
+			assert.Equal(t, tc.expectedExplicit, bookkeepingTaskDef.TaskDefinition.ExplicitInputs)
+		})
+	}
+
+	// An explicit empty list is inherited like any other inputs, and null removes it
+	var base, override, unrelated BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"inputs": []}`), &base))
+	assert.NoError(t, json.Unmarshal([]byte(`{"inputs": null}`), &override))
+	assert.NoError(t, json.Unmarshal([]byte(`{"persistent": true}`), &unrelated))
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, unrelated})
+	assert.NoError(t, err)
+	assert.True(t, merged.ExplicitInputs)
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base, override})
+	assert.NoError(t, err)
+	assert.False(t, merged.ExplicitInputs)
+}
+
+func Test_GetTaskAndGetTaskDefinition(t *testing.T) {
+	pipeline := parseTurboJSON(t, `{"pipeline": {
+		"build": {"outputs": ["dist/**"]},
+		"web#build": {"outputs": [".next/**"]},
+		"//#format": {"cache": false}
+	}}`).Pipeline
+
+	testCases := []struct {
+		taskID          string
+		expectedOutputs []string
+		expectedFound   bool
+	}{
+		{taskID: "web#build", expectedOutputs: []string{".next/**"}, expectedFound: true},
+		{taskID: "docs#build", expectedOutputs: []string{"dist/**"}, expectedFound: true},
+		{taskID: "build", expectedOutputs: []string{"dist/**"}, expectedFound: true},
+		{taskID: "//#format", expectedFound: true},
+		{taskID: "web#format", expectedFound: false},
+		{taskID: "lint", expectedFound: false},
+		{taskID: "web#lint", expectedFound: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.taskID, func(t *testing.T) {
+			taskDefinition, found := pipeline.GetTaskDefinition(tc.taskID)
+			bookkeepingTaskDef, err := pipeline.GetTaskByID(tc.taskID)
+			// GetTask still takes the name of the task, but resolves the same way
+			_, taskName, _ := pipeline.SplitKey(tc.taskID)
+			legacyTaskDef, legacyErr := pipeline.GetTask(tc.taskID, taskName)
+			assert.Equal(t, tc.expectedFound, found)
+			if !tc.expectedFound {
+				assert.EqualError(t, err, fmt.Sprintf("Could not find task \"%s\" in pipeline", tc.taskID))
+				assert.EqualError(t, legacyErr, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, legacyErr)
+			assert.EqualValues(t, tc.expectedOutputs, taskDefinition.Outputs.Inclusions)
+			assert.True(t, taskDefinition.Equal(bookkeepingTaskDef.TaskDefinition))
+			assert.True(t, taskDefinition.Equal(legacyTaskDef.TaskDefinition))
+		})
+	}
+}
+
+func Test_ReadTurboConfigFromReader(t *testing.T) {
+	turboJSONPath := getTestDir(t, "correct").UntypedJoin(configFile)
+	contents, err := turboJSONPath.ReadFile()
+	assert.NoError(t, err)
+
+	fromFile, err := readTurboConfig(turboJSONPath, ParseOptions{})
+	assert.NoError(t, err)
+	fromReader, err := ReadTurboConfigFromReader(strings.NewReader(string(contents)))
+	assert.NoError(t, err)
+	assert.True(t, reflect.DeepEqual(fromFile, fromReader))
+
+	_, err = ReadTurboConfigFromReader(strings.NewReader(`{"pipeline": {"build": {"outputs": "dist"}}}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), configFile+":1:")
+}
+
+func Test_RootRelativeOutputs(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"test": {"outputs": [
+		"coverage/**",
+		"$TURBO_ROOT$/coverage/**",
+		"!$TURBO_ROOT$/coverage/tmp/**",
+		"!coverage/tmp/**"
+	]}}}`)
+	outputs := turboJSON.Pipeline["test"].TaskDefinition.Outputs
+	assert.EqualValues(t, []string{"coverage/**"}, outputs.Inclusions)
+	assert.EqualValues(t, []string{"coverage/tmp/**"}, outputs.Exclusions)
+	assert.EqualValues(t, []string{"coverage/**"}, outputs.RootInclusions)
+	assert.EqualValues(t, []string{"coverage/tmp/**"}, outputs.RootExclusions)
+
+	serialized, err := json.Marshal(turboJSON.Pipeline["test"].TaskDefinition)
+	assert.NoError(t, err)
+	assert.Contains(t, string(serialized), `"outputs":["!$TURBO_ROOT$/coverage/tmp/**","!coverage/tmp/**","$TURBO_ROOT$/coverage/**","coverage/**"]`)
+
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.True(t, turboJSON.Pipeline["test"].TaskDefinition.Equal(roundTripped.TaskDefinition))
+	assert.EqualValues(t, outputs, roundTripped.TaskDefinition.Outputs)
+
+	// Package and root relative outputs with the same glob are different outputs
+	packageOnly := parseTurboJSON(t, `{"pipeline": {"test": {"outputs": ["coverage/**"]}}}`)
+	assert.False(t, packageOnly.Pipeline["test"].TaskDefinition.Equal(parseTurboJSON(t, `{"pipeline": {"test": {"outputs": ["$TURBO_ROOT$/coverage/**"]}}}`).Pipeline["test"].TaskDefinition))
+}
+
+func Test_IsCacheable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		task     string
+		expected bool
+	}{
+		{name: "defaults", task: `{}`, expected: true},
+		{name: "cache enabled", task: `{"cache": true, "outputs": ["dist/**"]}`, expected: true},
+		{name: "only remote caching", task: `{"cache": {"local": false}}`, expected: true},
+		{name: "cache disabled", task: `{"cache": false}`, expected: false},
+		{name: "persistent", task: `{"persistent": true}`, expected: false},
+		{name: "interactive", task: `{"interactive": true}`, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {"task": `+tc.task+`}}`)
+			merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{turboJSON.Pipeline["task"]})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, merged.IsCacheable())
+		})
+	}
+}
+
+func Test_CacheKey(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {}, "test": {"cacheKey": "node-20"}}}`)
+	assert.Equal(t, "", turboJSON.Pipeline["build"].TaskDefinition.CacheKey)
+	assert.False(t, turboJSON.Pipeline["build"].hasField("CacheKey"))
+	assert.Equal(t, "node-20", turboJSON.Pipeline["test"].TaskDefinition.CacheKey)
+	assert.True(t, turboJSON.Pipeline["test"].hasField("CacheKey"))
+
+	serialized, err := json.Marshal(turboJSON)
+	assert.NoError(t, err)
+	roundTripped := parseTurboJSON(t, string(serialized))
+	assert.Equal(t, "", roundTripped.Pipeline["build"].TaskDefinition.CacheKey)
+	assert.Equal(t, "node-20", roundTripped.Pipeline["test"].TaskDefinition.CacheKey)
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{turboJSON.Pipeline["test"], turboJSON.Pipeline["build"]})
+	assert.NoError(t, err)
+	assert.Equal(t, "node-20", merged.CacheKey)
+}
+
+func Test_DuplicatePipelineKeys(t *testing.T) {
+	testCases := []struct {
+		name        string
+		turboJSON   string
+		expectedErr string
+	}{
+		{
+			name: "duplicate task",
+			turboJSON: `{
+				// build the app
+				"pipeline": {
+					"build": {"outputs": ["dist/**"]},
+					"test": {},
+					"build": {"outputs": ["lib/**"]}
+				}
+			}`,
+			expectedErr: `task "build" is configured more than once in "pipeline"`,
+		},
+		{
+			name:        "same key in different tasks",
+			turboJSON:   `{"pipeline": {"build": {"cache": false}, "test": {"cache": false}}}`,
+			expectedErr: "",
+		},
+		{
+			name:        "duplicate key outside the pipeline",
+			turboJSON:   `{"globalEnv": ["CI"], "pipeline": {"build": {}}, "globalEnv": ["CI"]}`,
+			expectedErr: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON, err := ReadTurboConfigFromReader(strings.NewReader(tc.turboJSON))
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.NotNil(t, turboJSON)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func Test_PipelineSortedTaskIDs(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"test": {}, "web#build": {}, "//#format": {}, "build": {}, "lint": {}}}`)
+	expected := []string{"//#format", "build", "lint", "test", "web#build"}
+	for i := 0; i < 10; i++ {
+		assert.EqualValues(t, expected, turboJSON.Pipeline.SortedTaskIDs())
+	}
+
+	assert.EqualValues(t, []string{}, Pipeline{}.SortedTaskIDs())
+}
+
+func Test_ResolveRemoteCacheOptions(t *testing.T) {
+	root := parseTurboJSON(t, `{"pipeline": {}, "remoteCache": {"teamId": "team_base", "apiUrl": "https://cache.example.com"}}`)
+	shared := parseTurboJSON(t, `{"extends": ["//"], "pipeline": {}}`)
+	workspace := parseTurboJSON(t, `{"extends": ["shared"], "pipeline": {}, "remoteCache": {"signature": true}}`)
+
+	resolved := workspace.ResolveRemoteCacheOptions([]*TurboJSON{root, shared, workspace})
+	assert.Equal(t, RemoteCacheOptions{TeamID: "team_base", Signature: true, APIURL: "https://cache.example.com"}, resolved)
+
+	// tj is merged last even if it is not part of the chain
+	assert.Equal(t, resolved, workspace.ResolveRemoteCacheOptions([]*TurboJSON{root, shared}))
+
+	// A field that the child sets overrides the base, even to its zero value
+	override := parseTurboJSON(t, `{"extends": ["//"], "pipeline": {}, "remoteCache": {"teamId": "team_child", "signature": false}}`)
+	root.RemoteCacheOptions.Signature = true
+	root.remoteCacheFields.Add("signature")
+	resolved = override.ResolveRemoteCacheOptions([]*TurboJSON{root})
+	assert.Equal(t, RemoteCacheOptions{TeamID: "team_child", Signature: false, APIURL: "https://cache.example.com"}, resolved)
+
+	// The configurations themselves are not modified
+	assert.Equal(t, RemoteCacheOptions{Signature: true}, workspace.RemoteCacheOptions)
+}
+
+func Test_AllGlobs(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalDependencies": ["tsconfig.json", ".env"],
+		"pipeline": {
+			"build": {"inputs": ["src/**", "package.json"], "outputs": ["dist/**", "!dist/cache/**"]},
+			"web#build": {"inputs": ["src/**", "next.config.js"], "outputs": ["dist/**", ".next/**", "!.next/cache/**"]},
+			"test": {"inputs": ["src/**", "test/**"], "outputs": ["$TURBO_ROOT$/coverage/**", "!dist/cache/**"]},
+			"lint": {}
+		}
+	}`)
+
+	inputs, outputs, globalDeps := turboJSON.AllGlobs()
+	assert.EqualValues(t, []string{"next.config.js", "package.json", "src/**", "test/**"}, inputs)
+	assert.EqualValues(t, []string{"!.next/cache/**", "!dist/cache/**", "$TURBO_ROOT$/coverage/**", ".next/**", "dist/**"}, outputs)
+	assert.EqualValues(t, []string{".env", "tsconfig.json"}, globalDeps)
+}
+
+func Test_SchemaKey(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {}}, "$schema": "https://turbo.build/schema.json"}`)
+	assert.Equal(t, "https://turbo.build/schema.json", turboJSON.Schema)
+
+	canonical, err := turboJSON.MarshalIndentCanonical()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(canonical), "{\n  \"$schema\": \"https://turbo.build/schema.json\",\n"))
+	roundTripped := parseTurboJSON(t, string(canonical))
+	assert.Equal(t, turboJSON.Schema, roundTripped.Schema)
+
+	// Strict parsing accepts it as well
+	turboJSONPath := turbopath.AbsoluteSystemPath(t.TempDir()).UntypedJoin(configFile)
+	assert.NoError(t, turboJSONPath.WriteFile(canonical, 0644))
+	_, err = readTurboConfig(turboJSONPath, ParseOptions{DisallowUnknownFields: true})
+	assert.NoError(t, err)
+
+	// Without "$schema" the key is omitted
+	serialized, err := json.Marshal(parseTurboJSON(t, `{"pipeline": {}}`))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(serialized), "$schema")
+}
+
+func Test_Overrides(t *testing.T) {
+	contents := `{
+		"pipeline": {
+			"build": {"outputs": ["dist/**"]},
+			"test": {"dependsOn": ["build"]}
+		},
+		"overrides": {
+			"ci": {
+				"build": {"cache": false},
+				"e2e": {"dependsOn": ["build"]}
+			}
+		}
+	}`
+	repoRoot := turbopath.AbsoluteSystemPath(t.TempDir())
+	assert.NoError(t, repoRoot.UntypedJoin(configFile).WriteFile([]byte(contents), 0644))
+	rootPackageJSON := &PackageJSON{}
+
+	// Without the condition, the base pipeline applies
+	turboJSON, _, err := LoadTurboConfig(repoRoot, rootPackageJSON, false)
+	assert.NoError(t, err)
+	assert.True(t, turboJSON.Pipeline["build"].TaskDefinition.ShouldCache)
+	assert.NotContains(t, turboJSON.Pipeline, "e2e")
+
+	turboJSON, err = LoadTurboConfigWithOptions(repoRoot, rootPackageJSON, false, ParseOptions{Conditions: []string{"local", "ci"}})
+	assert.NoError(t, err)
+	build := turboJSON.Pipeline["build"].TaskDefinition
+	assert.False(t, build.ShouldCache)
+	assert.EqualValues(t, []string{"dist/**"}, build.Outputs.Inclusions)
+	assert.EqualValues(t, []string{"build"}, turboJSON.Pipeline["e2e"].TaskDefinition.TaskDependencies)
+	assert.True(t, turboJSON.Pipeline["test"].TaskDefinition.ShouldCache)
+
+	// Overrides keep only the keys they set through a round trip
+	serialized, err := json.Marshal(parseTurboJSON(t, contents))
+	assert.NoError(t, err)
+	roundTripped := parseTurboJSON(t, string(serialized))
+	assert.NoError(t, roundTripped.ApplyOverrides([]string{"ci"}))
+	build = roundTripped.Pipeline["build"].TaskDefinition
+	assert.False(t, build.ShouldCache)
+	assert.EqualValues(t, []string{"dist/**"}, build.Outputs.Inclusions)
+}
+
+func Test_MergeTaskDefinitions_InheritedInputs(t *testing.T) {
+	root := parseTurboJSON(t, `{"pipeline": {"build": {"inputs": ["src/**", "package.json"], "dependsOn": ["^build"]}}}`)
+	shared := parseTurboJSON(t, `{"extends": ["//"], "pipeline": {"build": {"inputs": ["...", "tsconfig.json"]}}}`)
+
+	testCases := []struct {
+		name          string
+		inputs        string
+		expected      []string
+		defaultInputs bool
+	}{
+		{
+			name:     "add to inherited inputs",
+			inputs:   `["...", "codegen/**"]`,
+			expected: []string{"codegen/**", "package.json", "src/**", "tsconfig.json"},
+		},
+		{
+			name:          "add default inputs",
+			inputs:        `["$TURBO_DEFAULT$", "..."]`,
+			expected:      []string{"package.json", "src/**", "tsconfig.json"},
+			defaultInputs: true,
+		},
+		{
+			name:     "missing token replaces",
+			inputs:   `["codegen/**"]`,
+			expected: []string{"codegen/**"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workspace := parseTurboJSON(t, `{"extends": ["shared"], "pipeline": {"build": {"inputs": `+tc.inputs+`}}}`)
+			assert.NotContains(t, workspace.Pipeline["build"].TaskDefinition.Inputs, inheritedInputs)
+
+			resolved, err := workspace.ResolvePipeline([]*TurboJSON{root, shared, workspace})
+			assert.NoError(t, err)
+			build := resolved["build"]
+			assert.Equal(t, tc.expected, build.Inputs)
+			assert.Equal(t, tc.defaultInputs, build.DefaultInputs)
+			// dependsOn is inherited independently
+			assert.Equal(t, []string{"build"}, build.TopologicalDependencies)
+		})
+	}
+
+	// Pipeline.Merge keeps inheriting if the base didn't set inputs
+	merged, err := Pipeline{}.Merge(shared.Pipeline)
+	assert.NoError(t, err)
+	assert.True(t, merged["build"].inheritsInputs)
+	merged, err = root.Pipeline.Merge(shared.Pipeline)
+	assert.NoError(t, err)
+	assert.False(t, merged["build"].inheritsInputs)
+	assert.Equal(t, []string{"package.json", "src/**", "tsconfig.json"}, merged["build"].TaskDefinition.Inputs)
+}
+
+func Test_Retries(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"test": {"retries": 2}, "build": {}}}`)
+	test := turboJSON.Pipeline["test"]
+	assert.Equal(t, 2, test.TaskDefinition.Retries)
+	assert.True(t, test.hasField("Retries"))
+
+	build := turboJSON.Pipeline["build"]
+	assert.Equal(t, 0, build.TaskDefinition.Retries)
+	assert.False(t, build.hasField("Retries"))
+
+	serialized, err := json.Marshal(test.TaskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, 2, roundTripped.TaskDefinition.Retries)
+
+	// An inherited value is kept unless it is overridden, even with 0
+	var override BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"retries": 0}`), &override))
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{test, build})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, merged.Retries)
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{test, override})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, merged.Retries)
+
+	var invalid TurboJSON
+	err = json.Unmarshal([]byte(`{"pipeline": {"test": {"retries": -1}}}`), &invalid)
+	assert.EqualError(t, err, `task "test": invalid "retries" value -1: must not be negative`)
+}
+
+func Test_TaskDependencyGlobs(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"pipeline": {
+			"test:unit": {},
+			"test:e2e": {},
+			"test:all": {"dependsOn": ["test:*", "build"]},
+			"build": {},
+			"web#test:visual": {},
+			"ci": {"dependsOn": ["web#test:*"]}
+		}
+	}`)
+
+	testAll := turboJSON.Pipeline["test:all"].TaskDefinition
+	assert.Equal(t, []string{"build"}, testAll.TaskDependencies)
+	assert.Equal(t, []string{"test:*"}, testAll.TaskDependencyGlobs)
+	// The task itself is never matched, and bare globs only match the package tasks of
+	// the task's own package, by name
+	assert.Equal(t, []string{"build", "test:e2e", "test:unit"}, testAll.ResolveTaskDependencies("test:all", turboJSON.Pipeline))
+	assert.Equal(t, []string{"build", "test:e2e", "test:unit", "test:visual"}, testAll.ResolveTaskDependencies("web#test:all", turboJSON.Pipeline))
+	assert.Equal(t, []string{"build", "test:e2e", "test:unit"}, testAll.ResolveTaskDependencies("docs#test:all", turboJSON.Pipeline))
+
+	// In a single-package repository, every task is a root task
+	rootPipeline := Pipeline{}
+	for taskID, taskDefinition := range turboJSON.Pipeline {
+		if !util.IsPackageTask(taskID) {
+			rootPipeline[util.RootTaskID(taskID)] = taskDefinition
+		}
+	}
+	assert.Equal(t, []string{"build", "test:e2e", "test:unit"}, testAll.ResolveTaskDependencies("//#test:all", rootPipeline))
+
+	ci := turboJSON.Pipeline["ci"].TaskDefinition
+	assert.Equal(t, []string{"web#test:visual"}, ci.ResolveTaskDependencies("ci", turboJSON.Pipeline))
+
+	// Without globs, the dependencies are returned as declared
+	build := turboJSON.Pipeline["build"].TaskDefinition
+	assert.Empty(t, build.ResolveTaskDependencies("build", turboJSON.Pipeline))
+
+	serialized, err := json.Marshal(testAll)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.Equal(t, testAll.TaskDependencies, roundTripped.TaskDefinition.TaskDependencies)
+	assert.Equal(t, testAll.TaskDependencyGlobs, roundTripped.TaskDefinition.TaskDependencyGlobs)
+
+	var invalid TurboJSON
+	err = json.Unmarshal([]byte(`{"pipeline": {"test": {"dependsOn": ["test:["]}}}`), &invalid)
+	assert.EqualError(t, err, `task "test": invalid "dependsOn" value "test:[": syntax error in pattern`)
+}
+
+func Test_LoadLayeredTurboConfig(t *testing.T) {
+	testDir := getTestDir(t, "layered")
+
+	turboJSON, err := LoadLayeredTurboConfig(testDir, []string{"turbo.json", "turbo.local.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CI"}, turboJSON.GlobalEnv)
+	assert.Equal(t, "team_abc", turboJSON.RemoteCacheOptions.TeamID)
+	assert.False(t, *turboJSON.RemoteCacheOptions.Enabled)
+
+	assert.Equal(t, []string{"build", "dev", "test"}, turboJSON.Pipeline.TaskNames())
+	build := turboJSON.Pipeline["build"].TaskDefinition
+	assert.Equal(t, []string{".cache/**", "dist/**"}, build.Outputs.Inclusions)
+	assert.Equal(t, []string{"build"}, build.TopologicalDependencies)
+	assert.True(t, turboJSON.Pipeline["dev"].TaskDefinition.Persistent)
+
+	// Later files win, and missing files are skipped
+	reversed, err := LoadLayeredTurboConfig(testDir, []string{"turbo.local.json", "turbo.missing.json", "turbo.json"})
+	assert.NoError(t, err)
+	assert.True(t, *reversed.RemoteCacheOptions.Enabled)
+	assert.Equal(t, []string{"dist/**"}, reversed.Pipeline["build"].TaskDefinition.Outputs.Inclusions)
+
+	baseOnly, err := LoadLayeredTurboConfig(testDir, []string{"turbo.json", "turbo.missing.json"})
+	assert.NoError(t, err)
+	assert.True(t, *baseOnly.RemoteCacheOptions.Enabled)
+
+	_, err = LoadLayeredTurboConfig(testDir, []string{"turbo.missing.json"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_WithCachingDisabled(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalEnv": ["CI"],
+		"pipeline": {
+			"build": {"outputs": ["dist/**"]},
+			"test": {"cache": {"local": true, "remote": false}},
+			"dev": {"cache": false, "persistent": true},
+			"lint": {"cache": null}
+		},
+		"overrides": {
+			"ci": {"build": {"cache": true}}
+		}
+	}`)
+
+	disabled := turboJSON.WithCachingDisabled()
+	for _, taskID := range disabled.Pipeline.SortedTaskIDs() {
+		task := disabled.Pipeline[taskID]
+		assert.False(t, task.TaskDefinition.IsCacheable(), taskID)
+		assert.Equal(t, CacheConfig{Local: false, Remote: false}, task.TaskDefinition.Cache, taskID)
+		assert.True(t, task.hasField("ShouldCache"), taskID)
+	}
+	assert.NoError(t, disabled.ApplyOverrides([]string{"ci"}))
+	assert.False(t, disabled.Pipeline["build"].TaskDefinition.ShouldCache)
+
+	serialized, err := json.Marshal(disabled.Pipeline["build"].TaskDefinition)
+	assert.NoError(t, err)
+	assert.Contains(t, string(serialized), `"cache":false`)
+
+	// The original is unchanged
+	assert.True(t, turboJSON.Pipeline["build"].TaskDefinition.IsCacheable())
+	assert.False(t, turboJSON.Pipeline["build"].hasField("ShouldCache"))
+	assert.Equal(t, CacheConfig{Local: true, Remote: false}, turboJSON.Pipeline["test"].TaskDefinition.Cache)
+	assert.True(t, turboJSON.Overrides["ci"]["build"].TaskDefinition.ShouldCache)
+	assert.Equal(t, []string{"CI"}, disabled.GlobalEnv)
+}
+
+func Test_RawTaskJSON(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {"dependsOn": ["^build", "codegen"], "outputs": ["dist/**", "!dist/cache/**"], "env": ["NODE_ENV"], "timeout": "5m"},
+		"web#build": {"cache": {"remote": false}, "inputs": ["src/**"]},
+		"codegen": {}
+	}}`)
+
+	for _, taskID := range []string{"build", "web#build", "docs#build"} {
+		data, err := turboJSON.Pipeline.RawTaskJSON(taskID)
+		assert.NoError(t, err, taskID)
+		var roundTripped BookkeepingTaskDefinition
+		assert.NoError(t, json.Unmarshal(data, &roundTripped), taskID)
+		expected, err := turboJSON.Pipeline.GetTaskByID(taskID)
+		assert.NoError(t, err, taskID)
+		assert.True(t, expected.TaskDefinition.Equal(roundTripped.TaskDefinition), string(data))
+	}
+
+	_, err := turboJSON.Pipeline.RawTaskJSON("deploy")
+	assert.EqualError(t, err, `Could not find task "deploy" in pipeline`)
+}
+
+func Test_RemoteCacheOptionsEnvInterpolation(t *testing.T) {
+	testDir := getTestDir(t, "remote-cache-env")
+	rootPackageJSON := &PackageJSON{}
+	env := map[string]string{"VERCEL_TEAM_ID": "team_abc", "CACHE_HOST": "cache.example.com"}
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	turboJSON, err := LoadTurboConfigWithOptions(testDir, rootPackageJSON, false, ParseOptions{LookupEnv: lookupEnv})
+	assert.NoError(t, err)
+	assert.Equal(t, "team_abc", turboJSON.RemoteCacheOptions.TeamID)
+	assert.Equal(t, "https://cache.example.com/v8", turboJSON.RemoteCacheOptions.APIURL)
+
+	// Without a lookup function, the values are kept as written
+	literal, _, err := LoadTurboConfig(testDir, rootPackageJSON, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "$VERCEL_TEAM_ID", literal.RemoteCacheOptions.TeamID)
+
+	// Values without references are not changed
+	plain := parseTurboJSON(t, `{"remoteCache": {"teamId": "team_xyz", "apiUrl": "https://api.vercel.com"}}`)
+	assert.NoError(t, plain.interpolateRemoteCacheOptions(lookupEnv))
+	assert.Equal(t, "team_xyz", plain.RemoteCacheOptions.TeamID)
+	assert.Equal(t, "https://api.vercel.com", plain.RemoteCacheOptions.APIURL)
+
+	delete(env, "CACHE_HOST")
+	_, err = LoadTurboConfigWithOptions(testDir, rootPackageJSON, false, ParseOptions{LookupEnv: lookupEnv})
+	assert.EqualError(t, err, `"remoteCache.apiUrl" references $CACHE_HOST, which is not set`)
+}
+
+func Test_PipelineSplitKey(t *testing.T) {
+	testCases := []struct {
+		key             string
+		pkg             string
+		task            string
+		isPackageScoped bool
+	}{
+		{key: "web#build", pkg: "web", task: "build", isPackageScoped: true},
+		{key: "//#lint", pkg: "//", task: "lint", isPackageScoped: true},
+		{key: "build", pkg: "", task: "build", isPackageScoped: false},
+		{key: "#build", pkg: "", task: "#build", isPackageScoped: false},
+		{key: "web#", pkg: "web", task: "", isPackageScoped: true},
+		{key: "", pkg: "", task: "", isPackageScoped: false},
+	}
+
+	pipeline := Pipeline{}
+	for _, tc := range testCases {
+		pkg, task, isPackageScoped := pipeline.SplitKey(tc.key)
+		assert.Equal(t, tc.pkg, pkg, tc.key)
+		assert.Equal(t, tc.task, task, tc.key)
+		assert.Equal(t, tc.isPackageScoped, isPackageScoped, tc.key)
+	}
+
+	turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {}, "web#test": {}, "#lint": {}}}`)
+	assert.True(t, turboJSON.Pipeline.HasTask("test"))
+	assert.False(t, turboJSON.Pipeline.HasTask("lint"))
+	_, ok := turboJSON.Pipeline.GetTaskDefinition("docs#build")
+	assert.True(t, ok)
+	_, ok = turboJSON.Pipeline.GetTaskDefinition("#build")
+	assert.False(t, ok)
+}
+
+func Test_MarshalMinimal(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{
+		"globalEnv": ["CI"],
+		"pipeline": {
+			"build": {"dependsOn": ["^build"], "outputs": ["dist/**"]},
+			"test": {"outputs": [], "env": [], "cache": false},
+			"lint": {},
+			"web#build": {"dependsOn": ["...", "codegen"], "inputs": ["...", "src/**"], "outputMode": null}
+		}
+	}`)
+
+	data, err := turboJSON.MarshalMinimal()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"globalEnv": ["CI"],
+		"pipeline": {
+			"build": {"dependsOn": ["^build"], "outputs": ["dist/**"]},
+			"test": {"outputs": [], "env": [], "cache": false},
+			"lint": {},
+			"web#build": {"dependsOn": ["...", "codegen"], "inputs": ["...", "src/**"], "outputMode": null}
+		}
+	}`, string(data))
+
+	// The minimal form parses back to the same configuration
+	var roundTripped TurboJSON
+	assert.NoError(t, json.Unmarshal(data, &roundTripped))
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		assert.True(t, turboJSON.Pipeline[taskID].TaskDefinition.Equal(roundTripped.Pipeline[taskID].TaskDefinition), taskID)
+		assert.Equal(t, turboJSON.Pipeline[taskID].definedFields, roundTripped.Pipeline[taskID].definedFields, taskID)
+	}
+
+	// Top-level modes stay at the top level instead of being written into every task
+	turboJSON = parseTurboJSON(t, `{
+		"outputMode": "new-only",
+		"envMode": "strict",
+		"pipeline": {
+			"build": {"outputs": ["dist/**"]},
+			"dev": {"outputMode": "full", "envMode": "loose"}
+		}
+	}`)
+	data, err = turboJSON.MarshalMinimal()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"outputMode": "new-only",
+		"envMode": "strict",
+		"pipeline": {
+			"build": {"outputs": ["dist/**"]},
+			"dev": {"outputMode": "full", "envMode": "loose"}
+		}
+	}`, string(data))
+	roundTripped = TurboJSON{}
+	assert.NoError(t, json.Unmarshal(data, &roundTripped))
+	assert.Equal(t, util.LooseEnvMode, roundTripped.Pipeline["dev"].TaskDefinition.EnvMode)
+	assert.Equal(t, util.StrictEnvMode, roundTripped.Pipeline["build"].TaskDefinition.EnvMode)
+
+	// Programmatic edits are reflected, and keys that weren't set stay out
+	build, err := NewTaskDefinition().WithOutputs("dist/**").WithCache(false).Build()
+	assert.NoError(t, err)
+	data, err = build.MarshalMinimal()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"outputs": ["dist/**"], "cache": false}`, string(data))
+}
+
+func Test_OutputsExclude(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {
+			"outputs": ["dist/**", "!dist/cache/**", "$TURBO_ROOT$/coverage/**"],
+			"outputsExclude": ["dist/cache/**", "dist/tmp/**", "$TURBO_ROOT$/coverage/tmp/**"]
+		},
+		"lint": {"outputsExclude": [".eslintcache"]}
+	}}`)
+
+	build := turboJSON.Pipeline["build"]
+	assert.Equal(t, TaskOutputs{
+		Inclusions:     []string{"dist/**"},
+		Exclusions:     []string{"dist/cache/**", "dist/tmp/**"},
+		RootInclusions: []string{"coverage/**"},
+		RootExclusions: []string{"coverage/tmp/**"},
+	}, build.TaskDefinition.Outputs)
+	assert.True(t, turboJSON.Pipeline["lint"].hasField("Outputs"))
+	assert.Equal(t, []string{".eslintcache"}, turboJSON.Pipeline["lint"].TaskDefinition.Outputs.Exclusions)
+
+	// Marshaling uses the "!" form in "outputs"
+	serialized, err := json.Marshal(build.TaskDefinition)
+	assert.NoError(t, err)
+	raw := map[string]json.RawMessage{}
+	assert.NoError(t, json.Unmarshal(serialized, &raw))
+	assert.JSONEq(t, `["!$TURBO_ROOT$/coverage/tmp/**", "!dist/cache/**", "!dist/tmp/**", "$TURBO_ROOT$/coverage/**", "dist/**"]`, string(raw["outputs"]))
+	assert.NotContains(t, raw, "outputsExclude")
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.True(t, build.TaskDefinition.Equal(roundTripped.TaskDefinition))
+
+	var invalid TurboJSON
+	err = json.Unmarshal([]byte(`{"pipeline": {"build": {"outputsExclude": ["!dist/cache/**"]}}}`), &invalid)
+	assert.EqualError(t, err, `task "build": invalid "outputsExclude" value "!dist/cache/**": globs are already excluded, remove the "!"`)
+}
+
+func Test_LoadTurboConfig_Warnings(t *testing.T) {
+	testDir := getTestDir(t, "deprecated-env-dependency")
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	turboJSON, warnings, err := LoadTurboConfig(testDir, &PackageJSON{}, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"MY_VAR"}, turboJSON.Pipeline["build"].TaskDefinition.EnvVarDependencies)
+	assert.Contains(t, warnings, "task \"build\": Declaring an environment variable in \"dependsOn\" is deprecated, found $MY_VAR. Use the \"env\" key or use `npx @turbo/codemod migrate-env-var-dependencies`.")
+	assert.EqualValues(t, warnings, turboJSON.Warnings())
+	// The warnings are left to the caller to report
+	assert.Empty(t, logs.String())
+
+	_, err = LoadTurboConfigWithOptions(testDir, &PackageJSON{}, false, ParseOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "[WARNING] task \"build\": Declaring an environment variable in \"dependsOn\" is deprecated, found $MY_VAR.")
+}
+
+func Test_LoadTurboConfig_Null(t *testing.T) {
+	repoRoot := turbopath.AbsoluteSystemPath(t.TempDir())
+	assert.NoError(t, repoRoot.UntypedJoin(configFile).WriteFile([]byte("null"), 0644))
+
+	turboJSON, warnings, err := LoadTurboConfig(repoRoot, &PackageJSON{}, false)
+	assert.NoError(t, err)
+	assert.Nil(t, turboJSON)
+	assert.Empty(t, warnings)
+}
+
+func Test_ExplicitOutputs(t *testing.T) {
+	testCases := []struct {
+		name             string
+		task             string
+		expectedOutputs  []string
+		expectedExplicit bool
+	}{
+		{
+			name:             "unset",
+			task:             `{}`,
+			expectedOutputs:  []string{},
+			expectedExplicit: false,
+		},
+		{
+			name:             "empty array",
+			task:             `{"outputs": []}`,
+			expectedOutputs:  []string{},
+			expectedExplicit: true,
+		},
+		{
+			name:             "patterns",
+			task:             `{"outputs": ["dist/**"]}`,
+			expectedOutputs:  []string{"dist/**"},
+			expectedExplicit: true,
+		},
+		{
+			name:             "null",
+			task:             `{"outputs": null}`,
+			expectedOutputs:  []string{},
+			expectedExplicit: false,
+		},
+		{
+			name:             "only exclusions",
+			task:             `{"outputsExclude": ["dist/cache/**"]}`,
+			expectedOutputs:  []string{"!dist/cache/**"},
+			expectedExplicit: false,
+		},
+	}
+
+	// The globs are the same for unset and empty outputs, only ExplicitOutputs tells
+	// them apart
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bookkeepingTaskDef BookkeepingTaskDefinition
+			assert.NoError(t, json.Unmarshal([]byte(tc.task), &bookkeepingTaskDef))
+			assert.EqualValues(t, tc.expectedOutputs, bookkeepingTaskDef.TaskDefinition.Outputs.Globs())
+			assert.Equal(t, tc.expectedExplicit, bookkeepingTaskDef.TaskDefinition.ExplicitOutputs)
+			// Logs are cached either way
+			assert.True(t, bookkeepingTaskDef.TaskDefinition.ShouldCache)
+		})
+	}
+
+	// An explicit empty list is inherited like any other outputs, and null removes it
+	var base, override, unrelated BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputs": []}`), &base))
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputs": null}`), &override))
+	assert.NoError(t, json.Unmarshal([]byte(`{"persistent": true}`), &unrelated))
+
+	merged, err := MergeTaskDefinitions([]BookkeepingTaskDefinition{base, unrelated})
+	assert.NoError(t, err)
+	assert.True(t, merged.ExplicitOutputs)
+	merged, err = MergeTaskDefinitions([]BookkeepingTaskDefinition{base, override})
+	assert.NoError(t, err)
+	assert.False(t, merged.ExplicitOutputs)
+}
+
+func Test_LoadTurboConfig_StrictRemoteCacheOptions(t *testing.T) {
+	repoRoot := turbopath.AbsoluteSystemPath(t.TempDir())
+	assert.NoError(t, repoRoot.UntypedJoin(configFile).WriteFile([]byte(`{"remoteCache": {"teamId": "$TEAM_ID", "signature": true}, "pipeline": {}}`), 0644))
+	lookupEnv := func(key string) (string, bool) {
+		if key == "TEAM_ID" {
+			return "abc123", true
+		}
+		return "", false
+	}
+
+	// Without strict, the problem is left to the caller
+	turboJSON, err := LoadTurboConfigWithOptions(repoRoot, &PackageJSON{}, false, ParseOptions{LookupEnv: lookupEnv})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", turboJSON.RemoteCacheOptions.TeamID)
+
+	// The resolved value is what is checked
+	_, err = LoadTurboConfigWithOptions(repoRoot, &PackageJSON{}, false, ParseOptions{LookupEnv: lookupEnv, StrictRemoteCacheOptions: true})
+	assert.EqualError(t, err, "turbo.json: \"abc123\" is not a valid \"remoteCache.teamId\", team ids start with \"team_\"")
+
+	valid := func(key string) (string, bool) { return "team_abc123", true }
+	_, err = LoadTurboConfigWithOptions(repoRoot, &PackageJSON{}, false, ParseOptions{LookupEnv: valid, StrictRemoteCacheOptions: true})
+	assert.NoError(t, err)
+}
+
+func Test_TaskDefinitionHash_EqualDefinitions(t *testing.T) {
+	var unset, empty BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{}`), &unset))
+	assert.NoError(t, json.Unmarshal([]byte(`{"inputs": []}`), &empty))
+	assert.True(t, empty.TaskDefinition.ExplicitInputs)
+	assert.True(t, unset.TaskDefinition.Equal(empty.TaskDefinition))
+
+	unsetHash, err := unset.TaskDefinition.Hash()
+	assert.NoError(t, err)
+	emptyHash, err := empty.TaskDefinition.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, unsetHash, emptyHash)
+
+	// An explicitly empty outputs list hashes like unset outputs
+	var emptyOutputs BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal([]byte(`{"outputs": []}`), &emptyOutputs))
+	assert.True(t, emptyOutputs.TaskDefinition.ExplicitOutputs)
+	assert.True(t, unset.TaskDefinition.Equal(emptyOutputs.TaskDefinition))
+	emptyOutputsHash, err := emptyOutputs.TaskDefinition.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, unsetHash, emptyOutputsHash)
+
+	// The hash survives a round trip, which always writes "inputs" and "outputs"
+	serialized, err := json.Marshal(unset.TaskDefinition)
+	assert.NoError(t, err)
+	var roundTripped BookkeepingTaskDefinition
+	assert.NoError(t, json.Unmarshal(serialized, &roundTripped))
+	assert.True(t, unset.TaskDefinition.Equal(roundTripped.TaskDefinition))
+	roundTrippedHash, err := roundTripped.TaskDefinition.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, unsetHash, roundTrippedHash)
 }
 
 // Helpers
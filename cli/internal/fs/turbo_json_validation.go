@@ -0,0 +1,731 @@
+package fs
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vercel/turbo/cli/internal/doublestar"
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+// resolveDependency looks up the TaskDefinition for a dependency of taskID. Bare
+// dependencies of a package task (e.g. "dev" from "web#build") are looked up as
+// tasks in the same package first.
+func (pc Pipeline) resolveDependency(taskID string, dependency string) (TaskDefinition, bool) {
+	if util.IsPackageTask(dependency) {
+		return pc.GetTaskDefinition(dependency)
+	}
+	if util.IsPackageTask(taskID) {
+		pkg, _ := util.GetPackageTaskFromId(taskID)
+		if entry, ok := pc[util.GetTaskId(pkg, dependency)]; ok {
+			return entry.TaskDefinition, true
+		}
+	}
+	entry, ok := pc[dependency]
+	return entry.TaskDefinition, ok
+}
+
+// hasPersistentTask returns true if taskName is persistent in any workspace, either
+// for every workspace (e.g. "dev") or for a single one (e.g. "web#dev").
+func (pc Pipeline) hasPersistentTask(taskName string) bool {
+	for key, entry := range pc {
+		pkg, task, _ := pc.SplitKey(key)
+		// Root tasks don't run as a dependency of another workspace
+		if pkg != util.RootPkgName && task == taskName && entry.TaskDefinition.Persistent {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNoPersistentDependencies checks that no task depends on a persistent
+// task, since persistent tasks never exit and the dependent would never start.
+func ValidateNoPersistentDependencies(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+		// Persistent tasks are allowed to depend on each other, since neither is expected to exit
+		if taskDefinition.Persistent {
+			continue
+		}
+
+		dependencies := []string{}
+		// Dependencies that match a glob in dependsOn (e.g. "dev:*") are checked as well
+		for _, dependency := range taskDefinition.ResolveTaskDependencies(taskID, turboJSON.Pipeline) {
+			if depDefinition, ok := turboJSON.Pipeline.resolveDependency(taskID, dependency); ok && depDefinition.Persistent {
+				dependencies = append(dependencies, dependency)
+			}
+		}
+		for _, dependency := range taskDefinition.TopologicalDependencies {
+			if turboJSON.Pipeline.hasPersistentTask(dependency) {
+				dependencies = append(dependencies, topologicalPipelineDelimiter+dependency)
+			}
+		}
+		for _, dependency := range taskDefinition.RootTaskDependencies {
+			if depDefinition, ok := turboJSON.Pipeline.GetTaskDefinition(util.RootTaskID(dependency)); ok && depDefinition.Persistent {
+				dependencies = append(dependencies, util.RootPkgName+dependency)
+			}
+		}
+
+		for _, dependency := range dependencies {
+			errors = append(errors, fmt.Errorf("\"%s\" is a persistent task, \"%s\" cannot depend on it", dependency, taskID))
+		}
+	}
+
+	return errors
+}
+
+// hasDependency returns true if the pipeline has an entry that a (possibly
+// package-scoped) dependency would resolve to.
+func (pc Pipeline) hasDependency(dependency string) bool {
+	if util.IsPackageTask(dependency) {
+		if _, ok := pc[dependency]; ok {
+			return true
+		}
+		_, task := util.GetPackageTaskFromId(dependency)
+		_, ok := pc[task]
+		return ok
+	}
+	return pc.HasTask(dependency)
+}
+
+// ValidateDependenciesExist checks that every task in dependsOn is defined in
+// the pipeline, catching typos that would otherwise silently be ignored.
+func ValidateDependenciesExist(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+
+		missing := []string{}
+		for _, dependency := range taskDefinition.TaskDependencies {
+			if !turboJSON.Pipeline.hasDependency(dependency) {
+				missing = append(missing, dependency)
+			}
+		}
+		for _, dependency := range taskDefinition.TopologicalDependencies {
+			if !turboJSON.Pipeline.HasTask(dependency) {
+				missing = append(missing, topologicalPipelineDelimiter+dependency)
+			}
+		}
+		for _, dependency := range taskDefinition.RootTaskDependencies {
+			if !turboJSON.Pipeline.hasDependency(util.RootTaskID(dependency)) {
+				missing = append(missing, util.RootPkgName+dependency)
+			}
+		}
+
+		for _, dependency := range missing {
+			errors = append(errors, fmt.Errorf("\"%s\" depends on \"%s\", which is not defined in the pipeline", taskID, dependency))
+		}
+		for _, glob := range taskDefinition.TaskDependencyGlobs {
+			matches := TaskDefinition{TaskDependencyGlobs: []string{glob}}.ResolveTaskDependencies(taskID, turboJSON.Pipeline)
+			if len(matches) == 0 {
+				errors = append(errors, fmt.Errorf("\"%s\" depends on \"%s\", which does not match any task in the pipeline", taskID, glob))
+			}
+		}
+	}
+
+	return errors
+}
+
+// isSelfDependency returns true if dependency, from the dependsOn of taskID, refers to
+// taskID itself. Bare dependencies of a package task refer to the task in the same package.
+func isSelfDependency(taskID string, dependency string) bool {
+	if dependency == taskID {
+		return true
+	}
+	if util.IsPackageTask(taskID) && !util.IsPackageTask(dependency) {
+		pkg, _ := util.GetPackageTaskFromId(taskID)
+		return util.GetTaskId(pkg, dependency) == taskID
+	}
+	return false
+}
+
+// ValidateNoSelfDependency checks that no task depends on itself, which is always a
+// mistake. Topological dependencies (e.g. "^build") are on other packages, so they
+// don't count.
+func ValidateNoSelfDependency(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+
+		dependencies := []string{}
+		for _, dependency := range taskDefinition.TaskDependencies {
+			if isSelfDependency(taskID, dependency) {
+				dependencies = append(dependencies, dependency)
+			}
+		}
+		for _, dependency := range taskDefinition.RootTaskDependencies {
+			if util.RootTaskID(dependency) == taskID {
+				dependencies = append(dependencies, util.RootPkgName+dependency)
+			}
+		}
+
+		for _, dependency := range dependencies {
+			errors = append(errors, fmt.Errorf("\"%s\" depends on itself (\"%s\" in \"dependsOn\"), remove it", taskID, dependency))
+		}
+	}
+
+	return errors
+}
+
+// taskDependencyIDs returns the IDs of the tasks that taskID depends on directly.
+// Bare dependencies of a package task (e.g. "dev" from "web#build") are in the same
+// package. Topological dependencies run in other packages, so they are not included.
+func (pc Pipeline) taskDependencyIDs(taskID string, taskDefinition TaskDefinition) []string {
+	pkg, _, isPackageScoped := pc.SplitKey(taskID)
+	dependencies := []string{}
+	for _, dependency := range taskDefinition.ResolveTaskDependencies(taskID, pc) {
+		if isPackageScoped {
+			dependency = util.GetTaskId(pkg, dependency)
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	for _, dependency := range taskDefinition.RootTaskDependencies {
+		dependencies = append(dependencies, util.RootTaskID(dependency))
+	}
+	return dependencies
+}
+
+// ValidateNoCycles checks that no task transitively depends on itself (e.g.
+// "a" -> "b" -> "a"), which can never run. Each cycle is reported with its full path.
+// Only dependencies within a package are followed, since topological dependencies
+// (e.g. "^build") run in other packages. Direct self-dependencies are reported by
+// ValidateNoSelfDependency instead.
+func ValidateNoCycles(turboJSON *TurboJSON) []error {
+	errors := []error{}
+	pipeline := turboJSON.Pipeline
+
+	done := make(util.Set)
+	var visit func(taskID string, stack []string)
+	visit = func(taskID string, stack []string) {
+		for i, visiting := range stack {
+			if visiting == taskID {
+				cycle := append(append([]string{}, stack[i:]...), taskID)
+				errors = append(errors, fmt.Errorf("\"%s\" depends on itself through %s, remove one of these dependencies", taskID, strings.Join(quoted(cycle), " -> ")))
+				return
+			}
+		}
+		if done.Includes(taskID) {
+			return
+		}
+		bookkeepingTaskDef, ok := pipeline.resolveTask(taskID)
+		if !ok {
+			// Missing dependencies are reported by ValidateDependenciesExist
+			return
+		}
+
+		stack = append(stack, taskID)
+		for _, dependency := range pipeline.taskDependencyIDs(taskID, bookkeepingTaskDef.TaskDefinition) {
+			if dependency != taskID {
+				visit(dependency, stack)
+			}
+		}
+		done.Add(taskID)
+	}
+
+	for _, taskID := range pipeline.SortedTaskIDs() {
+		visit(taskID, []string{})
+	}
+
+	return errors
+}
+
+// quoted returns values, each in double quotes
+func quoted(values []string) []string {
+	quotedValues := make([]string, len(values))
+	for i, value := range values {
+		quotedValues[i] = fmt.Sprintf("\"%s\"", value)
+	}
+	return quotedValues
+}
+
+// escapesPackage returns true if glob is absolute, or resolves outside of the
+// directory it is relative to
+func escapesPackage(glob string) bool {
+	if filepath.IsAbs(glob) || path.IsAbs(filepath.ToSlash(glob)) {
+		return true
+	}
+	cleaned := path.Clean(filepath.ToSlash(glob))
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
+
+// ValidateOutputsWithinPackage checks that no outputs glob is absolute or
+// resolves outside of the package directory, since such outputs cannot be
+// cached correctly. Callers decide whether to treat these as errors or warnings.
+func ValidateOutputsWithinPackage(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		outputs := turboJSON.Pipeline[taskID].TaskDefinition.Outputs
+
+		globs := []string{}
+		for _, inclusion := range outputs.Inclusions {
+			if escapesPackage(inclusion) {
+				globs = append(globs, inclusion)
+			}
+		}
+		for _, exclusion := range outputs.Exclusions {
+			if escapesPackage(exclusion) {
+				globs = append(globs, "!"+exclusion)
+			}
+		}
+
+		for _, glob := range globs {
+			errors = append(errors, fmt.Errorf("\"%s\" in the \"outputs\" of \"%s\" is outside of the package directory", glob, taskID))
+		}
+	}
+
+	return errors
+}
+
+// nodeModulesFile is a path in node_modules that outputs globs are matched against to
+// check whether they include node_modules
+const nodeModulesFile = "node_modules/some-package/index.js"
+
+// matchesNodeModules returns true if any of globs matches a file in node_modules
+func matchesNodeModules(globs []string) bool {
+	for _, glob := range globs {
+		if matched, _ := doublestar.Match(glob, nodeModulesFile); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOutputsExcludeNodeModules checks that no "outputs" include node_modules,
+// either explicitly or with a glob that matches everything (e.g. "**"), unless it
+// is excluded again. Caching node_modules is almost always a mistake that bloats
+// the cache. This is informational, callers should only warn about it.
+func ValidateOutputsExcludeNodeModules(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		outputs := turboJSON.Pipeline[taskID].TaskDefinition.Outputs
+
+		globs := []string{}
+		if !matchesNodeModules(outputs.Exclusions) {
+			for _, inclusion := range outputs.Inclusions {
+				if matchesNodeModules([]string{inclusion}) {
+					globs = append(globs, inclusion)
+				}
+			}
+		}
+		if !matchesNodeModules(outputs.RootExclusions) {
+			for _, inclusion := range outputs.RootInclusions {
+				if matchesNodeModules([]string{inclusion}) {
+					globs = append(globs, turboRootPrefix+"/"+inclusion)
+				}
+			}
+		}
+
+		for _, glob := range globs {
+			errors = append(errors, fmt.Errorf("\"%s\" in the \"outputs\" of \"%s\" includes node_modules, which bloats the cache. Exclude it with \"!node_modules/**\"", glob, taskID))
+		}
+	}
+
+	return errors
+}
+
+// validateGlob returns an error if glob can't be compiled by the globber that expands
+// inputs and outputs when running tasks
+func validateGlob(glob string) error {
+	if !doublestar.ValidatePattern(filepath.ToSlash(glob)) {
+		return doublestar.ErrBadPattern
+	}
+	return nil
+}
+
+// ValidateGlobsCompile checks that every "globalDependencies", "inputs" and "outputs"
+// glob is valid syntax for the globber that turbo runs tasks with, so that a malformed
+// glob (e.g. "src/[") is reported when loading configFile instead of when hashing.
+func ValidateGlobsCompile(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, glob := range turboJSON.GlobalDeps {
+		if err := validateGlob(glob); err != nil {
+			errors = append(errors, fmt.Errorf("\"%s\" in \"globalDependencies\" is not a valid glob: %w", glob, err))
+		}
+	}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+
+		for _, glob := range taskDefinition.Inputs {
+			if err := validateGlob(glob); err != nil {
+				errors = append(errors, fmt.Errorf("\"%s\" in the \"inputs\" of \"%s\" is not a valid glob: %w", glob, taskID, err))
+			}
+		}
+
+		// Outputs are checked as they are declared, but without the "!" and
+		// turboRootPrefix, which turbo handles before globbing
+		for _, declared := range taskDefinition.Outputs.Globs() {
+			glob, _ := trimTurboRootPrefix(strings.TrimPrefix(declared, "!"))
+			if err := validateGlob(glob); err != nil {
+				errors = append(errors, fmt.Errorf("\"%s\" in the \"outputs\" of \"%s\" is not a valid glob: %w", declared, taskID, err))
+			}
+		}
+	}
+
+	return errors
+}
+
+// ValidatePersistentTaskConfig checks that persistent tasks do not configure
+// outputs or enable caching. Persistent tasks never finish, so they are never
+// cached and these keys are usually a mistake. Callers decide whether to treat
+// these as errors or warnings.
+func ValidatePersistentTaskConfig(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		bookkeepingTaskDef := turboJSON.Pipeline[taskID]
+		taskDefinition := bookkeepingTaskDef.TaskDefinition
+		if !taskDefinition.Persistent {
+			continue
+		}
+
+		if bookkeepingTaskDef.hasField("Outputs") && (len(taskDefinition.Outputs.Inclusions) > 0 || len(taskDefinition.Outputs.RootInclusions) > 0) {
+			errors = append(errors, fmt.Errorf("\"%s\" is a persistent task and will never be cached, remove \"outputs\" from it", taskID))
+		}
+		if bookkeepingTaskDef.hasField("ShouldCache") && taskDefinition.ShouldCache {
+			errors = append(errors, fmt.Errorf("\"%s\" is a persistent task and will never be cached, remove \"cache\" from it or set it to false", taskID))
+		}
+	}
+
+	return errors
+}
+
+// overlappingEnv returns the sorted env vars that are in both env and passThroughEnv
+func overlappingEnv(env []string, passThroughEnv []string) []string {
+	overlap := util.SetFromStrings(env).Intersection(util.SetFromStrings(passThroughEnv)).UnsafeListOfStrings()
+	sort.Strings(overlap)
+	return overlap
+}
+
+// ValidateEnvNoOverlap checks that no env var is declared both as affecting the
+// hash ("env") and as excluded from it ("passThroughEnv"), globally or per task.
+func ValidateEnvNoOverlap(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, envVar := range overlappingEnv(turboJSON.GlobalEnv, turboJSON.GlobalPassThroughEnv) {
+		errors = append(errors, fmt.Errorf("\"%s\" is declared in both \"globalEnv\" and \"globalPassThroughEnv\"", envVar))
+	}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+		for _, envVar := range overlappingEnv(taskDefinition.EnvVarDependencies, taskDefinition.PassThroughEnv) {
+			errors = append(errors, fmt.Errorf("\"%s\" is declared in both \"env\" and \"passThroughEnv\" of \"%s\"", envVar, taskID))
+		}
+	}
+
+	return errors
+}
+
+// recursiveGlobContains returns true if glob is a literal directory followed by
+// "/**" (e.g. "dist/**"), and other is that directory or a path inside of it.
+func recursiveGlobContains(glob string, other string) bool {
+	base := strings.TrimSuffix(glob, "/**")
+	if base == glob || strings.ContainsAny(base, "*?[{") {
+		return false
+	}
+	return other == base || strings.HasPrefix(other, base+"/")
+}
+
+// globsOverlap is a conservative check for whether two globs match some of the
+// same files: they are identical, or one is a recursive glob containing the other.
+func globsOverlap(a string, b string) bool {
+	a = path.Clean(filepath.ToSlash(a))
+	b = path.Clean(filepath.ToSlash(b))
+	return a == b || recursiveGlobContains(a, b) || recursiveGlobContains(b, a)
+}
+
+// ValidateInputsOutputsDisjoint checks that no outputs glob obviously overlaps with
+// an inputs glob of the same task. Otherwise the task's outputs become part of its
+// hash, and it will never hit the cache. This is a heuristic, so callers should
+// treat these as warnings.
+func ValidateInputsOutputsDisjoint(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+		for _, output := range taskDefinition.Outputs.Inclusions {
+			for _, input := range taskDefinition.Inputs {
+				if strings.HasPrefix(input, "!") {
+					continue
+				}
+				if globsOverlap(output, input) {
+					errors = append(errors, fmt.Errorf("\"%s\" in the \"outputs\" of \"%s\" overlaps with \"%s\" in its \"inputs\", so the task's outputs will change its hash", output, taskID, input))
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+// ValidateWithTasksExist checks that every task in "with" is defined in the pipeline
+func ValidateWithTasksExist(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		for _, sidecar := range turboJSON.Pipeline[taskID].TaskDefinition.With {
+			if !turboJSON.Pipeline.hasDependency(sidecar) {
+				errors = append(errors, fmt.Errorf("\"%s\" is started with \"%s\", which is not defined in the pipeline", taskID, sidecar))
+			}
+		}
+	}
+
+	return errors
+}
+
+// ValidateTaskNames checks that every pipeline key is either a bare task name or
+// a well-formed <package>#<task> ID. Malformed keys otherwise fail in confusing ways
+// when the task graph is built.
+func ValidateTaskNames(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		if taskID == "" {
+			errors = append(errors, fmt.Errorf("task names in the pipeline cannot be empty"))
+			continue
+		}
+		if strings.Count(taskID, util.TaskDelimiter) > 1 {
+			errors = append(errors, fmt.Errorf("\"%s\" is not a valid task name, it contains more than one \"%s\"", taskID, util.TaskDelimiter))
+			continue
+		}
+
+		taskName := taskID
+		if strings.Contains(taskID, util.TaskDelimiter) {
+			pkg, task := util.GetPackageTaskFromId(taskID)
+			if pkg == "" {
+				errors = append(errors, fmt.Errorf("\"%s\" is not a valid task name, it is missing a package name before \"%s\"", taskID, util.TaskDelimiter))
+				continue
+			}
+			if task == "" {
+				errors = append(errors, fmt.Errorf("\"%s\" is not a valid task name, it is missing a task name after \"%s\"", taskID, util.TaskDelimiter))
+				continue
+			}
+			taskName = task
+		}
+
+		for _, reserved := range []string{topologicalPipelineDelimiter, envPipelineDelimiter} {
+			if strings.HasPrefix(taskName, reserved) {
+				errors = append(errors, fmt.Errorf("\"%s\" is not a valid task name, task names cannot start with \"%s\"", taskID, reserved))
+			}
+		}
+	}
+
+	return errors
+}
+
+// teamIDPrefix is the prefix of every remote cache team id
+const teamIDPrefix = "team_"
+
+// ValidateRemoteCacheOptions checks that a well-formed remoteCache.teamId is set when
+// artifact signatures are enabled, since signing requires it. Otherwise this only fails
+// when the remote cache is used. Callers decide whether to treat these as errors or warnings,
+// and ParseOptions.StrictRemoteCacheOptions makes them an error when loading configFile.
+func ValidateRemoteCacheOptions(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	remoteCacheOptions := turboJSON.RemoteCacheOptions
+	if !remoteCacheOptions.Signature {
+		return errors
+	}
+
+	teamID := remoteCacheOptions.TeamID
+	if teamID == "" {
+		errors = append(errors, fmt.Errorf("\"remoteCache.signature\" is enabled, but \"remoteCache.teamId\" is not set"))
+	} else if !strings.HasPrefix(teamID, teamIDPrefix) || len(teamID) == len(teamIDPrefix) {
+		errors = append(errors, fmt.Errorf("\"%s\" is not a valid \"remoteCache.teamId\", team ids start with \"%s\"", teamID, teamIDPrefix))
+	}
+
+	return errors
+}
+
+// matchesWholeTree returns true if glob matches every file in the directory it is
+// relative to, e.g. "**" or "./**/*"
+func matchesWholeTree(glob string) bool {
+	cleaned := path.Clean(filepath.ToSlash(glob))
+	segments := strings.Split(cleaned, "/")
+	for i, segment := range segments {
+		if segment == "**" || (segment == "*" && i == len(segments)-1 && i > 0) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// ValidateGlobalDependenciesNotTooBroad checks that no globalDependencies glob matches the
+// whole repository, since then every change invalidates the cache of every task. This is
+// informational, callers should only warn about it.
+func ValidateGlobalDependenciesNotTooBroad(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, glob := range turboJSON.GlobalDeps {
+		if matchesWholeTree(glob) {
+			errors = append(errors, fmt.Errorf("\"%s\" in \"globalDependencies\" matches every file in the repository, so any change invalidates the cache of every task. Consider narrowing it to the files that affect all tasks (e.g. \"tsconfig.json\")", glob))
+		}
+	}
+
+	return errors
+}
+
+// ValidateCachedTasksHaveInputs checks that cached tasks with outputs declare their
+// "inputs". Otherwise every file in the package is hashed, which is slow and often
+// includes more than intended. This is informational, callers should only warn about
+// it, and repositories can opt out with "experimental.implicitInputsWarning".
+func ValidateCachedTasksHaveInputs(turboJSON *TurboJSON) []error {
+	errors := []error{}
+	if !turboJSON.ImplicitInputsWarning() {
+		return errors
+	}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		taskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+		hasOutputs := len(taskDefinition.Outputs.Inclusions) > 0 || len(taskDefinition.Outputs.RootInclusions) > 0
+		if taskDefinition.IsCacheable() && hasOutputs && !taskDefinition.ExplicitInputs {
+			errors = append(errors, fmt.Errorf("\"%s\" caches its \"outputs\" but does not declare its \"inputs\", so every file in its package is hashed. Declare \"inputs\" to hash it faster", taskID))
+		}
+	}
+
+	return errors
+}
+
+// describeCaching describes where the outputs of a task with cache are cached
+func describeCaching(cache CacheConfig) string {
+	switch {
+	case cache.Local && cache.Remote:
+		return "cached"
+	case cache.Local:
+		return "only cached locally"
+	case cache.Remote:
+		return "only cached remotely"
+	default:
+		return "not cached"
+	}
+}
+
+// ValidatePackageTaskCaching checks that package tasks (e.g. "web#build") are cached
+// the same way as the task of the same name (e.g. "build"), and keep its "outputs" if
+// both are cached. A package task replaces the task for its workspace rather than
+// extending it, so such differences are easy to miss. This is informational, callers
+// should only warn about it.
+func ValidatePackageTaskCaching(turboJSON *TurboJSON) []error {
+	errors := []error{}
+
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		pkg, taskName, isPackageScoped := turboJSON.Pipeline.SplitKey(taskID)
+		if !isPackageScoped {
+			continue
+		}
+		baseTask, ok := turboJSON.Pipeline[taskName]
+		if !ok {
+			continue
+		}
+		packageTaskDefinition := turboJSON.Pipeline[taskID].TaskDefinition
+		baseTaskDefinition := baseTask.TaskDefinition
+
+		if packageTaskDefinition.Cache != baseTaskDefinition.Cache {
+			errors = append(errors, fmt.Errorf("\"%s\" is %s, but \"%s\" is %s. \"%s\" replaces \"%s\" in the \"%s\" workspace", taskID, describeCaching(packageTaskDefinition.Cache), taskName, describeCaching(baseTaskDefinition.Cache), taskID, taskName, pkg))
+			continue
+		}
+		if packageTaskDefinition.ShouldCache && len(packageTaskDefinition.Outputs.Globs()) == 0 && len(baseTaskDefinition.Outputs.Globs()) > 0 {
+			errors = append(errors, fmt.Errorf("\"%s\" has no \"outputs\", but \"%s\" does. \"%s\" replaces \"%s\" in the \"%s\" workspace, so only its logs are cached", taskID, taskName, taskID, taskName, pkg))
+		}
+	}
+
+	return errors
+}
+
+// ValidateOutputModeValues checks that the default outputMode and the outputMode of
+// every task is one of util.TaskOutputModeStrings. Values parsed from configFile always
+// are, but a TurboJSON can also be built or modified in code.
+func ValidateOutputModeValues(turboJSON *TurboJSON) []error {
+	errors := []error{}
+	validValues := strings.Join(util.TaskOutputModeStrings, ", ")
+
+	if turboJSON.OutputMode != nil {
+		if _, err := util.ToTaskOutputModeString(*turboJSON.OutputMode); err != nil {
+			errors = append(errors, fmt.Errorf("the default \"outputMode\" (%d) is invalid. Valid values are: %s", *turboJSON.OutputMode, validValues))
+		}
+	}
+	for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+		outputMode := turboJSON.Pipeline[taskID].TaskDefinition.OutputMode
+		if _, err := util.ToTaskOutputModeString(outputMode); err != nil {
+			errors = append(errors, fmt.Errorf("the \"outputMode\" of \"%s\" (%d) is invalid. Valid values are: %s", taskID, outputMode, validValues))
+		}
+	}
+
+	return errors
+}
+
+// ValidateExtends returns a validation for the "extends" of the root configFile if isRoot
+// is true, or of a workspace's otherwise. There is nothing above the root configuration,
+// so it must not extend anything. Workspace configurations must extend the root
+// workspace, or they don't inherit its tasks.
+func ValidateExtends(isRoot bool) TurboJSONValidation {
+	return func(turboJSON *TurboJSON) []error {
+		errors := []error{}
+		if isRoot {
+			if len(turboJSON.Extends) > 0 {
+				errors = append(errors, fmt.Errorf("the root %s cannot extend other configurations, remove \"extends\"", configFile))
+			}
+			return errors
+		}
+
+		if !util.SetFromStrings(turboJSON.Extends).Includes(util.RootPkgName) {
+			errors = append(errors, fmt.Errorf("\"extends\" does not include the root workspace (\"%s\"), so the tasks in the root %s are not inherited", util.RootPkgName, configFile))
+		}
+		return errors
+	}
+}
+
+// ValidateTopologicalDependencies returns a validation that every "^" dependency names a
+// task that is defined in the pipeline. A dependency on a workspace (e.g. "^web") is a
+// common mistake, since "^" always refers to a task in the workspace's dependencies,
+// so entries that are one of packageNames get a hint instead.
+func ValidateTopologicalDependencies(packageNames []string) TurboJSONValidation {
+	packages := util.SetFromStrings(packageNames)
+	return func(turboJSON *TurboJSON) []error {
+		errors := []error{}
+
+		for _, taskID := range turboJSON.Pipeline.SortedTaskIDs() {
+			for _, dependency := range turboJSON.Pipeline[taskID].TaskDefinition.TopologicalDependencies {
+				if turboJSON.Pipeline.HasTask(dependency) {
+					continue
+				}
+				if packages.Includes(dependency) {
+					errors = append(errors, fmt.Errorf("\"%s\" depends on \"%s%s\", but \"%s\" is a workspace, not a task. \"%s\" must be followed by the name of a task in the workspace's dependencies, e.g. \"%sbuild\"", taskID, topologicalPipelineDelimiter, dependency, dependency, topologicalPipelineDelimiter, topologicalPipelineDelimiter))
+				} else {
+					errors = append(errors, fmt.Errorf("\"%s\" depends on \"%s%s\", which is not defined in the pipeline", taskID, topologicalPipelineDelimiter, dependency))
+				}
+			}
+		}
+
+		return errors
+	}
+}
+
+// DefaultValidations returns the checks that every turbo.json is expected to pass.
+// Each validation reports its errors in pipeline key order, and they are run in the
+// order listed here, so the combined errors from Validate are deterministic.
+func DefaultValidations() []TurboJSONValidation {
+	return []TurboJSONValidation{
+		ValidateTaskNames,
+		ValidateDependenciesExist,
+		ValidateNoSelfDependency,
+		ValidateNoCycles,
+		ValidateNoPersistentDependencies,
+		ValidateEnvNoOverlap,
+		ValidateOutputsWithinPackage,
+		ValidateGlobsCompile,
+		ValidateOutputModeValues,
+	}
+}
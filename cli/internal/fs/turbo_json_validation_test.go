@@ -0,0 +1,903 @@
+package fs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+// parseTurboJSON is a test helper that unmarshals a turbo.json string
+func parseTurboJSON(t *testing.T, data string) *TurboJSON {
+	t.Helper()
+	var turboJSON *TurboJSON
+	if err := json.Unmarshal([]byte(data), &turboJSON); err != nil {
+		t.Fatalf("invalid parse: %v", err)
+	}
+	return turboJSON
+}
+
+func errorMessages(errs []error) []string {
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func Test_ValidateNoPersistentDependencies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "direct dependency on a persistent task",
+			json: `{"pipeline": {
+				"dev": {"persistent": true},
+				"build": {"dependsOn": ["dev"]}
+			}}`,
+			expected: []string{"\"dev\" is a persistent task, \"build\" cannot depend on it"},
+		},
+		{
+			name: "topological dependency on a persistent task",
+			json: `{"pipeline": {
+				"dev": {"persistent": true},
+				"build": {"dependsOn": ["^dev"]}
+			}}`,
+			expected: []string{"\"^dev\" is a persistent task, \"build\" cannot depend on it"},
+		},
+		{
+			name: "topological dependency on a persistent package task",
+			json: `{"pipeline": {
+				"dev": {},
+				"ui#dev": {"persistent": true},
+				"build": {"dependsOn": ["^dev"]}
+			}}`,
+			expected: []string{"\"^dev\" is a persistent task, \"build\" cannot depend on it"},
+		},
+		{
+			name: "glob dependency on a persistent task",
+			json: `{"pipeline": {
+				"dev:web": {"persistent": true},
+				"dev:docs": {},
+				"build": {"dependsOn": ["dev:*"]}
+			}}`,
+			expected: []string{"\"dev:web\" is a persistent task, \"build\" cannot depend on it"},
+		},
+		{
+			name: "package task depending on a persistent package task",
+			json: `{"pipeline": {
+				"dev": {},
+				"web#dev": {"persistent": true},
+				"web#build": {"dependsOn": ["dev"]}
+			}}`,
+			expected: []string{"\"dev\" is a persistent task, \"web#build\" cannot depend on it"},
+		},
+		{
+			name: "persistent task depending on a persistent task",
+			json: `{"pipeline": {
+				"dev": {"persistent": true},
+				"serve": {"persistent": true, "dependsOn": ["dev"]}
+			}}`,
+			expected: []string{},
+		},
+		{
+			name: "non-persistent dependencies",
+			json: `{"pipeline": {
+				"dev": {"persistent": true},
+				"lint": {},
+				"build": {"dependsOn": ["^build", "lint", "missing"]}
+			}}`,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateNoPersistentDependencies})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateDependenciesExist(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "valid dependencies",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["^build", "codegen"]},
+				"web#codegen": {},
+				"test": {"dependsOn": ["build", "web#build"]}
+			}}`,
+			expected: []string{},
+		},
+		{
+			name: "typo'd dependencies",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["^buidl"]},
+				"test": {"dependsOn": ["buidl", "web#buidl"]}
+			}}`,
+			expected: []string{
+				"\"build\" depends on \"^buidl\", which is not defined in the pipeline",
+				"\"test\" depends on \"buidl\", which is not defined in the pipeline",
+				"\"test\" depends on \"web#buidl\", which is not defined in the pipeline",
+			},
+		},
+		{
+			name: "root task dependencies",
+			json: `{"pipeline": {
+				"//#foo": {},
+				"build": {"dependsOn": ["//#foo", "//#bar"]}
+			}}`,
+			expected: []string{"\"build\" depends on \"//#bar\", which is not defined in the pipeline"},
+		},
+		{
+			name: "root task shorthand dependencies",
+			json: `{"pipeline": {
+				"//#lint": {},
+				"build": {"dependsOn": ["//lint", "//typecheck"]}
+			}}`,
+			expected: []string{"\"build\" depends on \"//typecheck\", which is not defined in the pipeline"},
+		},
+		{
+			name: "glob dependencies",
+			json: `{"pipeline": {
+				"test:unit": {},
+				"test": {"dependsOn": ["test:*", "lint:*"]}
+			}}`,
+			expected: []string{"\"test\" depends on \"lint:*\", which does not match any task in the pipeline"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateDependenciesExist})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateOutputsWithinPackage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		outputs  string
+		expected []string
+	}{
+		{
+			name:     "relative outputs",
+			outputs:  `["./ok/**", "dist/**", "dist/../build/**", "!dist/cache/**"]`,
+			expected: []string{},
+		},
+		{
+			name:     "parent directory",
+			outputs:  `["../dist/**", "dist/../../build/**"]`,
+			expected: []string{"\"../dist/**\" in the \"outputs\" of \"build\" is outside of the package directory", "\"dist/../../build/**\" in the \"outputs\" of \"build\" is outside of the package directory"},
+		},
+		{
+			name:     "absolute path",
+			outputs:  `["/abs/path/**"]`,
+			expected: []string{"\"/abs/path/**\" in the \"outputs\" of \"build\" is outside of the package directory"},
+		},
+		{
+			name:     "negated parent directory",
+			outputs:  `["dist/**", "!../exclude/**"]`,
+			expected: []string{"\"!../exclude/**\" in the \"outputs\" of \"build\" is outside of the package directory"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {"outputs": `+tc.outputs+`}}}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateOutputsWithinPackage})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidatePersistentTaskConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name:     "persistent task with outputs",
+			json:     `{"pipeline": {"dev": {"persistent": true, "outputs": [".next/**"]}}}`,
+			expected: []string{"\"dev\" is a persistent task and will never be cached, remove \"outputs\" from it"},
+		},
+		{
+			name:     "persistent task with cache true",
+			json:     `{"pipeline": {"dev": {"persistent": true, "cache": true}}}`,
+			expected: []string{"\"dev\" is a persistent task and will never be cached, remove \"cache\" from it or set it to false"},
+		},
+		{
+			name:     "clean persistent task",
+			json:     `{"pipeline": {"dev": {"persistent": true, "cache": false, "outputs": []}}}`,
+			expected: []string{},
+		},
+		{
+			name:     "non-persistent task with outputs and cache",
+			json:     `{"pipeline": {"build": {"cache": true, "outputs": ["dist/**"]}}}`,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidatePersistentTaskConfig})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateEnvNoOverlap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "no overlap",
+			json: `{
+				"globalEnv": ["CI"],
+				"globalPassThroughEnv": ["HOME"],
+				"pipeline": {"build": {"env": ["API_URL"], "passThroughEnv": ["AWS_SECRET"]}}
+			}`,
+			expected: []string{},
+		},
+		{
+			name: "task overlap",
+			json: `{"pipeline": {
+				"build": {"env": ["API_URL", "NODE_ENV"], "passThroughEnv": ["NODE_ENV", "API_URL"]},
+				"test": {"env": ["API_URL"]}
+			}}`,
+			expected: []string{
+				"\"API_URL\" is declared in both \"env\" and \"passThroughEnv\" of \"build\"",
+				"\"NODE_ENV\" is declared in both \"env\" and \"passThroughEnv\" of \"build\"",
+			},
+		},
+		{
+			name: "global overlap",
+			json: `{
+				"globalEnv": ["CI", "HOME"],
+				"globalPassThroughEnv": ["HOME"],
+				"pipeline": {}
+			}`,
+			expected: []string{"\"HOME\" is declared in both \"globalEnv\" and \"globalPassThroughEnv\""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateEnvNoOverlap})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateInputsOutputsDisjoint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		task     string
+		expected []string
+	}{
+		{
+			name:     "exact overlap",
+			task:     `{"inputs": ["src/**", "dist/**"], "outputs": ["dist/**"]}`,
+			expected: []string{"\"dist/**\" in the \"outputs\" of \"build\" overlaps with \"dist/**\" in its \"inputs\", so the task's outputs will change its hash"},
+		},
+		{
+			name:     "prefix overlap",
+			task:     `{"inputs": ["src/**"], "outputs": ["src/generated/**"]}`,
+			expected: []string{"\"src/generated/**\" in the \"outputs\" of \"build\" overlaps with \"src/**\" in its \"inputs\", so the task's outputs will change its hash"},
+		},
+		{
+			name:     "disjoint",
+			task:     `{"inputs": ["src/**", "package.json", "!dist/**"], "outputs": ["dist/**", ".next/**", "!.next/cache/**"]}`,
+			expected: []string{},
+		},
+		{
+			name:     "wildcards are not compared",
+			task:     `{"inputs": ["src/*.ts"], "outputs": ["src/*.js", "*/generated/**"]}`,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {"build": `+tc.task+`}}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateInputsOutputsDisjoint})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateWithTasksExist(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"mock": {"persistent": true},
+		"web#proxy": {"persistent": true},
+		"test": {"with": ["mock", "web#proxy", "mokc"]}
+	}}`)
+	errs := turboJSON.Validate([]TurboJSONValidation{ValidateWithTasksExist})
+	assert.EqualValues(t, []string{"\"test\" is started with \"mokc\", which is not defined in the pipeline"}, errorMessages(errs))
+}
+
+func Test_ValidateTaskNames(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pipeline string
+		want     []string
+	}{
+		{
+			name:     "valid names",
+			pipeline: `{"build": {}, "web#build": {}, "//#lint": {}, "@scope/pkg#test": {}}`,
+			want:     []string{},
+		},
+		{
+			name:     "missing package",
+			pipeline: `{"#build": {}}`,
+			want:     []string{"\"#build\" is not a valid task name, it is missing a package name before \"#\""},
+		},
+		{
+			name:     "missing task",
+			pipeline: `{"web#": {}}`,
+			want:     []string{"\"web#\" is not a valid task name, it is missing a task name after \"#\""},
+		},
+		{
+			name:     "empty",
+			pipeline: `{"": {}}`,
+			want:     []string{"task names in the pipeline cannot be empty"},
+		},
+		{
+			name:     "multiple delimiters",
+			pipeline: `{"web#build#dev": {}}`,
+			want:     []string{"\"web#build#dev\" is not a valid task name, it contains more than one \"#\""},
+		},
+		{
+			name:     "reserved prefixes",
+			pipeline: `{"^build": {}, "web#$lint": {}}`,
+			want: []string{
+				"\"^build\" is not a valid task name, task names cannot start with \"^\"",
+				"\"web#$lint\" is not a valid task name, task names cannot start with \"$\"",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": `+tc.pipeline+`}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateTaskNames})
+			assert.EqualValues(t, tc.want, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateRemoteCacheOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		remoteCache string
+		want        []string
+	}{
+		{
+			name:        "valid team id",
+			remoteCache: `{"teamId": "team_abc123", "signature": true}`,
+			want:        []string{},
+		},
+		{
+			name:        "signature disabled",
+			remoteCache: `{"teamId": "abc123"}`,
+			want:        []string{},
+		},
+		{
+			name:        "missing team id",
+			remoteCache: `{"signature": true}`,
+			want:        []string{"\"remoteCache.signature\" is enabled, but \"remoteCache.teamId\" is not set"},
+		},
+		{
+			name:        "malformed team id",
+			remoteCache: `{"teamId": "abc123", "signature": true}`,
+			want:        []string{"\"abc123\" is not a valid \"remoteCache.teamId\", team ids start with \"team_\""},
+		},
+		{
+			name:        "bare prefix",
+			remoteCache: `{"teamId": "team_", "signature": true}`,
+			want:        []string{"\"team_\" is not a valid \"remoteCache.teamId\", team ids start with \"team_\""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {}, "remoteCache": `+tc.remoteCache+`}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateRemoteCacheOptions})
+			assert.EqualValues(t, tc.want, errorMessages(errs))
+		})
+	}
+}
+
+func Test_DefaultValidations(t *testing.T) {
+	turboJSON := parseTurboJSON(t, `{"pipeline": {
+		"build": {"dependsOn": ["codegen", "dev"], "outputs": ["../dist/**"]},
+		"dev": {"persistent": true, "cache": false},
+		"test": {"env": ["CI"], "passThroughEnv": ["CI"]},
+		"web#": {}
+	}}`)
+
+	want := []string{
+		"\"web#\" is not a valid task name, it is missing a task name after \"#\"",
+		"\"build\" depends on \"codegen\", which is not defined in the pipeline",
+		"\"dev\" is a persistent task, \"build\" cannot depend on it",
+		"\"CI\" is declared in both \"env\" and \"passThroughEnv\" of \"test\"",
+		"\"../dist/**\" in the \"outputs\" of \"build\" is outside of the package directory",
+	}
+	// Run it a few times, since the pipeline is a map
+	for i := 0; i < 5; i++ {
+		errs := turboJSON.Validate(DefaultValidations())
+		assert.EqualValues(t, want, errorMessages(errs))
+	}
+}
+
+func Test_ValidateGlobalDependenciesNotTooBroad(t *testing.T) {
+	testCases := []struct {
+		name               string
+		globalDependencies string
+		want               []string
+	}{
+		{
+			name:               "double star",
+			globalDependencies: `["**"]`,
+			want:               []string{"\"**\" in \"globalDependencies\" matches every file in the repository, so any change invalidates the cache of every task. Consider narrowing it to the files that affect all tasks (e.g. \"tsconfig.json\")"},
+		},
+		{
+			name:               "double star with file",
+			globalDependencies: `["tsconfig.json", "./**/*"]`,
+			want:               []string{"\"./**/*\" in \"globalDependencies\" matches every file in the repository, so any change invalidates the cache of every task. Consider narrowing it to the files that affect all tasks (e.g. \"tsconfig.json\")"},
+		},
+		{
+			name:               "specific globs",
+			globalDependencies: `["tsconfig.json", "config/**", "**/.env", "*"]`,
+			want:               []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {}, "globalDependencies": `+tc.globalDependencies+`}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateGlobalDependenciesNotTooBroad})
+			assert.EqualValues(t, tc.want, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateNoSelfDependency(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "no self dependencies",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["^build", "codegen"]},
+				"web#build": {"dependsOn": ["^build", "docs#build"]},
+				"//#lint": {"dependsOn": ["//format"]}
+			}}`,
+			expected: []string{},
+		},
+		{
+			name: "bare self dependency",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["^build", "build"]},
+				"web#test": {"dependsOn": ["test"]},
+				"//#lint": {"dependsOn": ["lint"]}
+			}}`,
+			expected: []string{
+				"\"//#lint\" depends on itself (\"lint\" in \"dependsOn\"), remove it",
+				"\"build\" depends on itself (\"build\" in \"dependsOn\"), remove it",
+				"\"web#test\" depends on itself (\"test\" in \"dependsOn\"), remove it",
+			},
+		},
+		{
+			name: "package-scoped self dependency",
+			json: `{"pipeline": {
+				"web#build": {"dependsOn": ["web#build"]},
+				"//#lint": {"dependsOn": ["//lint"]}
+			}}`,
+			expected: []string{
+				"\"//#lint\" depends on itself (\"//lint\" in \"dependsOn\"), remove it",
+				"\"web#build\" depends on itself (\"web#build\" in \"dependsOn\"), remove it",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateNoSelfDependency})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateCachedTasksHaveInputs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name:     "outputs without inputs",
+			json:     `{"pipeline": {"build": {"outputs": ["dist/**"]}}}`,
+			expected: []string{`"build" caches its "outputs" but does not declare its "inputs", so every file in its package is hashed. Declare "inputs" to hash it faster`},
+		},
+		{
+			name:     "outputs and inputs",
+			json:     `{"pipeline": {"build": {"outputs": ["dist/**"], "inputs": ["src/**"]}}}`,
+			expected: []string{},
+		},
+		{
+			name:     "default inputs",
+			json:     `{"pipeline": {"build": {"outputs": ["dist/**"], "inputs": ["$TURBO_DEFAULT$"]}}}`,
+			expected: []string{},
+		},
+		{
+			name: "not cached or no outputs",
+			json: `{"pipeline": {
+				"build": {"outputs": ["dist/**"], "cache": false},
+				"dev": {"outputs": ["dist/**"], "persistent": true},
+				"lint": {}
+			}}`,
+			expected: []string{},
+		},
+		{
+			name:     "opted out",
+			json:     `{"experimental": {"implicitInputsWarning": false}, "pipeline": {"build": {"outputs": ["dist/**"]}}}`,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateCachedTasksHaveInputs})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateOutputModeValues(t *testing.T) {
+	for _, outputMode := range util.TaskOutputModeStrings {
+		t.Run(outputMode, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"outputMode": "`+outputMode+`", "pipeline": {"build": {"outputMode": "`+outputMode+`"}}}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateOutputModeValues})
+			assert.Empty(t, errorMessages(errs))
+		})
+	}
+
+	t.Run("invalid string", func(t *testing.T) {
+		var turboJSON TurboJSON
+		err := json.Unmarshal([]byte(`{"pipeline": {"build": {"outputMode": "errors"}}}`), &turboJSON)
+		assert.ErrorContains(t, err, "invalid task output mode: errors. Valid values are: full, none, hash-only, new-only, errors-only")
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {}, "test": {}}}`)
+		invalid := util.TaskOutputMode(42)
+		turboJSON.OutputMode = &invalid
+		build := turboJSON.Pipeline["build"]
+		build.TaskDefinition.OutputMode = invalid
+		turboJSON.Pipeline["build"] = build
+
+		errs := turboJSON.Validate([]TurboJSONValidation{ValidateOutputModeValues})
+		assert.EqualValues(t, []string{
+			"the default \"outputMode\" (42) is invalid. Valid values are: full, none, hash-only, new-only, errors-only",
+			"the \"outputMode\" of \"build\" (42) is invalid. Valid values are: full, none, hash-only, new-only, errors-only",
+		}, errorMessages(errs))
+	})
+}
+
+func Test_ValidateExtends(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		isRoot   bool
+		expected []string
+	}{
+		{
+			name:     "root without extends",
+			json:     `{"pipeline": {"build": {}}}`,
+			isRoot:   true,
+			expected: []string{},
+		},
+		{
+			name:     "root with extends",
+			json:     `{"extends": ["//"], "pipeline": {"build": {}}}`,
+			isRoot:   true,
+			expected: []string{`the root turbo.json cannot extend other configurations, remove "extends"`},
+		},
+		{
+			name:     "workspace extending the root",
+			json:     `{"extends": ["//"], "pipeline": {"build": {}}}`,
+			isRoot:   false,
+			expected: []string{},
+		},
+		{
+			name:     "workspace without extends",
+			json:     `{"pipeline": {"build": {}}}`,
+			isRoot:   false,
+			expected: []string{`"extends" does not include the root workspace ("//"), so the tasks in the root turbo.json are not inherited`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateExtends(tc.isRoot)})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateTopologicalDependencies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name:     "task dependency",
+			json:     `{"pipeline": {"build": {"dependsOn": ["^build"]}}}`,
+			expected: []string{},
+		},
+		{
+			name:     "package task dependency",
+			json:     `{"pipeline": {"web#codegen": {}, "build": {"dependsOn": ["^codegen"]}}}`,
+			expected: []string{},
+		},
+		{
+			name: "workspace dependency",
+			json: `{"pipeline": {"build": {"dependsOn": ["^web"]}}}`,
+			expected: []string{
+				"\"build\" depends on \"^web\", but \"web\" is a workspace, not a task. \"^\" must be followed by the name of a task in the workspace's dependencies, e.g. \"^build\"",
+			},
+		},
+		{
+			name:     "undefined dependency",
+			json:     `{"pipeline": {"build": {"dependsOn": ["^buidl"]}}}`,
+			expected: []string{"\"build\" depends on \"^buidl\", which is not defined in the pipeline"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateTopologicalDependencies([]string{"web", "docs"})})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidatePackageTaskCaching(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "consistent package tasks",
+			json: `{"pipeline": {
+				"build": {"outputs": ["dist/**"]},
+				"web#build": {"outputs": [".next/**"]},
+				"dev": {"cache": false},
+				"web#dev": {"cache": false, "persistent": true},
+				"docs#lint": {"cache": false}
+			}}`,
+			expected: []string{},
+		},
+		{
+			name: "package task disables caching",
+			json: `{"pipeline": {
+				"build": {"outputs": ["dist/**"]},
+				"web#build": {"cache": false}
+			}}`,
+			expected: []string{
+				"\"web#build\" is not cached, but \"build\" is cached. \"web#build\" replaces \"build\" in the \"web\" workspace",
+			},
+		},
+		{
+			name: "package task enables caching",
+			json: `{"pipeline": {
+				"deploy": {"cache": false},
+				"web#deploy": {},
+				"docs#deploy": {"cache": {"remote": false}}
+			}}`,
+			expected: []string{
+				"\"docs#deploy\" is only cached locally, but \"deploy\" is not cached. \"docs#deploy\" replaces \"deploy\" in the \"docs\" workspace",
+				"\"web#deploy\" is cached, but \"deploy\" is not cached. \"web#deploy\" replaces \"deploy\" in the \"web\" workspace",
+			},
+		},
+		{
+			name: "package task drops outputs",
+			json: `{"pipeline": {
+				"build": {"outputs": ["dist/**"]},
+				"web#build": {"dependsOn": ["^build"]}
+			}}`,
+			expected: []string{
+				"\"web#build\" has no \"outputs\", but \"build\" does. \"web#build\" replaces \"build\" in the \"web\" workspace, so only its logs are cached",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidatePackageTaskCaching})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateOutputsExcludeNodeModules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		outputs  string
+		expected []string
+	}{
+		{
+			name:     "package outputs",
+			outputs:  `["dist/**", ".next/**", "!.next/cache/**"]`,
+			expected: []string{},
+		},
+		{
+			name:     "node_modules",
+			outputs:  `["dist/**", "node_modules/**"]`,
+			expected: []string{"\"node_modules/**\" in the \"outputs\" of \"build\" includes node_modules, which bloats the cache. Exclude it with \"!node_modules/**\""},
+		},
+		{
+			name:     "everything",
+			outputs:  `["**"]`,
+			expected: []string{"\"**\" in the \"outputs\" of \"build\" includes node_modules, which bloats the cache. Exclude it with \"!node_modules/**\""},
+		},
+		{
+			name:     "everything but node_modules",
+			outputs:  `["**", "!node_modules/**"]`,
+			expected: []string{},
+		},
+		{
+			name:     "root node_modules",
+			outputs:  `["$TURBO_ROOT$/node_modules/.cache/**", "$TURBO_ROOT$/node_modules/**"]`,
+			expected: []string{"\"$TURBO_ROOT$/node_modules/**\" in the \"outputs\" of \"build\" includes node_modules, which bloats the cache. Exclude it with \"!node_modules/**\""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, `{"pipeline": {"build": {"outputs": `+tc.outputs+`}}}`)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateOutputsExcludeNodeModules})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateNoCycles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "no cycles",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["^build", "codegen"]},
+				"codegen": {},
+				"test": {"dependsOn": ["build", "codegen", "//#lint"]},
+				"//#lint": {},
+				"web#test": {"dependsOn": ["build", "test:*"]},
+				"test:e2e": {"dependsOn": ["build"]}
+			}}`,
+			expected: []string{},
+		},
+		{
+			name: "three task cycle",
+			json: `{"pipeline": {
+				"a": {"dependsOn": ["b"]},
+				"b": {"dependsOn": ["c"]},
+				"c": {"dependsOn": ["a"]},
+				"d": {"dependsOn": ["a"]}
+			}}`,
+			expected: []string{
+				"\"a\" depends on itself through \"a\" -> \"b\" -> \"c\" -> \"a\", remove one of these dependencies",
+			},
+		},
+		{
+			name: "cycle through a package task",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["codegen"]},
+				"codegen": {},
+				"web#codegen": {"dependsOn": ["build"]}
+			}}`,
+			expected: []string{
+				"\"web#codegen\" depends on itself through \"web#codegen\" -> \"web#build\" -> \"web#codegen\", remove one of these dependencies",
+			},
+		},
+		{
+			name: "self dependencies are reported separately",
+			json: `{"pipeline": {
+				"build": {"dependsOn": ["build"]}
+			}}`,
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateNoCycles})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
+
+func Test_ValidateGlobsCompile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name: "valid globs",
+			json: `{
+				"globalDependencies": ["tsconfig.json", "config/{a,b}.json"],
+				"pipeline": {
+					"build": {"inputs": ["src/**/*.[jt]s", "$TURBO_DEFAULT$"], "outputs": ["dist/**", "!dist/cache/**", "$TURBO_ROOT$/coverage/**"]}
+				}
+			}`,
+			expected: []string{},
+		},
+		{
+			name: "malformed input",
+			json: `{"pipeline": {
+				"build": {},
+				"test": {"inputs": ["src/[", "tests/**"]}
+			}}`,
+			expected: []string{
+				"\"src/[\" in the \"inputs\" of \"test\" is not a valid glob: syntax error in pattern",
+			},
+		},
+		{
+			name: "malformed outputs",
+			json: `{"pipeline": {
+				"web#build": {"outputs": ["dist/{a,b", "!dist/[", "$TURBO_ROOT$/out/["]}
+			}}`,
+			expected: []string{
+				"\"dist/{a,b\" in the \"outputs\" of \"web#build\" is not a valid glob: syntax error in pattern",
+				"\"!dist/[\" in the \"outputs\" of \"web#build\" is not a valid glob: syntax error in pattern",
+				"\"$TURBO_ROOT$/out/[\" in the \"outputs\" of \"web#build\" is not a valid glob: syntax error in pattern",
+			},
+		},
+		{
+			name: "malformed globalDependencies",
+			json: `{"globalDependencies": ["config/[a-"], "pipeline": {}}`,
+			expected: []string{
+				"\"config/[a-\" in \"globalDependencies\" is not a valid glob: syntax error in pattern",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			turboJSON := parseTurboJSON(t, tc.json)
+			errs := turboJSON.Validate([]TurboJSONValidation{ValidateGlobsCompile})
+			assert.EqualValues(t, tc.expected, errorMessages(errs))
+		})
+	}
+}
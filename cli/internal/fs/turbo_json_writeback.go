@@ -0,0 +1,474 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/muhammadmuzzammil1998/jsonc"
+)
+
+// jsoncNode is a JSON value found in a JSONC document, along with its position.
+// Comments and whitespace are skipped over, but since we only ever record offsets
+// into the original buffer, they are left intact when we rewrite parts of it.
+type jsoncNode struct {
+	// start and end are the byte offsets of the value in the original document
+	start int
+	end   int
+	// members are only populated for objects
+	members []*jsoncMember
+}
+
+// jsoncMember is a single key/value pair inside of a JSONC object
+type jsoncMember struct {
+	key      string
+	keyStart int
+	value    *jsoncNode
+}
+
+func (n *jsoncNode) isObject(data []byte) bool {
+	return data[n.start] == '{'
+}
+
+func (n *jsoncNode) member(key string) (int, *jsoncMember) {
+	for i, member := range n.members {
+		if member.key == key {
+			return i, member
+		}
+	}
+	return -1, nil
+}
+
+// find returns the value at the given path of object keys, or nil if there isn't one
+func (n *jsoncNode) find(path []string) *jsoncNode {
+	node := n
+	for _, key := range path {
+		_, member := node.member(key)
+		if member == nil {
+			return nil
+		}
+		node = member.value
+	}
+	return node
+}
+
+// locate finds the value at path within any object in the tree, where the value ends
+// at offset bytes from the start of that object. This is how encoding/json reports type
+// errors from nested Unmarshalers (e.g. a single task definition).
+func (n *jsoncNode) locate(path []string, offset int) *jsoncNode {
+	if node := n.find(path); node != nil && node.end-n.start == offset {
+		return node
+	}
+	for _, member := range n.members {
+		if node := member.value.locate(path, offset); node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+// blankJSONCComments returns a copy of data where every comment has been replaced
+// with spaces, so that it can be parsed as JSON while keeping offsets intact.
+func blankJSONCComments(data []byte) []byte {
+	blanked := append([]byte{}, data...)
+	s := &jsoncScanner{data: data}
+	for s.pos < len(data) {
+		switch {
+		case data[s.pos] == '"':
+			if s.str() != nil {
+				return blanked
+			}
+		case bytes.HasPrefix(data[s.pos:], []byte("//")) || bytes.HasPrefix(data[s.pos:], []byte("/*")):
+			start := s.pos
+			s.skip()
+			for i := start; i < s.pos; i++ {
+				if blanked[i] != '\n' && blanked[i] != '\r' {
+					blanked[i] = ' '
+				}
+			}
+		default:
+			s.pos++
+		}
+	}
+	return blanked
+}
+
+// jsoncScanner parses a JSONC document, recording the position of each value
+type jsoncScanner struct {
+	data []byte
+	pos  int
+}
+
+// parseJSONCPositions parses data as JSONC and returns the root value with position information
+func parseJSONCPositions(data []byte) (*jsoncNode, error) {
+	s := &jsoncScanner{data: data}
+	node, err := s.value()
+	if err != nil {
+		return nil, err
+	}
+	s.skip()
+	if s.pos != len(s.data) {
+		return nil, s.errorf("unexpected content after top-level value")
+	}
+	return node, nil
+}
+
+func (s *jsoncScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", s.pos, fmt.Sprintf(format, args...))
+}
+
+// skip advances past any whitespace and comments
+func (s *jsoncScanner) skip() {
+	for s.pos < len(s.data) {
+		switch {
+		case s.data[s.pos] == ' ' || s.data[s.pos] == '\t' || s.data[s.pos] == '\n' || s.data[s.pos] == '\r':
+			s.pos++
+		case bytes.HasPrefix(s.data[s.pos:], []byte("//")):
+			end := bytes.IndexByte(s.data[s.pos:], '\n')
+			if end == -1 {
+				s.pos = len(s.data)
+			} else {
+				s.pos += end + 1
+			}
+		case bytes.HasPrefix(s.data[s.pos:], []byte("/*")):
+			end := bytes.Index(s.data[s.pos+2:], []byte("*/"))
+			if end == -1 {
+				s.pos = len(s.data)
+			} else {
+				s.pos += end + 4
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsoncScanner) value() (*jsoncNode, error) {
+	s.skip()
+	if s.pos >= len(s.data) {
+		return nil, s.errorf("unexpected end of input")
+	}
+	switch s.data[s.pos] {
+	case '{':
+		return s.object()
+	case '[':
+		return s.array()
+	case '"':
+		start := s.pos
+		if err := s.str(); err != nil {
+			return nil, err
+		}
+		return &jsoncNode{start: start, end: s.pos}, nil
+	default:
+		start := s.pos
+		for s.pos < len(s.data) && !strings.ContainsRune(",}] \t\r\n/", rune(s.data[s.pos])) {
+			s.pos++
+		}
+		if start == s.pos {
+			return nil, s.errorf("unexpected character %q", s.data[s.pos])
+		}
+		return &jsoncNode{start: start, end: s.pos}, nil
+	}
+}
+
+func (s *jsoncScanner) str() error {
+	// skip the opening quote
+	s.pos++
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '"':
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return s.errorf("unterminated string")
+}
+
+func (s *jsoncScanner) object() (*jsoncNode, error) {
+	node := &jsoncNode{start: s.pos}
+	// skip the opening brace
+	s.pos++
+	for {
+		s.skip()
+		if s.pos >= len(s.data) {
+			return nil, s.errorf("unterminated object")
+		}
+		if s.data[s.pos] == '}' {
+			s.pos++
+			node.end = s.pos
+			return node, nil
+		}
+		if len(node.members) > 0 {
+			if s.data[s.pos] != ',' {
+				return nil, s.errorf("expected ',' or '}'")
+			}
+			s.pos++
+			s.skip()
+		}
+		if s.pos >= len(s.data) || s.data[s.pos] != '"' {
+			return nil, s.errorf("expected object key")
+		}
+		keyStart := s.pos
+		if err := s.str(); err != nil {
+			return nil, err
+		}
+		var key string
+		if err := json.Unmarshal(s.data[keyStart:s.pos], &key); err != nil {
+			return nil, err
+		}
+		s.skip()
+		if s.pos >= len(s.data) || s.data[s.pos] != ':' {
+			return nil, s.errorf("expected ':' after object key")
+		}
+		s.pos++
+		value, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		node.members = append(node.members, &jsoncMember{key: key, keyStart: keyStart, value: value})
+	}
+}
+
+func (s *jsoncScanner) array() (*jsoncNode, error) {
+	node := &jsoncNode{start: s.pos}
+	// skip the opening bracket
+	s.pos++
+	first := true
+	for {
+		s.skip()
+		if s.pos >= len(s.data) {
+			return nil, s.errorf("unterminated array")
+		}
+		if s.data[s.pos] == ']' {
+			s.pos++
+			node.end = s.pos
+			return node, nil
+		}
+		if !first {
+			if s.data[s.pos] != ',' {
+				return nil, s.errorf("expected ',' or ']'")
+			}
+			s.pos++
+		}
+		first = false
+		if _, err := s.value(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// textEdit replaces original[start:end] with replacement
+type textEdit struct {
+	start       int
+	end         int
+	replacement string
+}
+
+// WriteBackPreservingComments returns the contents of original (a turbo.json file) updated
+// to match turboJSON. Only the values that changed are rewritten, so comments and key order
+// of untouched keys are preserved.
+func WriteBackPreservingComments(original []byte, turboJSON *TurboJSON) ([]byte, error) {
+	root, err := parseJSONCPositions(original)
+	if err != nil {
+		return nil, err
+	}
+
+	var originalTurboJSON *TurboJSON
+	if err := jsonc.Unmarshal(original, &originalTurboJSON); err != nil {
+		return nil, err
+	}
+
+	// Compare serialized forms so that only semantic changes are written back,
+	// rather than every difference in formatting between the file and MarshalJSON.
+	before, err := json.Marshal(originalTurboJSON)
+	if err != nil {
+		return nil, err
+	}
+	after, err := json.Marshal(turboJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := []textEdit{}
+	if err := diffJSONC(original, root, before, after, &edits); err != nil {
+		return nil, err
+	}
+
+	// Apply the edits from the end of the document so that offsets stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	result := append([]byte{}, original...)
+	for _, edit := range edits {
+		result = append(result[:edit.start], append([]byte(edit.replacement), result[edit.end:]...)...)
+	}
+
+	return result, nil
+}
+
+// diffJSONC compares before and after, and records edits against node (which holds
+// the position of the same value in the original document) for anything that changed.
+func diffJSONC(data []byte, node *jsoncNode, before json.RawMessage, after json.RawMessage, edits *[]textEdit) error {
+	if jsonEqual(before, after) {
+		return nil
+	}
+
+	beforeMembers := map[string]json.RawMessage{}
+	afterMembers := map[string]json.RawMessage{}
+	isObject := node.isObject(data) &&
+		json.Unmarshal(before, &beforeMembers) == nil &&
+		json.Unmarshal(after, &afterMembers) == nil
+	if !isObject {
+		replacement, err := formatJSONCValue(after, indentationAt(data, node.start))
+		if err != nil {
+			return err
+		}
+		*edits = append(*edits, textEdit{start: node.start, end: node.end, replacement: replacement})
+		return nil
+	}
+
+	keys := make([]string, 0, len(afterMembers))
+	for key := range afterMembers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	inserted := []string{}
+	for _, key := range keys {
+		if jsonEqual(beforeMembers[key], afterMembers[key]) {
+			continue
+		}
+		if _, member := node.member(key); member != nil {
+			if err := diffJSONC(data, member.value, beforeMembers[key], afterMembers[key], edits); err != nil {
+				return err
+			}
+		} else {
+			inserted = append(inserted, key)
+		}
+	}
+
+	removed := map[int]bool{}
+	for key := range beforeMembers {
+		if _, ok := afterMembers[key]; ok {
+			continue
+		}
+		if i, member := node.member(key); member != nil {
+			removed[i] = true
+		}
+	}
+	*edits = append(*edits, removeMembersEdits(data, node, removed)...)
+
+	if len(inserted) > 0 {
+		edit, err := insertMembersEdit(data, node, inserted, afterMembers)
+		if err != nil {
+			return err
+		}
+		*edits = append(*edits, edit)
+	}
+
+	return nil
+}
+
+// removeMembersEdits removes the members of node at the given indices, along with their separating commas
+func removeMembersEdits(data []byte, node *jsoncNode, removed map[int]bool) []textEdit {
+	edits := []textEdit{}
+	last := len(node.members) - 1
+	if removed[last] {
+		// Removing the trailing members means removing the comma after the last member we keep.
+		// Anything between that comma and the first removed key (e.g. a trailing comment) is kept.
+		kept := last
+		for kept >= 0 && removed[kept] {
+			kept--
+		}
+		if kept >= 0 {
+			s := &jsoncScanner{data: data, pos: node.members[kept].value.end}
+			s.skip()
+			edits = append(edits, textEdit{start: s.pos, end: s.pos + 1})
+		}
+		start := node.members[kept+1].keyStart
+		if lineStart := bytes.LastIndexByte(data[:start], '\n'); lineStart > node.start && strings.TrimSpace(string(data[lineStart:start])) == "" {
+			start = lineStart
+		}
+		edits = append(edits, textEdit{start: start, end: node.members[last].value.end})
+		last = kept
+	}
+
+	for i := 0; i < last; i++ {
+		if removed[i] {
+			// Remove up until the start of the next key, which swallows the trailing comma
+			edits = append(edits, textEdit{start: node.members[i].keyStart, end: node.members[i+1].keyStart})
+		}
+	}
+
+	return edits
+}
+
+// insertMembersEdit adds new keys at the end of the object represented by node
+func insertMembersEdit(data []byte, node *jsoncNode, keys []string, values map[string]json.RawMessage) (textEdit, error) {
+	indent := indentationAt(data, node.start) + "  "
+	insertAt := node.start + 1
+	if len(node.members) > 0 {
+		last := node.members[len(node.members)-1]
+		indent = indentationAt(data, last.keyStart)
+		insertAt = last.value.end
+	}
+
+	var builder strings.Builder
+	for i, key := range keys {
+		if i > 0 || len(node.members) > 0 {
+			builder.WriteString(",")
+		}
+		value, err := formatJSONCValue(values[key], indent)
+		if err != nil {
+			return textEdit{}, err
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return textEdit{}, err
+		}
+		builder.WriteString("\n" + indent + string(encodedKey) + ": " + value)
+	}
+	if len(node.members) == 0 {
+		builder.WriteString("\n" + indentationAt(data, node.start))
+	}
+
+	return textEdit{start: insertAt, end: insertAt, replacement: builder.String()}, nil
+}
+
+// formatJSONCValue pretty prints a value to be written at the given indentation
+func formatJSONCValue(value json.RawMessage, indent string) (string, error) {
+	var buffer bytes.Buffer
+	if err := json.Indent(&buffer, value, indent, "  "); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// indentationAt returns the leading whitespace of the line containing offset
+func indentationAt(data []byte, offset int) string {
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	lineEnd := lineStart
+	for lineEnd < len(data) && (data[lineEnd] == ' ' || data[lineEnd] == '\t') {
+		lineEnd++
+	}
+	return string(data[lineStart:lineEnd])
+}
+
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	var aValue interface{}
+	var bValue interface{}
+	if err := json.Unmarshal(a, &aValue); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &bValue); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
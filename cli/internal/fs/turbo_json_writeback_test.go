@@ -0,0 +1,80 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muhammadmuzzammil1998/jsonc"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteBackPreservingComments(t *testing.T) {
+	testDir := getTestDir(t, "commented")
+	original, err := testDir.UntypedJoin("turbo.json").ReadFile()
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	turboJSON, err := readTurboJSON(testDir.UntypedJoin("turbo.json"), ParseOptions{})
+	if err != nil {
+		t.Fatalf("invalid parse: %v", err)
+	}
+	turboJSON.RemoteCacheOptions.TeamID = "team_new"
+
+	updated, err := WriteBackPreservingComments(original, turboJSON)
+	assert.NoError(t, err)
+
+	expected := strings.Replace(string(original), `"teamId": "team_old"`, `"teamId": "team_new"`, 1)
+	assert.Equal(t, expected, string(updated))
+
+	var roundTripped *TurboJSON
+	err = jsonc.Unmarshal(updated, &roundTripped)
+	assert.NoError(t, err)
+	assert.Equal(t, "team_new", roundTripped.RemoteCacheOptions.TeamID)
+	assert.True(t, roundTripped.RemoteCacheOptions.Signature)
+}
+
+func Test_WriteBackPreservingComments_AddAndRemoveKeys(t *testing.T) {
+	original := []byte(`{
+  // Files that affect every task
+  "globalDependencies": ["tsconfig.json"],
+  "pipeline": {
+    "build": {} // builds things
+  },
+  "remoteCache": {
+    "teamId": "team_id", // the team
+    "signature": true
+  }
+}`)
+
+	var turboJSON *TurboJSON
+	if err := jsonc.Unmarshal(original, &turboJSON); err != nil {
+		t.Fatalf("invalid parse: %v", err)
+	}
+	turboJSON.GlobalDeps = []string{}
+	turboJSON.GlobalEnv = []string{"NODE_ENV"}
+	turboJSON.RemoteCacheOptions.Signature = false
+
+	updated, err := WriteBackPreservingComments(original, turboJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  // Files that affect every task
+  "pipeline": {
+    "build": {} // builds things
+  },
+  "remoteCache": {
+    "teamId": "team_id" // the team
+  },
+  "globalEnv": [
+    "NODE_ENV"
+  ]
+}`, string(updated))
+
+	var roundTripped *TurboJSON
+	err = jsonc.Unmarshal(updated, &roundTripped)
+	assert.NoError(t, err)
+	assert.EqualValues(t, turboJSON.GlobalDeps, roundTripped.GlobalDeps)
+	assert.EqualValues(t, turboJSON.GlobalEnv, roundTripped.GlobalEnv)
+	assert.EqualValues(t, turboJSON.RemoteCacheOptions, roundTripped.RemoteCacheOptions)
+	assert.EqualValues(t, turboJSON.Pipeline, roundTripped.Pipeline)
+}
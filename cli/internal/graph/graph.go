@@ -4,6 +4,7 @@ package graph
 import (
 	gocontext "context"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/pyr-sh/dag"
@@ -107,7 +108,7 @@ func (g *CompleteGraph) GetTurboConfigFromWorkspace(workspaceName string, isSing
 	// Note: pkgJSON.Dir for the root workspace will be an empty string, and for
 	// other workspaces, it will be a relative path.
 	workspaceAbsolutePath := workspacePackageJSON.Dir.RestoreAnchor(g.RepoRoot)
-	turboConfig, err := fs.LoadTurboConfig(workspaceAbsolutePath, workspacePackageJSON, isSinglePackage)
+	turboConfig, err := fs.LoadTurboConfigWithOptions(workspaceAbsolutePath, workspacePackageJSON, isSinglePackage, fs.ParseOptions{LookupEnv: os.LookupEnv})
 
 	// If we failed to load a TurboConfig, bubble up the error
 	if err != nil {
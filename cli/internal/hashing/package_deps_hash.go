@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/pkg/errors"
+	"github.com/vercel/turbo/cli/internal/doublestar"
 	"github.com/vercel/turbo/cli/internal/encoding/gitoutput"
 	"github.com/vercel/turbo/cli/internal/fs"
 	"github.com/vercel/turbo/cli/internal/globby"
@@ -23,7 +24,12 @@ type PackageDepsOptions struct {
 	// containing package.json. If omitted, the default value is the current working directory.
 	PackagePath turbopath.AnchoredSystemPath
 
+	// InputPatterns are globs, relative to PackagePath, of the files to hash. Patterns starting
+	// with "!" exclude the files they match, including the ones from DefaultInputs.
 	InputPatterns []string
+
+	// DefaultInputs includes every tracked file in the package, in addition to InputPatterns
+	DefaultInputs bool
 }
 
 // GetPackageDeps Builds an object containing git hashes for the files under the specified `packagePath` folder.
@@ -33,16 +39,26 @@ func GetPackageDeps(rootPath turbopath.AbsoluteSystemPath, p *PackageDepsOptions
 	var result map[turbopath.AnchoredUnixPath]string
 
 	// make a copy of the inputPatterns array, because we may be appending to it later.
-	calculatedInputs := make([]string, len(p.InputPatterns))
-	copy(calculatedInputs, p.InputPatterns)
+	// Negated patterns are set aside, they are applied once every file has been hashed.
+	calculatedInputs := []string{}
+	var excludedInputs []string
+	for _, pattern := range p.InputPatterns {
+		if strings.HasPrefix(pattern, "!") {
+			excludedInputs = append(excludedInputs, pattern[1:])
+		} else {
+			calculatedInputs = append(calculatedInputs, pattern)
+		}
+	}
 
-	if len(calculatedInputs) == 0 {
+	if len(calculatedInputs) == 0 || p.DefaultInputs {
 		gitLsTreeOutput, err := gitLsTree(pkgPath)
 		if err != nil {
 			return nil, fmt.Errorf("could not get git hashes for files in package %s: %w", p.PackagePath, err)
 		}
 		result = gitLsTreeOutput
-	} else {
+	}
+
+	if len(calculatedInputs) > 0 {
 		// Add in package.json and turbo.json to input patterns. Both file paths are relative to pkgPath
 		//
 		// - package.json is an input because if the `scripts` in
@@ -87,12 +103,24 @@ func GetPackageDeps(rootPath turbopath.AbsoluteSystemPath, p *PackageDepsOptions
 		if err != nil {
 			return nil, errors.Wrap(err, "failed hashing resolved inputs globs")
 		}
-		result = hashes
+		if result == nil {
+			result = hashes
+		} else {
+			for filePath, hash := range hashes {
+				result[filePath] = hash
+			}
+		}
+	}
+
+	// When the default inputs are included, the status of every file in the package matters
+	statusPatterns := calculatedInputs
+	if p.DefaultInputs {
+		statusPatterns = nil
 	}
 
 	// Update the checked in hashes with the current repo status
 	// The paths returned from this call are anchored at the package directory
-	gitStatusOutput, err := gitStatus(pkgPath, calculatedInputs)
+	gitStatusOutput, err := gitStatus(pkgPath, statusPatterns)
 	if err != nil {
 		return nil, fmt.Errorf("Could not get git hashes from git status: %v", err)
 	}
@@ -116,6 +144,19 @@ func GetPackageDeps(rootPath turbopath.AbsoluteSystemPath, p *PackageDepsOptions
 		result[filePath] = hash
 	}
 
+	for filePath := range result {
+		for _, pattern := range excludedInputs {
+			excluded, err := doublestar.Match(pattern, filePath.ToString())
+			if err != nil {
+				return nil, fmt.Errorf("invalid input pattern %q: %w", "!"+pattern, err)
+			}
+			if excluded {
+				delete(result, filePath)
+				break
+			}
+		}
+	}
+
 	return result, nil
 }
 
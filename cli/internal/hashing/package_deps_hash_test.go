@@ -365,6 +365,45 @@ func TestGetPackageDeps(t *testing.T) {
 				"uncommitted-file": "4e56ad89387e6379e4e91ddfe9872cf6a72c9976",
 			},
 		},
+		// default inputs hash every file in the package, alongside the specified inputs
+		{
+			opts: &PackageDepsOptions{
+				PackagePath:   "my-pkg",
+				InputPatterns: []string{"../new-root-file"},
+				DefaultInputs: true,
+			},
+			expected: map[turbopath.AnchoredUnixPath]string{
+				"../new-root-file": "8906ddcdd634706188bd8ef1c98ac07b9be3425e",
+				"committed-file":   "3a29e62ea9ba15c4a4009d1f605d391cdd262033",
+				"uncommitted-file": "4e56ad89387e6379e4e91ddfe9872cf6a72c9976",
+				"package.json":     "9e26dfeeb6e641a33dae4961196235bdb965b21b",
+				"dir/nested-file":  "bfe53d766e64d78f80050b73cd1c88095bc70abb",
+			},
+		},
+		// negated inputs exclude files from the default inputs
+		{
+			opts: &PackageDepsOptions{
+				PackagePath:   "my-pkg",
+				InputPatterns: []string{"!dir/**", "!uncommitted-file"},
+				DefaultInputs: true,
+			},
+			expected: map[turbopath.AnchoredUnixPath]string{
+				"committed-file": "3a29e62ea9ba15c4a4009d1f605d391cdd262033",
+				"package.json":   "9e26dfeeb6e641a33dae4961196235bdb965b21b",
+			},
+		},
+		// negated inputs also exclude files matched by the specified inputs
+		{
+			opts: &PackageDepsOptions{
+				PackagePath:   "my-pkg",
+				InputPatterns: []string{"**/*-file", "!**/nested-file"},
+			},
+			expected: map[turbopath.AnchoredUnixPath]string{
+				"committed-file":   "3a29e62ea9ba15c4a4009d1f605d391cdd262033",
+				"uncommitted-file": "4e56ad89387e6379e4e91ddfe9872cf6a72c9976",
+				"package.json":     "9e26dfeeb6e641a33dae4961196235bdb965b21b",
+			},
+		},
 	}
 	for _, tt := range tests {
 		got, err := GetPackageDeps(repoRoot, tt.opts)
@@ -30,13 +30,15 @@ func (pt *PackageTask) OutputPrefix(isSinglePackage bool) string {
 }
 
 // HashableOutputs returns the package-relative globs for files to be considered outputs
-// of this task
+// of this task, along with the ones that are relative to the repository root
 func (pt *PackageTask) HashableOutputs() fs.TaskOutputs {
 	inclusionOutputs := []string{fmt.Sprintf(".turbo/turbo-%v.log", pt.Task)}
 	inclusionOutputs = append(inclusionOutputs, pt.TaskDefinition.Outputs.Inclusions...)
 
 	return fs.TaskOutputs{
-		Inclusions: inclusionOutputs,
-		Exclusions: pt.TaskDefinition.Outputs.Exclusions,
+		Inclusions:     inclusionOutputs,
+		Exclusions:     pt.TaskDefinition.Outputs.Exclusions,
+		RootInclusions: pt.TaskDefinition.Outputs.RootInclusions,
+		RootExclusions: pt.TaskDefinition.Outputs.RootExclusions,
 	}
 }
@@ -208,10 +208,13 @@ func (p *prune) prune(opts *turbostate.PrunePayload) error {
 		}
 	}
 
-	turboJSON, err := fs.LoadTurboConfig(p.base.RepoRoot, rootPackageJSON, false)
+	turboJSON, warnings, err := fs.LoadTurboConfig(p.base.RepoRoot, rootPackageJSON, false)
 	if err != nil {
 		return errors.Wrap(err, "failed to read turbo.json")
 	}
+	for _, warning := range warnings {
+		p.base.LogWarning("", errors.New(warning))
+	}
 	if turboJSON != nil {
 		// when executing a prune, it is not enough to simply copy the file, as
 		// tasks may refer to scopes that no longer exist. to remedy this, we need
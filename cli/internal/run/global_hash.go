@@ -33,8 +33,13 @@ func calculateGlobalHash(rootpath turbopath.AbsoluteSystemPath, rootPackageJSON
 		globalHashableEnvPairs = append(globalHashableEnvPairs, fmt.Sprintf("%v=%v", builtinEnvVar, os.Getenv(builtinEnvVar)))
 	}
 
-	// Calculate global env var dependencies
-	for _, v := range envVarDependencies {
+	// Calculate global env var dependencies, expanding wildcards in "globalEnv" against
+	// the variables that are set
+	globalEnvNames, err := fs.ResolveEnvVarPatterns(envVarDependencies, fs.EnvVarNames(env))
+	if err != nil {
+		return "", err
+	}
+	for _, v := range globalEnvNames {
 		globalHashableEnvNames = append(globalHashableEnvNames, v)
 		globalHashableEnvPairs = append(globalHashableEnvPairs, fmt.Sprintf("%v=%v", v, os.Getenv(v)))
 	}
@@ -96,7 +101,7 @@ func calculateGlobalHash(rootpath turbopath.AbsoluteSystemPath, rootPackageJSON
 		rootExternalDepsHash: rootPackageJSON.ExternalDepsHash,
 		hashedSortedEnvPairs: globalHashableEnvPairs,
 		globalCacheKey:       _globalCacheKey,
-		pipeline:             pipeline.Pristine(),
+		pipeline:             pipeline.HashablePristine(),
 	}
 
 	globalHash, err := fs.HashObject(globalHashable)
@@ -1,8 +1,14 @@
 package run
 
 import (
+	"os"
 	"reflect"
 	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/lockfile"
+	"github.com/vercel/turbo/cli/internal/turbopath"
 )
 
 func Test_getHashableTurboEnvVarsFromOs(t *testing.T) {
@@ -24,3 +30,30 @@ func Test_getHashableTurboEnvVarsFromOs(t *testing.T) {
 		t.Errorf("getHashableTurboEnvVarsFromOs() env pairs got = %v, want %v", gotPairs, wantPairs)
 	}
 }
+
+func Test_calculateGlobalHash_EnvWildcards(t *testing.T) {
+	repoRoot := turbopath.AbsoluteSystemPath(t.TempDir())
+	hashWithEnv := func(globalEnv []string) string {
+		t.Helper()
+		hash, err := calculateGlobalHash(repoRoot, &fs.PackageJSON{}, fs.Pipeline{}, globalEnv, nil, nil, &lockfile.NpmLockfile{}, hclog.NewNullLogger(), os.Environ())
+		if err != nil {
+			t.Fatalf("calculateGlobalHash() error = %v", err)
+		}
+		return hash
+	}
+
+	t.Setenv("MY_APP_URL", "https://example.com")
+	t.Setenv("MY_APP_SECRET", "hunter2")
+	before := hashWithEnv([]string{"MY_APP_*", "!MY_APP_SECRET"})
+
+	t.Setenv("MY_APP_URL", "https://example.org")
+	if after := hashWithEnv([]string{"MY_APP_*", "!MY_APP_SECRET"}); after == before {
+		t.Errorf("calculateGlobalHash() did not change when a variable matching \"MY_APP_*\" changed")
+	}
+
+	t.Setenv("MY_APP_URL", "https://example.com")
+	t.Setenv("MY_APP_SECRET", "hunter3")
+	if after := hashWithEnv([]string{"MY_APP_*", "!MY_APP_SECRET"}); after != before {
+		t.Errorf("calculateGlobalHash() changed when a negated variable changed")
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/vercel/turbo/cli/internal/cmdutil"
 	"github.com/vercel/turbo/cli/internal/colorcache"
 	"github.com/vercel/turbo/cli/internal/core"
+	"github.com/vercel/turbo/cli/internal/env"
+	"github.com/vercel/turbo/cli/internal/fs"
 	"github.com/vercel/turbo/cli/internal/graph"
 	"github.com/vercel/turbo/cli/internal/logstreamer"
 	"github.com/vercel/turbo/cli/internal/nodes"
@@ -28,6 +30,7 @@ import (
 	"github.com/vercel/turbo/cli/internal/taskhash"
 	"github.com/vercel/turbo/cli/internal/turbopath"
 	"github.com/vercel/turbo/cli/internal/ui"
+	"github.com/vercel/turbo/cli/internal/util"
 )
 
 // RealRun executes a set of tasks
@@ -68,6 +71,11 @@ func RealRun(
 
 	runCache := runcache.New(turboCache, base.RepoRoot, rs.Opts.runcacheOpts, colorCache)
 
+	rootTurboJSON, err := g.GetTurboConfigFromWorkspace(util.RootPkgName, singlePackage)
+	if err != nil {
+		return err
+	}
+
 	ec := &execContext{
 		colorCache:      colorCache,
 		runState:        runState,
@@ -80,6 +88,7 @@ func RealRun(
 		taskHashes:      hashes,
 		repoRoot:        base.RepoRoot,
 		isSinglePackage: singlePackage,
+		globalEnv:       append(append([]string{}, rootTurboJSON.GlobalEnv...), rootTurboJSON.GlobalPassThroughEnv...),
 	}
 
 	// run the thing
@@ -136,6 +145,24 @@ type execContext struct {
 	taskHashes      *taskhash.Tracker
 	repoRoot        turbopath.AbsoluteSystemPath
 	isSinglePackage bool
+	// globalEnv holds the globalEnv and globalPassThroughEnv patterns, which
+	// are passed to every task, including strict ones
+	globalEnv []string
+}
+
+// taskEnv returns the environment a task's process is started with. Strict tasks
+// only get the variables they declare, the global ones, and the few that are
+// needed to spawn a process at all.
+func taskEnv(environ []string, taskDefinition *fs.TaskDefinition, globalEnv []string) ([]string, error) {
+	if taskDefinition.EnvMode != util.StrictEnvMode {
+		return environ, nil
+	}
+	patterns := append(append(append([]string{}, taskDefinition.EnvVarDependencies...), taskDefinition.PassThroughEnv...), globalEnv...)
+	names, err := fs.ResolveEnvVarPatterns(patterns, fs.EnvVarNames(environ))
+	if err != nil {
+		return nil, err
+	}
+	return env.FilterEnviron(environ, append(names, env.StrictEnvVars...)), nil
 }
 
 func (ec *execContext) logError(log hclog.Logger, prefix string, err error) {
@@ -206,7 +233,17 @@ func (ec *execContext) exec(ctx gocontext.Context, packageTask *nodes.PackageTas
 	cmd := exec.Command(ec.packageManager.Command, argsactual...)
 	cmd.Dir = packageTask.Pkg.Dir.ToSystemPath().RestoreAnchor(ec.repoRoot).ToString()
 	envs := fmt.Sprintf("TURBO_HASH=%v", hash)
-	cmd.Env = append(os.Environ(), envs)
+	taskEnvVars, err := taskEnv(os.Environ(), packageTask.TaskDefinition, ec.globalEnv)
+	if err != nil {
+		tracer(TargetBuildFailed, err)
+		ec.logError(progressLogger, prettyPrefix, err)
+		return err
+	}
+	cmd.Env = append(taskEnvVars, envs)
+	// Interactive tasks prompt for input, so give them the terminal's stdin
+	if packageTask.TaskDefinition.Interactive {
+		cmd.Stdin = os.Stdin
+	}
 
 	// Setup stdout/stderr
 	// If we are not caching anything, then we don't need to write logs to disk
@@ -0,0 +1,54 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/util"
+)
+
+func Test_taskEnv(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"API_KEY=secret",
+		"NEXT_PUBLIC_URL=https://example.com",
+		"AWS_REGION=us-east-1",
+		"CI=true",
+		"HOME=/home/user",
+	}
+
+	testCases := []struct {
+		name           string
+		taskDefinition *fs.TaskDefinition
+		globalEnv      []string
+		want           []string
+	}{
+		{
+			name:           "loose tasks get the whole environment",
+			taskDefinition: &fs.TaskDefinition{EnvMode: util.LooseEnvMode, EnvVarDependencies: []string{"API_KEY"}},
+			want:           environ,
+		},
+		{
+			name: "strict tasks only get declared and global env",
+			taskDefinition: &fs.TaskDefinition{
+				EnvMode:            util.StrictEnvMode,
+				EnvVarDependencies: []string{"API_KEY"},
+				PassThroughEnv:     []string{"AWS_*"},
+			},
+			globalEnv: []string{"CI"},
+			want:      []string{"PATH=/usr/bin", "API_KEY=secret", "AWS_REGION=us-east-1", "CI=true"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := taskEnv(environ, tc.taskDefinition, tc.globalEnv)
+			if err != nil {
+				t.Fatalf("taskEnv: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("taskEnv() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
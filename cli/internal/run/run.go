@@ -211,6 +211,12 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 
 	// TODO: these values come from a config file, hopefully viper can help us merge these
 	r.opts.cacheOpts.RemoteCacheOpts = turboJSON.RemoteCacheOptions
+	if enabled := turboJSON.RemoteCacheOptions.Enabled; enabled != nil && !*enabled {
+		r.opts.cacheOpts.SkipRemote = true
+	}
+	for _, err := range turboJSON.Validate([]fs.TurboJSONValidation{fs.ValidateRemoteCacheOptions}) {
+		r.base.LogWarning("", err)
+	}
 
 	pipeline := turboJSON.Pipeline
 	g.Pipeline = pipeline
@@ -243,7 +249,8 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 		rootPackageJSON,
 		pipeline,
 		turboJSON.GlobalEnv,
-		turboJSON.GlobalDeps,
+		// .env files are hashed like any other global file dependency
+		append(append([]string{}, turboJSON.GlobalDeps...), turboJSON.GlobalDotEnv...),
 		pkgDepGraph.PackageManager,
 		pkgDepGraph.Lockfile,
 		r.base.Logger,
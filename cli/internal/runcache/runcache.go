@@ -289,6 +289,13 @@ func (rc *RunCache) TaskCache(pt *nodes.PackageTask, hash string) TaskCache {
 	for index, output := range hashableOutputs.Exclusions {
 		repoRelativeGlobs.Exclusions[index] = filepath.Join(pt.Pkg.Dir.ToStringDuringMigration(), output)
 	}
+	// Root outputs are already relative to the repository
+	for _, output := range hashableOutputs.RootInclusions {
+		repoRelativeGlobs.Inclusions = append(repoRelativeGlobs.Inclusions, filepath.FromSlash(output))
+	}
+	for _, output := range hashableOutputs.RootExclusions {
+		repoRelativeGlobs.Exclusions = append(repoRelativeGlobs.Exclusions, filepath.FromSlash(output))
+	}
 
 	taskOutputMode := pt.TaskDefinition.OutputMode
 	if rc.taskOutputModeOverride != nil {
@@ -301,7 +308,7 @@ func (rc *RunCache) TaskCache(pt *nodes.PackageTask, hash string) TaskCache {
 		hash:              hash,
 		pt:                pt,
 		taskOutputMode:    taskOutputMode,
-		cachingDisabled:   !pt.TaskDefinition.ShouldCache,
+		cachingDisabled:   !pt.TaskDefinition.IsCacheable(),
 		LogFileName:       logFileName,
 	}
 }
@@ -5,6 +5,7 @@ package taskhash
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"sync"
@@ -52,15 +53,31 @@ func NewTracker(rootNode string, globalHash string, pipeline fs.Pipeline, worksp
 
 // packageFileSpec defines a combination of a package and optional set of input globs
 type packageFileSpec struct {
-	pkg    string
-	inputs []string
+	pkg           string
+	inputs        []string
+	defaultInputs bool
 }
 
-func specFromPackageTask(packageTask *nodes.PackageTask) packageFileSpec {
-	return packageFileSpec{
-		pkg:    packageTask.PackageName,
-		inputs: packageTask.TaskDefinition.Inputs,
+// newPackageFileSpec returns the packageFileSpec for a task in pkg. The task's dotEnv
+// files are added to its inputs. If it doesn't declare inputs, they are hashed in
+// addition to the default files instead.
+func newPackageFileSpec(pkg string, taskDefinition *fs.TaskDefinition) packageFileSpec {
+	spec := packageFileSpec{
+		pkg:           pkg,
+		inputs:        taskDefinition.Inputs,
+		defaultInputs: taskDefinition.DefaultInputs,
+	}
+	if len(taskDefinition.DotEnv) > 0 {
+		if len(spec.inputs) == 0 {
+			spec.defaultInputs = true
+		}
+		spec.inputs = append(append([]string{}, spec.inputs...), taskDefinition.DotEnv...)
 	}
+	return spec
+}
+
+func specFromPackageTask(packageTask *nodes.PackageTask) packageFileSpec {
+	return newPackageFileSpec(packageTask.PackageName, packageTask.TaskDefinition)
 }
 
 // packageFileHashKey is a hashable representation of a packageFileSpec.
@@ -69,6 +86,9 @@ type packageFileHashKey string
 // hashes the inputs for a packageTask
 func (pfs packageFileSpec) ToKey() packageFileHashKey {
 	sort.Strings(pfs.inputs)
+	if pfs.defaultInputs {
+		return packageFileHashKey(fmt.Sprintf("%v#%v#default", pfs.pkg, strings.Join(pfs.inputs, "!")))
+	}
 	return packageFileHashKey(fmt.Sprintf("%v#%v", pfs.pkg, strings.Join(pfs.inputs, "!")))
 }
 
@@ -84,9 +104,15 @@ func (pfs *packageFileSpec) hash(pkg *fs.PackageJSON, repoRoot turbopath.Absolut
 	hashObject, pkgDepsErr := hashing.GetPackageDeps(repoRoot, &hashing.PackageDepsOptions{
 		PackagePath:   pkg.Dir,
 		InputPatterns: pfs.inputs,
+		DefaultInputs: pfs.defaultInputs,
 	})
 	if pkgDepsErr != nil {
-		manualHashObject, err := manuallyHashPackage(pkg, pfs.inputs, repoRoot)
+		// Without input patterns the whole package is hashed, which is what the defaults cover
+		inputs := pfs.inputs
+		if pfs.defaultInputs {
+			inputs = nil
+		}
+		manualHashObject, err := manuallyHashPackage(pkg, inputs, repoRoot)
 		if err != nil {
 			return "", err
 		}
@@ -185,12 +211,9 @@ func (th *Tracker) CalculateFileHashes(
 			return fmt.Errorf("missing pipeline entry %v", taskID)
 		}
 
-		pfs := &packageFileSpec{
-			pkg:    pkgName,
-			inputs: taskDefinition.Inputs,
-		}
+		pfs := newPackageFileSpec(pkgName, taskDefinition)
 
-		hashTasks.Add(pfs)
+		hashTasks.Add(&pfs)
 	}
 
 	hashes := make(map[packageFileHashKey]string)
@@ -238,6 +261,7 @@ type taskHashInputs struct {
 	hashableEnvPairs     []string
 	globalHash           string
 	taskDependencyHashes []string
+	cacheKey             string
 }
 
 func (th *Tracker) calculateDependencyHashes(dependencySet dag.Set) ([]string, error) {
@@ -281,14 +305,23 @@ func (th *Tracker) CalculateTaskHash(packageTask *nodes.PackageTask, dependencyS
 	}
 
 	var envPrefixes []string
-	framework := inference.InferFramework(packageTask.Pkg)
-	if framework != nil && framework.EnvPrefix != "" {
-		// log auto detected framework and env prefix
-		logger.Debug(fmt.Sprintf("auto detected framework for %s", packageTask.PackageName), "framework", framework.Slug, "env_prefix", framework.EnvPrefix)
-		envPrefixes = append(envPrefixes, framework.EnvPrefix)
+	// Tasks in strict env mode only hash the variables they declare, so framework
+	// env prefixes are not inferred for them
+	if packageTask.TaskDefinition.EnvMode != util.StrictEnvMode {
+		framework := inference.InferFramework(packageTask.Pkg)
+		if framework != nil && framework.EnvPrefix != "" {
+			// log auto detected framework and env prefix
+			logger.Debug(fmt.Sprintf("auto detected framework for %s", packageTask.PackageName), "framework", framework.Slug, "env_prefix", framework.EnvPrefix)
+			envPrefixes = append(envPrefixes, framework.EnvPrefix)
+		}
 	}
 
-	hashableEnvPairs := env.GetHashableEnvPairs(packageTask.TaskDefinition.EnvVarDependencies, envPrefixes)
+	// Wildcards in "env" are expanded against the variables that are set
+	envVarNames, err := fs.ResolveEnvVarPatterns(packageTask.TaskDefinition.EnvVarDependencies, fs.EnvVarNames(os.Environ()))
+	if err != nil {
+		return "", err
+	}
+	hashableEnvPairs := env.GetHashableEnvPairs(envVarNames, envPrefixes)
 	outputs := packageTask.HashableOutputs()
 	taskDependencyHashes, err := th.calculateDependencyHashes(dependencySet)
 	if err != nil {
@@ -307,6 +340,7 @@ func (th *Tracker) CalculateTaskHash(packageTask *nodes.PackageTask, dependencyS
 		hashableEnvPairs:     hashableEnvPairs,
 		globalHash:           th.globalHash,
 		taskDependencyHashes: taskDependencyHashes,
+		cacheKey:             packageTask.TaskDefinition.CacheKey,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to hash task %v: %v", packageTask.TaskID, hash)
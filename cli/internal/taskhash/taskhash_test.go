@@ -5,8 +5,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/go-hclog"
+	"github.com/pyr-sh/dag"
 	"github.com/vercel/turbo/cli/internal/fs"
+	"github.com/vercel/turbo/cli/internal/graph"
+	"github.com/vercel/turbo/cli/internal/nodes"
 	"github.com/vercel/turbo/cli/internal/turbopath"
+	"github.com/vercel/turbo/cli/internal/util"
 )
 
 func Test_manuallyHashPackage(t *testing.T) {
@@ -135,3 +140,39 @@ func Test_manuallyHashPackage(t *testing.T) {
 		t.Errorf("found extra hashes in %v", hashes)
 	}
 }
+
+func Test_CalculateTaskHash_EnvMode(t *testing.T) {
+	pkg := &fs.PackageJSON{
+		Name:                   "web",
+		Dir:                    turbopath.AnchoredSystemPath("apps/web"),
+		UnresolvedExternalDeps: map[string]string{"vite": "*"},
+	}
+	hashTask := func(envMode util.EnvMode, vitePublic string) string {
+		t.Setenv("VITE_PUBLIC", vitePublic)
+		packageTask := &nodes.PackageTask{
+			TaskID:         "web#build",
+			Task:           "build",
+			PackageName:    "web",
+			Pkg:            pkg,
+			TaskDefinition: &fs.TaskDefinition{EnvMode: envMode},
+		}
+		tracker := NewTracker("___ROOT___", "global-hash", fs.Pipeline{}, graph.WorkspaceInfos{})
+		tracker.packageInputsHashes = packageFileHashes{
+			specFromPackageTask(packageTask).ToKey(): "file-hash",
+		}
+		hash, err := tracker.CalculateTaskHash(packageTask, make(dag.Set), hclog.NewNullLogger(), nil)
+		if err != nil {
+			t.Fatalf("failed to calculate task hash: %v", err)
+		}
+		return hash
+	}
+
+	// vite is inferred, so loose tasks hash its VITE_ variables
+	if hashTask(util.LooseEnvMode, "a") == hashTask(util.LooseEnvMode, "b") {
+		t.Error("expected loose task hash to depend on VITE_PUBLIC")
+	}
+	// strict tasks only hash the variables they declare
+	if hashTask(util.StrictEnvMode, "a") != hashTask(util.StrictEnvMode, "b") {
+		t.Error("expected strict task hash to ignore VITE_PUBLIC")
+	}
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EnvMode defines which environment variables are made available to a task
+type EnvMode int
+
+const (
+	// LooseEnvMode hashes the declared environment variables plus the ones inferred from the framework
+	LooseEnvMode EnvMode = iota
+	// StrictEnvMode only hashes, and only passes to the task, the environment variables that the task declares
+	StrictEnvMode
+)
+
+const (
+	looseEnvModeString  = "loose"
+	strictEnvModeString = "strict"
+)
+
+// EnvModeStrings is an array containing the string representations for env modes
+var EnvModeStrings = []string{
+	looseEnvModeString,
+	strictEnvModeString,
+}
+
+// FromEnvModeString converts an env mode string representation into the enum value
+func FromEnvModeString(value string) (EnvMode, error) {
+	switch value {
+	case looseEnvModeString:
+		return LooseEnvMode, nil
+	case strictEnvModeString:
+		return StrictEnvMode, nil
+	}
+
+	return LooseEnvMode, fmt.Errorf("invalid env mode: %v. Valid values are: %v", value, strings.Join(EnvModeStrings, ", "))
+}
+
+// ToEnvModeString converts an env mode enum value into the string representation
+func ToEnvModeString(value EnvMode) (string, error) {
+	switch value {
+	case LooseEnvMode:
+		return looseEnvModeString, nil
+	case StrictEnvMode:
+		return strictEnvModeString, nil
+	}
+
+	return "", fmt.Errorf("invalid env mode: %v", value)
+}
+
+// UnmarshalJSON converts an env mode string representation into an enum
+func (c *EnvMode) UnmarshalJSON(data []byte) error {
+	var rawEnvMode string
+	if err := json.Unmarshal(data, &rawEnvMode); err != nil {
+		return err
+	}
+
+	envMode, err := FromEnvModeString(rawEnvMode)
+	if err != nil {
+		return err
+	}
+
+	*c = envMode
+	return nil
+}
+
+// MarshalJSON converts an env mode value to its string representation
+func (c EnvMode) MarshalJSON() ([]byte, error) {
+	envModeString, err := ToEnvModeString(c)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envModeString)
+}
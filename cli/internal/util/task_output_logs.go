@@ -0,0 +1,93 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TaskOutputLogs defines the ways turbo can write task logs to the cache
+type TaskOutputLogs int
+
+const (
+	// FullTaskOutputLogs will write all task output to the cached logs
+	FullTaskOutputLogs TaskOutputLogs = iota
+	// HashTaskOutputLogs will only write turbo-computed task hashes to the cached logs
+	HashTaskOutputLogs
+	// NoTaskOutputLogs will not write any cached logs
+	NoTaskOutputLogs
+	// ErrorTaskOutputLogs will only write task output to the cached logs for failures
+	ErrorTaskOutputLogs
+)
+
+const (
+	fullTaskOutputLogsString  = "full"
+	hashTaskOutputLogsString  = "hash-only"
+	noTaskOutputLogsString    = "none"
+	errorTaskOutputLogsString = "errors-only"
+)
+
+// TaskOutputLogsStrings is an array containing the string representations for task output logs
+var TaskOutputLogsStrings = []string{
+	fullTaskOutputLogsString,
+	hashTaskOutputLogsString,
+	noTaskOutputLogsString,
+	errorTaskOutputLogsString,
+}
+
+// FromTaskOutputLogsString converts a task output logs string representation into the enum value
+func FromTaskOutputLogsString(value string) (TaskOutputLogs, error) {
+	switch value {
+	case fullTaskOutputLogsString:
+		return FullTaskOutputLogs, nil
+	case hashTaskOutputLogsString:
+		return HashTaskOutputLogs, nil
+	case noTaskOutputLogsString:
+		return NoTaskOutputLogs, nil
+	case errorTaskOutputLogsString:
+		return ErrorTaskOutputLogs, nil
+	}
+
+	return FullTaskOutputLogs, fmt.Errorf("invalid task output logs: %v. Valid values are: %v", value, strings.Join(TaskOutputLogsStrings, ", "))
+}
+
+// ToTaskOutputLogsString converts a task output logs enum value into the string representation
+func ToTaskOutputLogsString(value TaskOutputLogs) (string, error) {
+	switch value {
+	case FullTaskOutputLogs:
+		return fullTaskOutputLogsString, nil
+	case HashTaskOutputLogs:
+		return hashTaskOutputLogsString, nil
+	case NoTaskOutputLogs:
+		return noTaskOutputLogsString, nil
+	case ErrorTaskOutputLogs:
+		return errorTaskOutputLogsString, nil
+	}
+
+	return "", fmt.Errorf("invalid task output logs: %v", value)
+}
+
+// UnmarshalJSON converts a task output logs string representation into an enum
+func (c *TaskOutputLogs) UnmarshalJSON(data []byte) error {
+	var rawTaskOutputLogs string
+	if err := json.Unmarshal(data, &rawTaskOutputLogs); err != nil {
+		return err
+	}
+
+	taskOutputLogs, err := FromTaskOutputLogsString(rawTaskOutputLogs)
+	if err != nil {
+		return err
+	}
+
+	*c = taskOutputLogs
+	return nil
+}
+
+// MarshalJSON converts a task output logs value to its string representation
+func (c TaskOutputLogs) MarshalJSON() ([]byte, error) {
+	outputLogsString, err := ToTaskOutputLogsString(c)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(outputLogsString)
+}
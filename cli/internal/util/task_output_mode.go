@@ -3,6 +3,7 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // TaskOutputMode defines the ways turbo can display task output during a run
@@ -53,7 +54,7 @@ func FromTaskOutputModeString(value string) (TaskOutputMode, error) {
 		return ErrorTaskOutput, nil
 	}
 
-	return FullTaskOutput, fmt.Errorf("invalid task output mode: %v", value)
+	return FullTaskOutput, fmt.Errorf("invalid task output mode: %v. Valid values are: %v", value, strings.Join(TaskOutputModeStrings, ", "))
 }
 
 // ToTaskOutputModeString converts a task output mode enum value into the string representation